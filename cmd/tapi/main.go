@@ -14,21 +14,34 @@ import (
 const Version = "v0.1.0"
 
 func main() {
-	if err := logger.Init(); err != nil {
-		panic(err)
+	// Load configuration first, it decides where and how to log
+	cfg, cfgErr := config.Load()
+
+	if err := logger.InitWithOptions(logger.Options{
+		Level:      cfg.Log.Level,
+		Path:       storage.ExpandHome(cfg.Log.Path),
+		Format:     cfg.Log.Format,
+		MaxSizeMB:  cfg.Log.MaxSizeMB,
+		MaxBackups: cfg.Log.MaxBackups,
+	}); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to open log: %v\n", err)
+		os.Exit(1)
 	}
 
 	logger.Logger.Info("Starting TAPI " + Version)
-
-	// Load configuration
-	cfg, err := config.Load()
-	if err != nil {
-		logger.Logger.Error("Failed to load config, using defaults", "error", err)
+	if cfgErr != nil {
+		logger.Logger.Error("Failed to load config, using defaults", "error", cfgErr)
 	}
 
+	// Passphrase for encrypted secrets, e.g. in CI; the TUI can also :unlock
+	if p := os.Getenv("TAPI_PASSPHRASE"); p != "" {
+		storage.SetPassphrase(p)
+	}
 
-
-
+	// Headless mode: `tapi run` executes collections without the TUI
+	if len(os.Args) > 1 && os.Args[1] == "run" {
+		os.Exit(runCommand(os.Args[2:], cfg, os.Stdout, os.Stderr))
+	}
 
 	// First-run experience: create demo collection if none exist
 	collections, _, err := storage.LoadCollections()
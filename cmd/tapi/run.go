@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/styltsou/tapi/internal/config"
+	"github.com/styltsou/tapi/internal/cookies"
+	"github.com/styltsou/tapi/internal/http"
+	"github.com/styltsou/tapi/internal/oauth"
+	"github.com/styltsou/tapi/internal/runner"
+	"github.com/styltsou/tapi/internal/storage"
+)
+
+// Exit codes for the headless runner
+const (
+	exitOK     = 0
+	exitFailed = 1 // at least one request failed
+	exitUsage  = 2 // bad flags or setup error
+)
+
+// runCommand implements `tapi run`, executing collections without the TUI
+func runCommand(args []string, cfg config.Config, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("run", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	envName := fs.String("env", "", "environment to use for variable substitution")
+	collection := fs.String("collection", "", "only run the collection with this name")
+	request := fs.String("request", "", "only run requests whose name contains this text")
+	bail := fs.Bool("bail", false, "stop after the first failed request")
+	concurrency := fs.Int("concurrency", 1, "number of requests to run in parallel")
+	reports := map[string]*string{
+		runner.FormatJUnit: fs.String("report-junit", "", "write a JUnit XML report to this path"),
+		runner.FormatJSON:  fs.String("report-json", "", "write a JSON report to this path"),
+		runner.FormatTAP:   fs.String("report-tap", "", "write a TAP report to this path"),
+	}
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: tapi run [flags]")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if *concurrency < 1 {
+		fmt.Fprintln(stderr, "Error: --concurrency must be at least 1")
+		return exitUsage
+	}
+
+	collections, loadErrs, err := storage.LoadCollections()
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: failed to load collections: %v\n", err)
+		return exitUsage
+	}
+	for _, e := range loadErrs {
+		fmt.Fprintf(stderr, "Warning: %v\n", e)
+	}
+
+	opts := runner.Options{
+		Collection:     *collection,
+		Request:        *request,
+		DefaultHeaders: cfg.DefaultHeaders,
+		Bail:           *bail,
+		Concurrency:    *concurrency,
+		Transport:      cfg.Transport,
+	}
+
+	tokenEnv := "" // oauth2 tokens and cookies are kept per environment
+	if *envName != "" {
+		env, err := findEnvironment(*envName)
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return exitUsage
+		}
+		opts.Variables = env.Variables
+		opts.EnvTransport = env.Transport
+		opts.Secrets = env.SecretValues()
+		tokenEnv = env.Name
+		if env.Locked() {
+			locked := strings.Join(env.LockedNames(), ", ")
+			switch err := env.LockError(); {
+			case errors.Is(err, storage.ErrLocked):
+				fmt.Fprintf(stderr, "Warning: secrets of %s are encrypted (%s), set TAPI_PASSPHRASE to unlock them\n", env.Name, locked)
+			case errors.Is(err, storage.ErrBadPassphrase):
+				fmt.Fprintf(stderr, "Warning: TAPI_PASSPHRASE is not the passphrase of %s, its secrets stay locked (%s)\n", env.Name, locked)
+			default:
+				fmt.Fprintf(stderr, "Warning: secrets of %s cannot be decrypted (%s): %v\n", env.Name, locked, err)
+			}
+		}
+	}
+
+	globals, err := storage.LoadGlobals()
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: failed to load global variables: %v\n", err)
+		return exitUsage
+	}
+	opts.Globals = globals
+
+	if len(runner.Select(collections, opts)) == 0 {
+		fmt.Fprintln(stderr, "Error: no requests matched")
+		return exitUsage
+	}
+
+	client := http.NewClient(cfg.TimeoutDuration())
+	client.MaxBodySize = cfg.MaxBodySize()
+	if store, err := oauth.DefaultStore(); err == nil {
+		client.Tokens = oauth.NewManager(store)
+		client.Tokens.SetEnvironment(tokenEnv)
+	}
+	if cfg.CookieJar {
+		if jar, err := cookies.DefaultJar(); err == nil {
+			if err := jar.SetEnvironment(tokenEnv); err != nil {
+				fmt.Fprintf(stderr, "Warning: failed to load cookies: %v\n", err)
+			}
+			client.Cookies = jar
+		}
+	}
+	results := runner.Run(context.Background(), client, collections, opts)
+	runner.PrintSummary(stdout, results)
+
+	for _, format := range []string{runner.FormatJUnit, runner.FormatJSON, runner.FormatTAP} {
+		path := *reports[format]
+		if path == "" {
+			continue
+		}
+		if err := runner.WriteReportFile(path, format, results); err != nil {
+			fmt.Fprintf(stderr, "Error: failed to write %s report: %v\n", format, err)
+			return exitUsage
+		}
+		fmt.Fprintf(stdout, "Wrote %s report to %s\n", format, path)
+	}
+
+	if _, failed := runner.Count(results); failed > 0 {
+		return exitFailed
+	}
+	return exitOK
+}
+
+// findEnvironment looks up an environment by name (case-insensitive)
+func findEnvironment(name string) (storage.Environment, error) {
+	envs, err := storage.LoadEnvironments()
+	if err != nil {
+		return storage.Environment{}, fmt.Errorf("failed to load environments: %w", err)
+	}
+	for _, env := range envs {
+		if strings.EqualFold(env.Name, name) {
+			return env, nil
+		}
+	}
+	return storage.Environment{}, fmt.Errorf("environment %q not found", name)
+}
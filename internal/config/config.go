@@ -5,6 +5,7 @@ import (
 	"path/filepath"
 	"time"
 
+	"github.com/styltsou/tapi/internal/storage"
 	"gopkg.in/yaml.v3"
 )
 
@@ -13,6 +14,25 @@ type Config struct {
 	Timeout        int               `yaml:"timeout"`         // request timeout in seconds
 	DefaultHeaders map[string]string `yaml:"default_headers"` // headers added to every request
 	Theme          ThemeConfig       `yaml:"theme"`
+	Log            LogConfig         `yaml:"log"`
+	Transport      storage.Transport `yaml:"transport"` // proxy and TLS, overridden per collection and environment
+
+	// CookieJar keeps the cookies set by servers and sends them back, one jar
+	// per environment saved in ~/.tapi/cookies
+	CookieJar bool `yaml:"cookie_jar"`
+
+	// CaptureWire records each request and response as they went over the
+	// wire, for the Raw view of the response pane. Off by default: the
+	// capture holds credentials and secret values as sent.
+	CaptureWire bool `yaml:"capture_wire"`
+
+	// MaxBodyMB truncates response bodies shown in the response pane past
+	// this size; downloads are not limited
+	MaxBodyMB int `yaml:"max_body_mb"`
+
+	// AllowUnresolved sends requests with unresolved {{variables}} after a warning
+	// instead of blocking them
+	AllowUnresolved bool `yaml:"allow_unresolved"`
 }
 
 // ThemeConfig holds color customization
@@ -23,17 +43,33 @@ type ThemeConfig struct {
 	Error     string `yaml:"error"`
 }
 
+// LogConfig controls the application log
+type LogConfig struct {
+	Level      string `yaml:"level"`       // debug, info, warn, error
+	Path       string `yaml:"path"`        // defaults to ~/.tapi/logs/tapi.log
+	Format     string `yaml:"format"`      // text or json
+	MaxSizeMB  int    `yaml:"max_size_mb"` // rotate past this size
+	MaxBackups int    `yaml:"max_backups"` // rotated files to keep
+}
+
 // DefaultConfig returns the default configuration
 func DefaultConfig() Config {
 	return Config{
 		Timeout:        30,
 		DefaultHeaders: nil,
+		MaxBodyMB:      10,
 		Theme: ThemeConfig{
 			Primary:   "#7D56F4",
 			Secondary: "#04B575",
 			Accent:    "#EE6FF8",
 			Error:     "#FF4C4C",
 		},
+		Log: LogConfig{
+			Level:      "info",
+			Format:     "text",
+			MaxSizeMB:  5,
+			MaxBackups: 3,
+		},
 	}
 }
 
@@ -80,6 +116,14 @@ func LoadFrom(path string) Config {
 	return cfg
 }
 
+// MaxBodySize returns the body size limit in bytes
+func (c Config) MaxBodySize() int64 {
+	if c.MaxBodyMB <= 0 {
+		return 10 << 20
+	}
+	return int64(c.MaxBodyMB) << 20
+}
+
 // TimeoutDuration returns the timeout as a time.Duration
 func (c Config) TimeoutDuration() time.Duration {
 	if c.Timeout <= 0 {
@@ -67,3 +67,13 @@ func TestTimeoutDuration(t *testing.T) {
 		t.Errorf("Expected 10s duration, got %v", cfg.TimeoutDuration().String())
 	}
 }
+
+func TestMaxBodySize(t *testing.T) {
+	if got := DefaultConfig().MaxBodySize(); got != 10<<20 {
+		t.Errorf("Expected a default of 10MB, got %d", got)
+	}
+	cfg := Config{MaxBodyMB: 50}
+	if got := cfg.MaxBodySize(); got != 50<<20 {
+		t.Errorf("Expected 50MB, got %d", got)
+	}
+}
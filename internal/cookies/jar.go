@@ -0,0 +1,337 @@
+// Package cookies keeps the cookies set by servers between requests and runs,
+// one jar per environment
+package cookies
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gosimple/slug"
+	"github.com/styltsou/tapi/internal/logger"
+	"github.com/styltsou/tapi/internal/storage"
+	"golang.org/x/net/publicsuffix"
+	"gopkg.in/yaml.v3"
+)
+
+// Cookie is a stored cookie. Session cookies (no expiry) are kept too: the
+// jar outlives the session so a login is not lost on restart.
+type Cookie struct {
+	Name     string    `yaml:"name"`
+	Value    string    `yaml:"value"`
+	Domain   string    `yaml:"domain"`
+	HostOnly bool      `yaml:"host_only,omitempty"` // sent to Domain only, not its subdomains
+	Path     string    `yaml:"path"`
+	Expires  time.Time `yaml:"expires,omitempty"` // zero for session cookies
+	Secure   bool      `yaml:"secure,omitempty"`
+	HttpOnly bool      `yaml:"http_only,omitempty"`
+}
+
+// Expired reports whether the cookie expiry has passed
+func (c Cookie) Expired(now time.Time) bool {
+	return !c.Expires.IsZero() && !now.Before(c.Expires)
+}
+
+// sameAs reports whether two cookies are the same entry: a new cookie with
+// the same name, domain and path replaces the old one
+func (c Cookie) sameAs(o Cookie) bool {
+	return c.Name == o.Name && c.Domain == o.Domain && c.Path == o.Path
+}
+
+// Jar is an http.CookieJar saved on disk, one file per environment
+type Jar struct {
+	mu      sync.Mutex
+	dir     string
+	env     string
+	cookies []Cookie
+}
+
+// NewJar returns a jar keeping its files in dir, holding the cookies of the
+// requests sent without an environment
+func NewJar(dir string) *Jar {
+	j := &Jar{dir: dir}
+	j.cookies, _ = j.load(j.env)
+	return j
+}
+
+// DefaultJar returns the jar in ~/.tapi/cookies
+func DefaultJar() (*Jar, error) {
+	dir, err := storage.GetStoragePath("cookies")
+	if err != nil {
+		return nil, err
+	}
+	return NewJar(dir), nil
+}
+
+// SetEnvironment switches to the cookies of another environment
+func (j *Jar) SetEnvironment(env string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	cookies, err := j.load(env)
+	if err != nil {
+		return err
+	}
+	j.env = env
+	j.cookies = cookies
+	return nil
+}
+
+// Environment returns the environment the cookies belong to
+func (j *Jar) Environment() string {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.env
+}
+
+// SetCookies stores the cookies of a response from u. Cookies for a domain u
+// does not belong to are ignored; expired ones remove the stored cookie.
+func (j *Jar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	now := time.Now()
+	host := strings.ToLower(u.Hostname())
+	changed := false
+	for _, hc := range cookies {
+		c, ok := newCookie(host, u.EscapedPath(), hc, now)
+		if !ok {
+			continue
+		}
+		j.remove(c)
+		if !c.Expired(now) {
+			j.cookies = append(j.cookies, c)
+		}
+		changed = true
+	}
+	if changed {
+		if err := j.save(); err != nil {
+			logger.Logger.Warn("Failed to save cookies", "env", j.env, "error", err)
+		}
+	}
+}
+
+// newCookie converts a Set-Cookie received from host
+func newCookie(host, requestPath string, hc *http.Cookie, now time.Time) (Cookie, bool) {
+	if hc.Name == "" {
+		return Cookie{}, false
+	}
+	c := Cookie{
+		Name:     hc.Name,
+		Value:    hc.Value,
+		Domain:   host,
+		HostOnly: true,
+		Path:     hc.Path,
+		Secure:   hc.Secure,
+		HttpOnly: hc.HttpOnly,
+	}
+
+	if hc.Domain != "" {
+		domain := strings.ToLower(strings.TrimPrefix(hc.Domain, "."))
+		// A server can set cookies for its own domain and parents of it, not for
+		// a public suffix such as com or co.uk, nor for another site
+		if domain != host {
+			if !strings.HasSuffix(host, "."+domain) || !strings.Contains(domain, ".") || net.ParseIP(host) != nil {
+				return Cookie{}, false
+			}
+			if suffix, _ := publicsuffix.PublicSuffix(domain); suffix == domain {
+				return Cookie{}, false
+			}
+		}
+		c.Domain = domain
+		// A host that is itself a public suffix only gets host-only cookies
+		if suffix, _ := publicsuffix.PublicSuffix(domain); suffix != domain {
+			c.HostOnly = false
+		}
+	}
+
+	if !strings.HasPrefix(c.Path, "/") {
+		c.Path = defaultPath(requestPath)
+	}
+
+	switch {
+	case hc.MaxAge < 0:
+		c.Expires = now.Add(-time.Second) // deletes the stored cookie
+	case hc.MaxAge > 0:
+		c.Expires = now.Add(time.Duration(hc.MaxAge) * time.Second)
+	case !hc.Expires.IsZero():
+		c.Expires = hc.Expires
+	}
+	return c, true
+}
+
+// defaultPath is the request path up to its last "/" (RFC 6265 5.1.4)
+func defaultPath(p string) string {
+	idx := strings.LastIndex(p, "/")
+	if idx <= 0 {
+		return "/"
+	}
+	return p[:idx]
+}
+
+// Cookies returns the cookies to send to u, longest path first
+func (j *Jar) Cookies(u *url.URL) []*http.Cookie {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	now := time.Now()
+	host := strings.ToLower(u.Hostname())
+	requestPath := u.EscapedPath()
+	if requestPath == "" {
+		requestPath = "/"
+	}
+	secure := u.Scheme == "https" || u.Scheme == "wss"
+
+	var matched []Cookie
+	for _, c := range j.cookies {
+		if c.Expired(now) || (c.Secure && !secure) {
+			continue
+		}
+		if !domainMatch(c, host) || !pathMatch(c.Path, requestPath) {
+			continue
+		}
+		matched = append(matched, c)
+	}
+	sort.SliceStable(matched, func(a, b int) bool { return len(matched[a].Path) > len(matched[b].Path) })
+
+	cookies := make([]*http.Cookie, 0, len(matched))
+	for _, c := range matched {
+		cookies = append(cookies, &http.Cookie{Name: c.Name, Value: c.Value})
+	}
+	return cookies
+}
+
+func domainMatch(c Cookie, host string) bool {
+	if host == c.Domain {
+		return true
+	}
+	return !c.HostOnly && strings.HasSuffix(host, "."+c.Domain)
+}
+
+func pathMatch(cookiePath, requestPath string) bool {
+	if requestPath == cookiePath {
+		return true
+	}
+	if !strings.HasPrefix(requestPath, cookiePath) {
+		return false
+	}
+	return strings.HasSuffix(cookiePath, "/") || requestPath[len(cookiePath)] == '/'
+}
+
+// All returns the stored cookies that have not expired, by domain, path and name
+func (j *Jar) All() []Cookie {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	now := time.Now()
+	var cookies []Cookie
+	for _, c := range j.cookies {
+		if !c.Expired(now) {
+			cookies = append(cookies, c)
+		}
+	}
+	sort.Slice(cookies, func(a, b int) bool {
+		if cookies[a].Domain != cookies[b].Domain {
+			return cookies[a].Domain < cookies[b].Domain
+		}
+		if cookies[a].Path != cookies[b].Path {
+			return cookies[a].Path < cookies[b].Path
+		}
+		return cookies[a].Name < cookies[b].Name
+	})
+	return cookies
+}
+
+// Set adds a cookie, replacing the one with the same name, domain and path
+func (j *Jar) Set(c Cookie) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if c.Name == "" || c.Domain == "" {
+		return errors.New("a cookie needs a name and a domain")
+	}
+	if c.Path == "" {
+		c.Path = "/"
+	}
+	j.remove(c)
+	j.cookies = append(j.cookies, c)
+	return j.save()
+}
+
+// Delete removes a cookie
+func (j *Jar) Delete(c Cookie) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if !j.remove(c) {
+		return nil
+	}
+	return j.save()
+}
+
+// Clear removes every cookie of the environment
+func (j *Jar) Clear() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.cookies = nil
+	return j.save()
+}
+
+func (j *Jar) remove(c Cookie) bool {
+	for i, existing := range j.cookies {
+		if existing.sameAs(c) {
+			j.cookies = append(j.cookies[:i:i], j.cookies[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (j *Jar) path(env string) string {
+	name := slug.Make(env)
+	if name == "" {
+		name = "default"
+	}
+	return filepath.Join(j.dir, name+".yaml")
+}
+
+func (j *Jar) load(env string) ([]Cookie, error) {
+	data, err := os.ReadFile(j.path(env))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var cookies []Cookie
+	if err := yaml.Unmarshal(data, &cookies); err != nil {
+		return nil, err
+	}
+	return cookies, nil
+}
+
+// save writes the cookies of the current environment, dropping expired ones.
+// The file is readable by the user only, cookies often carry sessions.
+func (j *Jar) save() error {
+	now := time.Now()
+	kept := j.cookies[:0]
+	for _, c := range j.cookies {
+		if !c.Expired(now) {
+			kept = append(kept, c)
+		}
+	}
+	j.cookies = kept
+
+	if err := os.MkdirAll(j.dir, 0o700); err != nil {
+		return err
+	}
+	data, err := yaml.Marshal(j.cookies)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(j.path(j.env), data, 0o600)
+}
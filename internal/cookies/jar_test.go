@@ -0,0 +1,180 @@
+package cookies
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/styltsou/tapi/internal/logger"
+)
+
+func TestMain(m *testing.M) {
+	logger.InitDiscard()
+	os.Exit(m.Run())
+}
+
+func mustURL(t *testing.T, raw string) *url.URL {
+	t.Helper()
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+func names(cookies []*http.Cookie) []string {
+	var out []string
+	for _, c := range cookies {
+		out = append(out, c.Name)
+	}
+	return out
+}
+
+func TestJar_Matching(t *testing.T) {
+	jar := NewJar(t.TempDir())
+	jar.SetCookies(mustURL(t, "https://api.example.com/auth/login"), []*http.Cookie{
+		{Name: "host", Value: "1"},                                    // host only, path /auth
+		{Name: "site", Value: "2", Domain: ".example.com", Path: "/"}, // every subdomain
+		{Name: "secure", Value: "3", Path: "/", Secure: true},
+		{Name: "admin", Value: "4", Path: "/admin"},
+		{Name: "tld", Value: "5", Domain: "com"},            // rejected
+		{Name: "other", Value: "6", Domain: "evil.example"}, // rejected
+	})
+
+	tests := []struct {
+		url  string
+		want []string
+	}{
+		{"https://api.example.com/auth/me", []string{"host", "site", "secure"}},
+		{"https://api.example.com/admin/users", []string{"admin", "site", "secure"}},
+		{"https://api.example.com/administrator", []string{"site", "secure"}},
+		{"http://api.example.com/auth/me", []string{"host", "site"}},
+		{"https://www.example.com/auth/me", []string{"site"}},
+		{"https://example.org/", nil},
+	}
+	for _, tt := range tests {
+		got := names(jar.Cookies(mustURL(t, tt.url)))
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: expected %v, got %v", tt.url, tt.want, got)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: expected %v, got %v", tt.url, tt.want, got)
+				break
+			}
+		}
+	}
+}
+
+func TestJar_PublicSuffix(t *testing.T) {
+	jar := NewJar(t.TempDir())
+	jar.SetCookies(mustURL(t, "https://foo.co.uk/"), []*http.Cookie{
+		{Name: "suffix", Value: "1", Domain: "co.uk"}, // rejected: shared by every .co.uk site
+		{Name: "site", Value: "2", Domain: "foo.co.uk"},
+	})
+	if got := names(jar.Cookies(mustURL(t, "https://bar.co.uk/"))); len(got) != 0 {
+		t.Errorf("Expected no cookies for another .co.uk site, got %v", got)
+	}
+	if got := names(jar.Cookies(mustURL(t, "https://www.foo.co.uk/"))); len(got) != 1 || got[0] != "site" {
+		t.Errorf("Expected the site cookie on a subdomain, got %v", got)
+	}
+
+	// A host that is a public suffix itself only gets a host-only cookie
+	jar.SetCookies(mustURL(t, "https://github.io/"), []*http.Cookie{{Name: "host", Value: "3", Domain: "github.io"}})
+	if got := names(jar.Cookies(mustURL(t, "https://user.github.io/"))); len(got) != 0 {
+		t.Errorf("Expected no cookies for a site under github.io, got %v", got)
+	}
+	if got := names(jar.Cookies(mustURL(t, "https://github.io/"))); len(got) != 1 || got[0] != "host" {
+		t.Errorf("Expected the host-only cookie on github.io, got %v", got)
+	}
+}
+
+func TestJar_ExpiryAndReplace(t *testing.T) {
+	jar := NewJar(t.TempDir())
+	u := mustURL(t, "https://example.com/")
+
+	jar.SetCookies(u, []*http.Cookie{{Name: "sid", Value: "old"}, {Name: "gone", Value: "x", Expires: time.Now().Add(-time.Hour)}})
+	jar.SetCookies(u, []*http.Cookie{{Name: "sid", Value: "new", MaxAge: 60}})
+	all := jar.All()
+	if len(all) != 1 || all[0].Value != "new" || all[0].Expires.IsZero() {
+		t.Fatalf("Expected the new sid with an expiry, got %+v", all)
+	}
+
+	// Max-Age=0 in the header (MaxAge < 0 here) removes the cookie
+	jar.SetCookies(u, []*http.Cookie{{Name: "sid", MaxAge: -1}})
+	if all := jar.All(); len(all) != 0 {
+		t.Errorf("Expected the cookie to be removed, got %+v", all)
+	}
+}
+
+func TestJar_PersistedPerEnvironment(t *testing.T) {
+	dir := t.TempDir()
+	u := mustURL(t, "https://example.com/")
+
+	jar := NewJar(dir)
+	if err := jar.SetEnvironment("Staging"); err != nil {
+		t.Fatal(err)
+	}
+	jar.SetCookies(u, []*http.Cookie{{Name: "sid", Value: "staging"}})
+
+	info, err := os.Stat(filepath.Join(dir, "staging.yaml"))
+	if err != nil {
+		t.Fatalf("Expected a file for the environment: %v", err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("Expected mode 0600, got %v", info.Mode().Perm())
+	}
+
+	// Another environment starts empty; switching back restores the cookie
+	jar.SetEnvironment("Production")
+	if got := jar.Cookies(u); len(got) != 0 {
+		t.Errorf("Expected no cookies in another environment, got %v", names(got))
+	}
+	reopened := NewJar(dir)
+	reopened.SetEnvironment("Staging")
+	if got := reopened.Cookies(u); len(got) != 1 || got[0].Value != "staging" {
+		t.Errorf("Expected the saved cookie after reopening, got %v", got)
+	}
+}
+
+func TestJar_EditAndDelete(t *testing.T) {
+	jar := NewJar(t.TempDir())
+	u := mustURL(t, "https://example.com/")
+	jar.SetCookies(u, []*http.Cookie{{Name: "a", Value: "1"}, {Name: "b", Value: "2"}})
+
+	edited := jar.All()[0]
+	edited.Value = "changed"
+	if err := jar.Set(edited); err != nil {
+		t.Fatal(err)
+	}
+	if err := jar.Delete(jar.All()[1]); err != nil {
+		t.Fatal(err)
+	}
+	got := jar.Cookies(u)
+	if len(got) != 1 || got[0].Name != "a" || got[0].Value != "changed" {
+		t.Errorf("Expected only a=changed, got %v", got)
+	}
+
+	if err := jar.Set(Cookie{Name: "x"}); err == nil {
+		t.Error("Expected an error for a cookie without a domain")
+	}
+}
+
+func TestDefaultPath(t *testing.T) {
+	tests := map[string]string{
+		"":             "/",
+		"/":            "/",
+		"/login":       "/",
+		"/auth/login":  "/auth",
+		"/auth/login/": "/auth/login",
+	}
+	for in, want := range tests {
+		if got := defaultPath(in); got != want {
+			t.Errorf("defaultPath(%q): expected %q, got %q", in, want, got)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/styltsou/tapi/internal/storage"
+)
+
+// AssertionResult is the outcome of evaluating a single assertion
+type AssertionResult struct {
+	Assertion storage.Assertion
+	Passed    bool
+	Message   string // why the assertion failed, empty when it passed
+}
+
+// Evaluate checks every assertion against the response
+func (r *ProcessedResponse) Evaluate(assertions []storage.Assertion) []AssertionResult {
+	results := make([]AssertionResult, 0, len(assertions))
+	for _, a := range assertions {
+		res := AssertionResult{Assertion: a}
+		if err := r.evaluate(a); err != nil {
+			res.Message = err.Error()
+		} else {
+			res.Passed = true
+		}
+		results = append(results, res)
+	}
+	return results
+}
+
+// AllPassed reports whether every assertion result passed
+func AllPassed(results []AssertionResult) bool {
+	for _, res := range results {
+		if !res.Passed {
+			return false
+		}
+	}
+	return true
+}
+
+// evaluate returns nil if the assertion holds, or an error describing the mismatch
+func (r *ProcessedResponse) evaluate(a storage.Assertion) error {
+	switch a.Target {
+	case storage.AssertStatus:
+		return compareNumber(json.Number(strconv.Itoa(r.StatusCode)), a, "status")
+
+	case storage.AssertDuration:
+		return compareNumber(json.Number(strconv.FormatInt(r.Duration.Milliseconds(), 10)), a, "duration (ms)")
+
+	case storage.AssertSize:
+		return compareNumber(json.Number(strconv.FormatInt(r.Size, 10)), a, "size (bytes)")
+
+	case storage.AssertHeader:
+		values := r.Headers.Values(a.Property)
+		if len(values) == 0 {
+			return fmt.Errorf("header %s is missing", a.Property)
+		}
+		if a.Op == storage.OpExists {
+			return nil
+		}
+		return compareString(strings.Join(values, ", "), a, "header "+a.Property)
+
+	case storage.AssertBody:
+		return compareString(r.BodyString(), a, "body")
+
+	case storage.AssertJSON:
+		doc, err := r.JSON()
+		if err != nil {
+			return err
+		}
+		v, ok := LookupJSONPath(doc, a.Property)
+		switch a.Op {
+		case storage.OpExists:
+			if !ok {
+				return fmt.Errorf("%s does not exist", a.Property)
+			}
+			return nil
+		case storage.OpType:
+			if !ok {
+				return fmt.Errorf("%s does not exist", a.Property)
+			}
+			if got := JSONTypeOf(v); got != a.Value {
+				return fmt.Errorf("%s is %s, expected %s", a.Property, got, a.Value)
+			}
+			return nil
+		}
+		if !ok {
+			return fmt.Errorf("%s does not exist", a.Property)
+		}
+		if n, isNum := v.(json.Number); isNum && (a.Op == storage.OpEquals || a.Op == storage.OpBelow || a.Op == storage.OpInRange) {
+			return compareNumber(n, a, a.Property)
+		}
+		return compareString(FormatJSONValue(v), a, a.Property)
+	}
+
+	return fmt.Errorf("unknown assertion target %q", a.Target)
+}
+
+// compareNumber applies equals / in_range / below to a numeric actual value.
+// Numbers are compared exactly, so integers past float64 precision still
+// tell apart.
+func compareNumber(actual json.Number, a storage.Assertion, label string) error {
+	n, ok := parseNumber(actual.String())
+	if !ok {
+		return fmt.Errorf("%s is %s, not a number", label, actual)
+	}
+	switch a.Op {
+	case storage.OpEquals:
+		want, ok := parseNumber(a.Value)
+		if !ok {
+			return fmt.Errorf("invalid number %q", a.Value)
+		}
+		if n.Cmp(want) != 0 {
+			return fmt.Errorf("%s is %s, expected %s", label, actual, a.Value)
+		}
+	case storage.OpInRange:
+		lo, hi, err := parseRange(a.Value)
+		if err != nil {
+			return err
+		}
+		if n.Cmp(lo) < 0 || n.Cmp(hi) > 0 {
+			return fmt.Errorf("%s is %s, expected %s", label, actual, a.Value)
+		}
+	case storage.OpBelow:
+		limit, ok := parseNumber(a.Value)
+		if !ok {
+			return fmt.Errorf("invalid number %q", a.Value)
+		}
+		if n.Cmp(limit) >= 0 {
+			return fmt.Errorf("%s is %s, expected below %s", label, actual, a.Value)
+		}
+	default:
+		return fmt.Errorf("operator %q is not supported for %s", a.Op, label)
+	}
+	return nil
+}
+
+// compareString applies equals / contains / matches to a textual actual value
+func compareString(actual string, a storage.Assertion, label string) error {
+	switch a.Op {
+	case storage.OpEquals:
+		if actual != a.Value {
+			return fmt.Errorf("%s is %q, expected %q", label, truncateForMessage(actual), a.Value)
+		}
+	case storage.OpContains:
+		if !strings.Contains(actual, a.Value) {
+			return fmt.Errorf("%s does not contain %q", label, a.Value)
+		}
+	case storage.OpMatches:
+		re, err := regexp.Compile(a.Value)
+		if err != nil {
+			return fmt.Errorf("invalid regex %q: %w", a.Value, err)
+		}
+		if !re.MatchString(actual) {
+			return fmt.Errorf("%s does not match %q", label, a.Value)
+		}
+	default:
+		return fmt.Errorf("operator %q is not supported for %s", a.Op, label)
+	}
+	return nil
+}
+
+// parseRange parses "200-299" into its bounds, which may be negative as in
+// "-10--1"
+func parseRange(s string) (*big.Rat, *big.Rat, error) {
+	s = strings.TrimSpace(s)
+	// The separator is the first dash that follows a digit; the others are
+	// signs, of a bound or of an exponent as in 1e-3
+	for i := 1; i < len(s); i++ {
+		if s[i] != '-' {
+			continue
+		}
+		before := strings.TrimRight(s[:i], " ")
+		if c := before[len(before)-1]; (c < '0' || c > '9') && c != '.' {
+			continue
+		}
+		l, ok1 := parseNumber(s[:i])
+		h, ok2 := parseNumber(s[i+1:])
+		if !ok1 || !ok2 {
+			break
+		}
+		return l, h, nil
+	}
+	return nil, nil, fmt.Errorf("invalid range %q, expected min-max", s)
+}
+
+// parseNumber parses a decimal number such as 42, -1.5 or 1e3 exactly
+func parseNumber(s string) (*big.Rat, bool) {
+	return new(big.Rat).SetString(strings.TrimSpace(s))
+}
+
+func truncateForMessage(s string) string {
+	if len(s) > 60 {
+		return s[:60] + "…"
+	}
+	return s
+}
@@ -0,0 +1,123 @@
+package http
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/styltsou/tapi/internal/storage"
+)
+
+func TestProcessedResponse_Evaluate(t *testing.T) {
+	body := `{"user": {"id": 42, "name": "Ada", "roles": ["admin"]}}`
+	resp := &ProcessedResponse{
+		StatusCode: 201,
+		Headers:    http.Header{"Content-Type": []string{"application/json; charset=utf-8"}},
+		Body:       []byte(body),
+		Duration:   120 * time.Millisecond,
+		Size:       int64(len(body)),
+	}
+
+	tests := []struct {
+		name      string
+		assertion storage.Assertion
+		pass      bool
+	}{
+		{"status equals", storage.Assertion{Target: "status", Op: "equals", Value: "201"}, true},
+		{"status equals mismatch", storage.Assertion{Target: "status", Op: "equals", Value: "200"}, false},
+		{"status in range", storage.Assertion{Target: "status", Op: "in_range", Value: "200-299"}, true},
+		{"status out of range", storage.Assertion{Target: "status", Op: "in_range", Value: "400-499"}, false},
+		{"header present", storage.Assertion{Target: "header", Property: "content-type", Op: "exists"}, true},
+		{"header missing", storage.Assertion{Target: "header", Property: "X-Missing", Op: "exists"}, false},
+		{"header equals", storage.Assertion{Target: "header", Property: "Content-Type", Op: "equals", Value: "application/json; charset=utf-8"}, true},
+		{"header matches", storage.Assertion{Target: "header", Property: "Content-Type", Op: "matches", Value: "^application/json"}, true},
+		{"json equals number", storage.Assertion{Target: "json", Property: "$.user.id", Op: "equals", Value: "42"}, true},
+		{"json equals string", storage.Assertion{Target: "json", Property: "user.name", Op: "equals", Value: "Ada"}, true},
+		{"json exists", storage.Assertion{Target: "json", Property: "$.user.roles[0]", Op: "exists"}, true},
+		{"json not exists", storage.Assertion{Target: "json", Property: "$.user.email", Op: "exists"}, false},
+		{"json type", storage.Assertion{Target: "json", Property: "$.user.roles", Op: "type", Value: "array"}, true},
+		{"json type mismatch", storage.Assertion{Target: "json", Property: "$.user.id", Op: "type", Value: "string"}, false},
+		{"body contains", storage.Assertion{Target: "body", Op: "contains", Value: "Ada"}, true},
+		{"body regex", storage.Assertion{Target: "body", Op: "matches", Value: `"id":\s*\d+`}, true},
+		{"duration below", storage.Assertion{Target: "duration", Op: "below", Value: "500"}, true},
+		{"duration too slow", storage.Assertion{Target: "duration", Op: "below", Value: "100"}, false},
+		{"size below", storage.Assertion{Target: "size", Op: "below", Value: "10"}, false},
+		{"unknown target", storage.Assertion{Target: "cookie", Op: "exists"}, false},
+		{"unsupported op", storage.Assertion{Target: "status", Op: "contains", Value: "2"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := resp.Evaluate([]storage.Assertion{tt.assertion})
+			if results[0].Passed != tt.pass {
+				t.Errorf("Passed = %v, want %v (message: %s)", results[0].Passed, tt.pass, results[0].Message)
+			}
+			if !tt.pass && results[0].Message == "" {
+				t.Error("Expected a failure message")
+			}
+		})
+	}
+}
+
+func TestProcessedResponse_Evaluate_InvalidJSON(t *testing.T) {
+	resp := &ProcessedResponse{StatusCode: 200, Body: []byte("not json")}
+	results := resp.Evaluate([]storage.Assertion{{Target: "json", Property: "$.a", Op: "exists"}})
+	if results[0].Passed || AllPassed(results) {
+		t.Error("Expected JSON assertion on non-JSON body to fail")
+	}
+}
+
+func TestProcessedResponse_Evaluate_LargeNumbers(t *testing.T) {
+	// 2^53 + 1, which rounds to 2^53 as a float64
+	resp := &ProcessedResponse{StatusCode: 200, Body: []byte(`{"id": 9007199254740993, "price": 10.5}`)}
+
+	tests := []struct {
+		name      string
+		assertion storage.Assertion
+		pass      bool
+	}{
+		{"equals", storage.Assertion{Target: "json", Property: "id", Op: "equals", Value: "9007199254740993"}, true},
+		{"equals the rounded value", storage.Assertion{Target: "json", Property: "id", Op: "equals", Value: "9007199254740992"}, false},
+		{"below itself", storage.Assertion{Target: "json", Property: "id", Op: "below", Value: "9007199254740993"}, false},
+		{"below the next", storage.Assertion{Target: "json", Property: "id", Op: "below", Value: "9007199254740994"}, true},
+		{"in range", storage.Assertion{Target: "json", Property: "id", Op: "in_range", Value: "9007199254740993-9007199254740999"}, true},
+		{"out of range", storage.Assertion{Target: "json", Property: "id", Op: "in_range", Value: "9007199254740980-9007199254740992"}, false},
+		{"decimal equals", storage.Assertion{Target: "json", Property: "price", Op: "equals", Value: "10.50"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := resp.Evaluate([]storage.Assertion{tt.assertion})
+			if results[0].Passed != tt.pass {
+				t.Errorf("Passed = %v, want %v (message: %s)", results[0].Passed, tt.pass, results[0].Message)
+			}
+		})
+	}
+}
+
+func TestProcessedResponse_Evaluate_NegativeRanges(t *testing.T) {
+	resp := &ProcessedResponse{StatusCode: 200, Body: []byte(`{"delta": -5, "drift": -0.25}`)}
+
+	tests := []struct {
+		name      string
+		assertion storage.Assertion
+		pass      bool
+	}{
+		{"negative to zero", storage.Assertion{Target: "json", Property: "delta", Op: "in_range", Value: "-10-0"}, true},
+		{"both negative", storage.Assertion{Target: "json", Property: "delta", Op: "in_range", Value: "-5--1"}, true},
+		{"spaced", storage.Assertion{Target: "json", Property: "delta", Op: "in_range", Value: "-10 - -6"}, false},
+		{"decimal bounds", storage.Assertion{Target: "json", Property: "drift", Op: "in_range", Value: "-0.5--0.1"}, true},
+		{"exponent bounds", storage.Assertion{Target: "json", Property: "drift", Op: "in_range", Value: "-1e0-1e-1"}, true},
+		{"no upper bound", storage.Assertion{Target: "json", Property: "delta", Op: "in_range", Value: "-10-"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := resp.Evaluate([]storage.Assertion{tt.assertion})
+			if results[0].Passed != tt.pass {
+				t.Errorf("Passed = %v, want %v (message: %s)", results[0].Passed, tt.pass, results[0].Message)
+			}
+		})
+	}
+	if _, _, err := parseRange("-10-"); err == nil {
+		t.Error("Expected an error for a range without an upper bound")
+	}
+}
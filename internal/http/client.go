@@ -4,11 +4,15 @@ package http
 import (
 	"context"
 	"errors"
+	"io"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
 
+	"github.com/styltsou/tapi/internal/cookies"
 	"github.com/styltsou/tapi/internal/logger"
+	"github.com/styltsou/tapi/internal/oauth"
 	"github.com/styltsou/tapi/internal/storage"
 )
 
@@ -17,11 +21,26 @@ var (
 	ErrInvalidURL = errors.New("invalid URL")
 	ErrTimeout    = errors.New("request timeout")
 	ErrNetwork    = errors.New("network error")
+	ErrCancelled  = errors.New("request cancelled")
+	ErrAuth       = errors.New("authentication failed")
 )
 
 // Client wraps http.Client with custom configuration
 type Client struct {
 	HTTPClient *http.Client
+	Timeout    time.Duration  // default per-request timeout, overridable per request
+	Tokens     *oauth.Manager // access tokens for oauth2 auth, nil if not configured
+	Cookies    *cookies.Jar   // cookies kept between requests, nil when the jar is off
+
+	// CaptureWire records every request and response as sent and received,
+	// see ProcessedResponse.Wire
+	CaptureWire bool
+
+	// MaxBodySize truncates bodies read into memory, MaxResponseBodySize
+	// when 0. Downloads are not limited.
+	MaxBodySize int64
+
+	transports *transportCache // shared by the copies made by WithTransport
 }
 
 // NewClient creates a new HTTP client with safe defaults and configurable timeout
@@ -29,32 +48,38 @@ func NewClient(timeout time.Duration) *Client {
 	if timeout == 0 {
 		timeout = 30 * time.Second
 	}
+	// Redirects are followed as each request asks, see redirectRecorder
 	return &Client{
-		HTTPClient: &http.Client{
-			Timeout: timeout,
-			CheckRedirect: func(req *http.Request, via []*http.Request) error {
-				// Allow up to 10 redirects
-				if len(via) >= 10 {
-					return errors.New("stopped after 10 redirects")
-				}
-				return nil
-			},
-		},
+		Timeout:    timeout,
+		transports: &transportCache{transports: make(map[string]http.RoundTripper)},
+		HTTPClient: &http.Client{},
 	}
 }
 
-// Execute performs an HTTP request with timeout and error handling
-func (c *Client) Execute(req storage.Request, baseURL string) (*ProcessedResponse, error) {
-	// Create context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-
-	// Resolve URL robustly
-	fullURL, err := ResolveURL(baseURL, req.URL)
-	if err != nil {
-		return nil, err
+// httpClientFor returns the client sending req: redirects go through the
+// recorder, and the cookie jar is used when it is on. Requests that don't
+// send cookies still store the ones they receive.
+func (c *Client) httpClientFor(req storage.Request, redirects *redirectRecorder) *http.Client {
+	client := *c.HTTPClient
+	client.CheckRedirect = redirects.check
+	if c.Cookies != nil {
+		client.Jar = c.Cookies
+		if req.NoCookies {
+			client.Jar = receiveOnly{c.Cookies}
+		}
 	}
+	return &client
+}
+
+// receiveOnly is a cookie jar that stores cookies but never sends them
+type receiveOnly struct {
+	http.CookieJar
+}
+
+func (receiveOnly) Cookies(*url.URL) []*http.Cookie { return nil }
 
+// newHTTPRequest builds the request to send, with its headers and auth
+func newHTTPRequest(ctx context.Context, req storage.Request, fullURL string) (*http.Request, error) {
 	// Create HTTP request with context
 	httpReq, err := http.NewRequestWithContext(ctx, req.Method, fullURL, strings.NewReader(req.Body))
 	if err != nil {
@@ -62,39 +87,255 @@ func (c *Client) Execute(req storage.Request, baseURL string) (*ProcessedRespons
 		return nil, ErrInvalidURL
 	}
 
-	// Inject headers
-	for key, value := range req.Headers {
-		httpReq.Header.Set(key, value)
+	// Inject headers in order; repeated keys are sent once per entry
+	for _, h := range req.Headers.Enabled() {
+		if h.Key != "" {
+			httpReq.Header.Add(h.Key, h.Value)
+		}
 	}
 
-	// Apply Basic Auth if configured
-	if req.Auth != nil && req.Auth.Username != "" {
-		httpReq.SetBasicAuth(req.Auth.Username, req.Auth.Password)
+	if req.Auth != nil {
+		applyAuth(httpReq, *req.Auth)
+	}
+	return httpReq, nil
+}
+
+// applyAuth adds the credentials that are sent with the request itself.
+// Digest is answered after the server's challenge, see retryWithDigest.
+func applyAuth(httpReq *http.Request, auth storage.Auth) {
+	switch auth.EffectiveType() {
+	case storage.AuthBasic:
+		if auth.Username != "" {
+			httpReq.SetBasicAuth(auth.Username, auth.Password)
+		}
+	case storage.AuthBearer:
+		if auth.Token != "" {
+			httpReq.Header.Set("Authorization", "Bearer "+auth.Token)
+		}
+	case storage.AuthAPIKey:
+		if auth.Key == "" {
+			return
+		}
+		if auth.EffectiveIn() == storage.InQuery {
+			param := url.QueryEscape(auth.Key) + "=" + url.QueryEscape(auth.Value)
+			if httpReq.URL.RawQuery != "" {
+				param = "&" + param
+			}
+			httpReq.URL.RawQuery += param
+		} else {
+			httpReq.Header.Set(auth.Key, auth.Value)
+		}
+	}
+}
+
+// AccessToken returns a token for an oauth2 auth from the token manager. The
+// token requests go through the same proxy and TLS settings as the requests
+// of the client, see WithTransport.
+func (c *Client) AccessToken(ctx context.Context, config storage.Auth, forceRefresh bool) (oauth.Token, error) {
+	if c.Tokens == nil {
+		return oauth.Token{}, errors.New("oauth2 is not available")
+	}
+	client := &http.Client{Transport: c.HTTPClient.Transport}
+	if c.Tokens.HTTPClient != nil {
+		*client = *c.Tokens.HTTPClient
+		client.Transport = c.HTTPClient.Transport
+	}
+	return c.Tokens.Token(oauth.WithHTTPClient(ctx, client), config, forceRefresh)
+}
+
+// withAccessToken returns req with its oauth2 auth replaced by a bearer token
+func (c *Client) withAccessToken(ctx context.Context, req storage.Request, config storage.Auth, forceRefresh bool) (storage.Request, error) {
+	token, err := c.AccessToken(ctx, config, forceRefresh)
+	if err != nil {
+		switch ctx.Err() {
+		case context.DeadlineExceeded:
+			return req, ErrTimeout
+		case context.Canceled:
+			return req, ErrCancelled
+		}
+		return req, errors.Join(ErrAuth, err)
+	}
+	req.Auth = &storage.Auth{Type: storage.AuthBearer, Token: token.AccessToken}
+	return req, nil
+}
+
+// retryWithDigest sends the request again with the answer to the Digest
+// challenge of the 401 response. If the challenge can't be answered the 401
+// response is returned as is.
+func (c *Client) retryWithDigest(ctx context.Context, httpClient *http.Client, req storage.Request, fullURL string, challenge map[string]string, unauthorized *http.Response) (*http.Response, error) {
+	httpReq, err := newHTTPRequest(ctx, req, fullURL)
+	if err != nil {
+		return nil, err
+	}
+	authorization, err := digestAuthorization(challenge, httpReq.Method, httpReq.URL.RequestURI(), req.Auth.Username, req.Auth.Password, req.Body)
+	if err != nil {
+		logger.Logger.Warn("Cannot answer digest challenge", "url", fullURL, "error", err)
+		return unauthorized, nil
+	}
+	httpReq.Header.Set("Authorization", authorization)
+
+	io.Copy(io.Discard, unauthorized.Body)
+	unauthorized.Body.Close()
+	return httpClient.Do(httpReq)
+}
+
+// Execute performs an HTTP request with timeout and error handling.
+// The request is aborted when ctx is cancelled; the timeout comes from the
+// request itself if set, otherwise from the client.
+func (c *Client) Execute(ctx context.Context, req storage.Request, baseURL string) (*ProcessedResponse, error) {
+	return c.execute(ctx, req, baseURL, nil)
+}
+
+// Download performs an HTTP request like Execute, saving a successful
+// response body to dl.Path as it arrives. The timeout only bounds the wait
+// for the response, not the transfer; a cancelled transfer leaves the
+// partial file, which a Download with Resume continues.
+func (c *Client) Download(ctx context.Context, req storage.Request, baseURL string, dl Download) (*ProcessedResponse, error) {
+	return c.execute(ctx, req, baseURL, &dl)
+}
+
+func (c *Client) execute(ctx context.Context, req storage.Request, baseURL string, dl *Download) (*ProcessedResponse, error) {
+	var offset int64
+	if dl != nil {
+		var err error
+		if req, offset, err = dl.prepare(req); err != nil {
+			return nil, err
+		}
+	}
+
+	// Resolve URL robustly
+	fullURL, err := ResolveURL(baseURL, req.FullURL())
+	if err != nil {
+		return nil, err
+	}
+	// A unix:// URL is sent as http://localhost over the socket
+	socket, httpURL, unix := storage.SplitUnixURL(fullURL)
+	if unix {
+		fullURL = httpURL
+	}
+
+	// OAuth2 is sent as a bearer token obtained (or taken from the cache)
+	// first. Getting it may wait for a sign-in in the browser, so it is not
+	// bounded by the request timeout.
+	oauth2 := req.Auth != nil && req.Auth.EffectiveType() == storage.AuthOAuth2
+	var oauth2Config storage.Auth
+	tokenCtx := ctx
+	if oauth2 {
+		oauth2Config = *req.Auth
+		if req, err = c.withAccessToken(tokenCtx, req, oauth2Config, false); err != nil {
+			return nil, err
+		}
+	}
+
+	timeout := c.Timeout
+	if req.Timeout > 0 {
+		timeout = req.TimeoutDuration()
+	}
+	// The timeout of a download is stopped once the response arrives
+	gotResponse := func() {}
+	if timeout > 0 && dl != nil {
+		var cancel context.CancelCauseFunc
+		ctx, cancel = context.WithCancelCause(ctx)
+		defer cancel(nil)
+		timer := time.AfterFunc(timeout, func() { cancel(context.DeadlineExceeded) })
+		gotResponse = func() { timer.Stop() }
+	} else if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	// Only the request itself is traced, not the token request above
+	trace := &tracer{}
+	traced := trace.withTrace(ctx)
+
+	httpReq, err := newHTTPRequest(traced, req, fullURL)
+	if err != nil {
+		return nil, err
+	}
+
+	// Signing comes last so the signature covers the request as sent
+	if err := signRequest(httpReq, req.Auth, req.Body, time.Now()); err != nil {
+		logger.Logger.Warn("Cannot sign request", "url", fullURL, "error", err)
+		return nil, errors.Join(ErrAuth, err)
 	}
 
 	// Execute request and measure time
+	redirects := &redirectRecorder{req: req}
+	httpClient := c.httpClientFor(req, redirects)
+	if unix {
+		viaSocket := *httpClient
+		viaSocket.Transport = c.unixTransport(httpClient.Transport, socket)
+		httpClient = &viaSocket
+	}
+	var recorder *wireRecorder
+	if c.CaptureWire {
+		httpClient, recorder = withWireRecorder(httpClient)
+	}
 	start := time.Now()
-	resp, err := c.HTTPClient.Do(httpReq)
+	trace.start = start
+	redirects.start(start)
+	resp, err := httpClient.Do(httpReq)
+
+	// A rejected access token is renewed and the request sent again once
+	if err == nil && resp.StatusCode == http.StatusUnauthorized && oauth2 {
+		if retry, terr := c.withAccessToken(tokenCtx, req, oauth2Config, true); terr != nil {
+			logger.Logger.Warn("Cannot renew the access token", "url", fullURL, "error", terr)
+		} else if retryReq, rerr := newHTTPRequest(traced, retry, fullURL); rerr == nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			redirects.start(time.Now())
+			resp, err = httpClient.Do(retryReq)
+		}
+	}
+
+	// Digest credentials can only be computed from the server's challenge,
+	// so the request is sent again once with the answer
+	if err == nil && resp.StatusCode == http.StatusUnauthorized && req.Auth != nil && req.Auth.EffectiveType() == storage.AuthDigest {
+		if challenge, ok := digestChallenge(resp); ok {
+			redirects.start(time.Now())
+			resp, err = c.retryWithDigest(traced, httpClient, req, fullURL, challenge, resp)
+		}
+	}
 
 	// Error handling
 	if err != nil {
 		duration := time.Since(start)
-		// Check if context was cancelled (timeout)
-		if ctx.Err() == context.DeadlineExceeded {
+		// Check if context was cancelled (timeout or user abort)
+		if context.Cause(ctx) == context.DeadlineExceeded {
 			logger.Logger.Info("Request timeout", "url", fullURL, "duration", duration)
 			return nil, ErrTimeout
 		}
+		if ctx.Err() == context.Canceled {
+			logger.Logger.Info("Request cancelled", "url", fullURL, "duration", duration)
+			return nil, ErrCancelled
+		}
 
 		// Network-related errors (DNS, connection refused, etc.)
 		logger.Logger.Info("Network error", "url", fullURL, "error", err)
 		return nil, errors.Join(ErrNetwork, err)
 	}
 
+	gotResponse()
+
 	// Process response using internal/http/response.go
-	processed, err := ProcessResponse(resp, start)
+	processed, err := c.readBody(resp, start, dl, offset)
 	if err != nil {
+		// The body read can also be interrupted by a timeout or abort
+		switch context.Cause(ctx) {
+		case context.DeadlineExceeded:
+			return nil, ErrTimeout
+		case context.Canceled:
+			return nil, ErrCancelled
+		}
 		return nil, err
 	}
+	processed.Request = newSentRequest(resp.Request, req.Body)
+	processed.Timings = trace.timings(start.Add(processed.Duration))
+	processed.Redirects = redirects.hops
+	if recorder != nil {
+		processed.Wire = recorder.dump()
+	}
 
 	logger.Logger.Info("Request completed",
 		"method", req.Method,
@@ -1,19 +1,28 @@
 package http
 
 import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/styltsou/tapi/internal/cookies"
 	"github.com/styltsou/tapi/internal/logger"
+	"github.com/styltsou/tapi/internal/oauth"
 	"github.com/styltsou/tapi/internal/storage"
 )
 
 func TestMain(m *testing.M) {
-	_ = logger.Init()
+	logger.InitDiscard()
 	os.Exit(m.Run())
 }
 
@@ -71,7 +80,7 @@ func TestClient_Execute_URLResolution(t *testing.T) {
 				Method: "GET",
 				URL:    tt.reqURL,
 			}
-			_, err := client.Execute(req, tt.baseURL)
+			_, err := client.Execute(context.Background(), req, tt.baseURL)
 			if err != nil {
 				t.Fatalf("Execute failed: %v", err)
 			}
@@ -95,13 +104,13 @@ func TestClient_Execute_RequestData(t *testing.T) {
 	req := storage.Request{
 		Method: "POST",
 		URL:    server.URL,
-		Headers: map[string]string{
-			"X-Test": "Value",
+		Headers: storage.Params{
+			{Key: "X-Test", Value: "Value", Enabled: true},
 		},
 		Body: "test-body",
 	}
 
-	_, err := client.Execute(req, "")
+	_, err := client.Execute(context.Background(), req, "")
 	if err != nil {
 		t.Fatalf("Execute failed: %v", err)
 	}
@@ -115,6 +124,48 @@ func TestClient_Execute_RequestData(t *testing.T) {
 	}
 }
 
+func TestClient_Execute_OrderedParams(t *testing.T) {
+	var accept []string
+	var query, debug string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accept = r.Header.Values("Accept")
+		debug = r.Header.Get("X-Debug")
+		query = r.URL.RawQuery
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewClient(10 * time.Second)
+	req := storage.Request{
+		Method: "GET",
+		URL:    server.URL + "/search?fixed=1",
+		Query: storage.Params{
+			{Key: "q", Value: "a b", Enabled: true},
+			{Key: "debug", Value: "true", Enabled: false},
+			{Key: "q", Value: "c", Enabled: true},
+		},
+		Headers: storage.Params{
+			{Key: "Accept", Value: "application/json", Enabled: true},
+			{Key: "X-Debug", Value: "1", Enabled: false},
+			{Key: "Accept", Value: "text/plain", Enabled: true},
+		},
+	}
+
+	if _, err := client.Execute(context.Background(), req, ""); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	if strings.Join(accept, ",") != "application/json,text/plain" {
+		t.Errorf("Expected both Accept headers in order, got %v", accept)
+	}
+	if debug != "" {
+		t.Errorf("Disabled header was sent: %s", debug)
+	}
+	if query != "fixed=1&q=a+b&q=c" {
+		t.Errorf("Expected query fixed=1&q=a+b&q=c, got %s", query)
+	}
+}
+
 func TestClient_Execute_BasicAuth(t *testing.T) {
 	var capturedAuth string
 
@@ -130,13 +181,13 @@ func TestClient_Execute_BasicAuth(t *testing.T) {
 	req := storage.Request{
 		Method: "GET",
 		URL:    server.URL,
-		Auth: &storage.BasicAuth{
+		Auth: &storage.Auth{
 			Username: "admin",
 			Password: "secret123",
 		},
 	}
 
-	_, err := client.Execute(req, "")
+	_, err := client.Execute(context.Background(), req, "")
 	if err != nil {
 		t.Fatalf("Execute failed: %v", err)
 	}
@@ -154,7 +205,7 @@ func TestClient_Execute_BasicAuth(t *testing.T) {
 		Method: "GET",
 		URL:    server.URL,
 	}
-	_, err = client.Execute(reqNoAuth, "")
+	_, err = client.Execute(context.Background(), reqNoAuth, "")
 	if err != nil {
 		t.Fatalf("Execute failed: %v", err)
 	}
@@ -162,3 +213,307 @@ func TestClient_Execute_BasicAuth(t *testing.T) {
 		t.Errorf("Expected no Authorization header, got: %s", capturedAuth)
 	}
 }
+
+func TestClient_Execute_AuthTypes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Authorization", r.Header.Get("Authorization"))
+		w.Header().Set("X-Api-Key", r.Header.Get("X-Api-Key"))
+		w.Header().Set("X-Query", r.URL.RawQuery)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name      string
+		auth      storage.Auth
+		wantAuth  string
+		wantKey   string
+		wantQuery string
+	}{
+		{"bearer", storage.Auth{Type: storage.AuthBearer, Token: "abc"}, "Bearer abc", "", "page=1"},
+		{"api key header", storage.Auth{Type: storage.AuthAPIKey, Key: "X-Api-Key", Value: "k1"}, "", "k1", "page=1"},
+		{"api key query", storage.Auth{Type: storage.AuthAPIKey, Key: "api key", Value: "k&2", In: storage.InQuery}, "", "", "page=1&api+key=k%262"},
+	}
+
+	client := NewClient(10 * time.Second)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth := tt.auth
+			req := storage.Request{
+				Method: "GET",
+				URL:    server.URL,
+				Query:  storage.Params{{Key: "page", Value: "1", Enabled: true}},
+				Auth:   &auth,
+			}
+			resp, err := client.Execute(context.Background(), req, "")
+			if err != nil {
+				t.Fatalf("Execute failed: %v", err)
+			}
+			if got := resp.GetHeader("X-Authorization"); got != tt.wantAuth {
+				t.Errorf("Expected Authorization %q, got %q", tt.wantAuth, got)
+			}
+			if got := resp.GetHeader("X-Api-Key"); got != tt.wantKey {
+				t.Errorf("Expected X-Api-Key %q, got %q", tt.wantKey, got)
+			}
+			if got := resp.GetHeader("X-Query"); got != tt.wantQuery {
+				t.Errorf("Expected query %q, got %q", tt.wantQuery, got)
+			}
+		})
+	}
+}
+
+func TestClient_Execute_DigestAuth(t *testing.T) {
+	const realm, nonce, user, pass = "tapi", "n0nce", "alice", "wonderland"
+	md5hex := func(s string) string {
+		sum := md5.Sum([]byte(s))
+		return hex.EncodeToString(sum[:])
+	}
+
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		header := r.Header.Get("Authorization")
+		if !strings.HasPrefix(header, "Digest ") {
+			w.Header().Add("WWW-Authenticate", `Basic realm="other"`)
+			w.Header().Add("WWW-Authenticate", `Digest realm="`+realm+`", nonce="`+nonce+`", qop="auth,auth-int", opaque="xyz"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		p := parseAuthParams(strings.TrimPrefix(header, "Digest "))
+		ha1 := md5hex(user + ":" + realm + ":" + pass)
+		ha2 := md5hex(r.Method + ":" + p["uri"])
+		want := md5hex(ha1 + ":" + nonce + ":" + p["nc"] + ":" + p["cnonce"] + ":" + p["qop"] + ":" + ha2)
+		if p["response"] != want || p["opaque"] != "xyz" || p["uri"] != r.URL.RequestURI() {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewClient(10 * time.Second)
+	req := storage.Request{
+		Method: "POST",
+		URL:    server.URL + "/login?next=home",
+		Body:   `{"remember":true}`,
+		Auth:   &storage.Auth{Type: storage.AuthDigest, Username: user, Password: pass},
+	}
+	resp, err := client.Execute(context.Background(), req, "")
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200 after the digest round trip, got %d", resp.StatusCode)
+	}
+	if n := atomic.LoadInt32(&attempts); n != 2 {
+		t.Errorf("Expected 2 attempts, got %d", n)
+	}
+
+	// Wrong credentials end with the server's answer, without looping
+	atomic.StoreInt32(&attempts, 0)
+	req.Auth = &storage.Auth{Type: storage.AuthDigest, Username: user, Password: "nope"}
+	resp, err = client.Execute(context.Background(), req, "")
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if resp.StatusCode != http.StatusForbidden || atomic.LoadInt32(&attempts) != 2 {
+		t.Errorf("Expected a single retry answered with 403, got %d after %d attempts", resp.StatusCode, attempts)
+	}
+}
+
+func TestClient_Execute_OAuth2(t *testing.T) {
+	var issued int32
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&issued, 1)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token":"token-%d","token_type":"Bearer","expires_in":3600}`, n)
+	}))
+	defer tokenServer.Close()
+
+	// The API only accepts the second token, as if the first had been revoked
+	var calls int32
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if r.Header.Get("Authorization") != "Bearer token-2" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer api.Close()
+
+	client := NewClient(10 * time.Second)
+	req := storage.Request{
+		Method: "GET",
+		URL:    api.URL,
+		Auth:   &storage.Auth{Type: storage.AuthOAuth2, TokenURL: tokenServer.URL, ClientID: "app"},
+	}
+
+	// Without a token manager the request is not sent
+	if _, err := client.Execute(context.Background(), req, ""); !errors.Is(err, ErrAuth) {
+		t.Errorf("Expected ErrAuth without a token manager, got %v", err)
+	}
+
+	client.Tokens = oauth.NewManager(oauth.NewStore(t.TempDir()))
+	resp, err := client.Execute(context.Background(), req, "")
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || atomic.LoadInt32(&calls) != 2 || atomic.LoadInt32(&issued) != 2 {
+		t.Errorf("Expected a renewed token after the 401, got %d after %d calls and %d tokens", resp.StatusCode, calls, issued)
+	}
+
+	// The renewed token is cached
+	if _, err := client.Execute(context.Background(), req, ""); err != nil || atomic.LoadInt32(&issued) != 2 {
+		t.Errorf("Expected the cached token to be reused, %d tokens issued (%v)", issued, err)
+	}
+}
+
+func TestClient_Execute_OAuth2Transport(t *testing.T) {
+	// The token endpoint has a self-signed certificate, trusted only by the
+	// transport settings of the request
+	tokenServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"access_token":"tls-token","token_type":"Bearer","expires_in":3600}`)
+	}))
+	defer tokenServer.Close()
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Auth", r.Header.Get("Authorization"))
+	}))
+	defer api.Close()
+
+	base := NewClient(10 * time.Second)
+	base.Tokens = oauth.NewManager(oauth.NewStore(t.TempDir()))
+	req := storage.Request{
+		Method: "GET",
+		URL:    api.URL,
+		Auth:   &storage.Auth{Type: storage.AuthOAuth2, TokenURL: tokenServer.URL, ClientID: "app"},
+	}
+
+	if _, err := base.Execute(context.Background(), req, ""); !errors.Is(err, ErrAuth) {
+		t.Errorf("Expected the untrusted token endpoint to fail, got %v", err)
+	}
+	client, err := base.WithTransport(storage.Transport{Insecure: insecure()})
+	if err != nil {
+		t.Fatalf("WithTransport failed: %v", err)
+	}
+	resp, err := client.Execute(context.Background(), req, "")
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if got := resp.GetHeader("X-Auth"); got != "Bearer tls-token" {
+		t.Errorf("Expected the token fetched through the transport, got %q", got)
+	}
+}
+
+func TestParseAuthParams(t *testing.T) {
+	got := parseAuthParams(`realm="a, b", nonce=abc, qop="auth,auth-int", opaque="say \"hi\""`)
+	want := map[string]string{"realm": "a, b", "nonce": "abc", "qop": "auth,auth-int", "opaque": `say "hi"`}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s: expected %q, got %q", k, v, got[k])
+		}
+	}
+}
+
+func TestClient_Execute_CookieJar(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc", Path: "/"})
+		case "/refresh":
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "def", Path: "/"})
+		}
+		w.Header().Set("X-Cookie", r.Header.Get("Cookie"))
+	}))
+	defer server.Close()
+
+	// Without a jar nothing is kept
+	client := NewClient(10 * time.Second)
+	client.Execute(context.Background(), storage.Request{Method: "POST", URL: server.URL + "/login"}, "")
+	resp, err := client.Execute(context.Background(), storage.Request{Method: "GET", URL: server.URL + "/me"}, "")
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if got := resp.GetHeader("X-Cookie"); got != "" {
+		t.Errorf("Expected no cookie without a jar, got %q", got)
+	}
+
+	client.Cookies = cookies.NewJar(t.TempDir())
+	steps := []struct {
+		path      string
+		noCookies bool
+		want      string
+	}{
+		{"/login", false, ""},
+		{"/me", false, "session=abc"},
+		{"/refresh", true, ""}, // not sent, but the new value is stored
+		{"/me", false, "session=def"},
+	}
+	for _, step := range steps {
+		req := storage.Request{Method: "GET", URL: server.URL + step.path, NoCookies: step.noCookies}
+		resp, err := client.Execute(context.Background(), req, "")
+		if err != nil {
+			t.Fatalf("Execute %s failed: %v", step.path, err)
+		}
+		if got := resp.GetHeader("X-Cookie"); got != step.want {
+			t.Errorf("%s: expected Cookie %q, got %q", step.path, step.want, got)
+		}
+	}
+}
+
+func TestClient_Execute_Cancel(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client := NewClient(10 * time.Second)
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+
+	_, err := client.Execute(ctx, storage.Request{Method: "GET", URL: server.URL}, "")
+	if !errors.Is(err, ErrCancelled) {
+		t.Errorf("Expected ErrCancelled, got %v", err)
+	}
+}
+
+func TestClient_Execute_RequestTimeoutOverride(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	// Client default is short; the request override must win
+	client := NewClient(50 * time.Millisecond)
+	req := storage.Request{Method: "GET", URL: server.URL + "/slow"}
+
+	_, err := client.Execute(context.Background(), req, "")
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("Expected ErrTimeout with client default, got %v", err)
+	}
+
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer fast.Close()
+
+	req = storage.Request{Method: "GET", URL: fast.URL, Timeout: 2}
+	if _, err := client.Execute(context.Background(), req, ""); err != nil {
+		t.Errorf("Expected request timeout override to allow completion, got %v", err)
+	}
+}
@@ -0,0 +1,148 @@
+package http
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net/http"
+	"strings"
+)
+
+// digestChallenge returns the parameters of the Digest challenge in a 401
+// response, or false if the server did not offer Digest
+func digestChallenge(resp *http.Response) (map[string]string, bool) {
+	for _, header := range resp.Header.Values("WWW-Authenticate") {
+		scheme, params, _ := strings.Cut(strings.TrimSpace(header), " ")
+		if strings.EqualFold(scheme, "Digest") {
+			return parseAuthParams(params), true
+		}
+	}
+	return nil, false
+}
+
+// parseAuthParams splits `realm="a", nonce="b", qop="auth,auth-int"` into a
+// map, keeping commas inside quoted values
+func parseAuthParams(s string) map[string]string {
+	params := make(map[string]string)
+	for s != "" {
+		s = strings.TrimLeft(s, " ,")
+		key, rest, ok := strings.Cut(s, "=")
+		if !ok {
+			break
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		rest = strings.TrimSpace(rest)
+
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := 1
+			for end < len(rest) && rest[end] != '"' {
+				if rest[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			value = strings.ReplaceAll(rest[1:min(end, len(rest))], `\"`, `"`)
+			rest = rest[min(end+1, len(rest)):]
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+			value = strings.TrimSpace(value)
+		}
+		params[key] = value
+		s = rest
+	}
+	return params
+}
+
+// digestAuthorization answers a Digest challenge (RFC 7616) for the given
+// request. MD5, SHA-256 and their -sess variants are supported.
+func digestAuthorization(challenge map[string]string, method, uri, username, password, body string) (string, error) {
+	algorithm := challenge["algorithm"]
+	if algorithm == "" {
+		algorithm = "MD5"
+	}
+	session := strings.HasSuffix(strings.ToUpper(algorithm), "-SESS")
+	var newHash func() hash.Hash
+	switch strings.TrimSuffix(strings.ToUpper(algorithm), "-SESS") {
+	case "MD5":
+		newHash = md5.New
+	case "SHA-256":
+		newHash = sha256.New
+	default:
+		return "", fmt.Errorf("unsupported digest algorithm %q", algorithm)
+	}
+	h := func(s string) string {
+		sum := newHash()
+		sum.Write([]byte(s))
+		return hex.EncodeToString(sum.Sum(nil))
+	}
+
+	realm, nonce := challenge["realm"], challenge["nonce"]
+	cnonce, err := randomHex(16)
+	if err != nil {
+		return "", err
+	}
+	const nc = "00000001"
+
+	ha1 := h(username + ":" + realm + ":" + password)
+	if session {
+		ha1 = h(ha1 + ":" + nonce + ":" + cnonce)
+	}
+
+	// Prefer auth over auth-int, which also hashes the body
+	qop := ""
+	if offered, ok := challenge["qop"]; ok {
+		for _, q := range strings.Split(offered, ",") {
+			switch strings.TrimSpace(q) {
+			case "auth":
+				qop = "auth"
+			case "auth-int":
+				if qop == "" {
+					qop = "auth-int"
+				}
+			}
+		}
+		if qop == "" {
+			return "", fmt.Errorf("unsupported digest qop %q", offered)
+		}
+	}
+
+	ha2 := h(method + ":" + uri)
+	if qop == "auth-int" {
+		ha2 = h(method + ":" + uri + ":" + h(body))
+	}
+
+	var response string
+	if qop == "" {
+		response = h(ha1 + ":" + nonce + ":" + ha2)
+	} else {
+		response = h(ha1 + ":" + nonce + ":" + nc + ":" + cnonce + ":" + qop + ":" + ha2)
+	}
+
+	parts := []string{
+		fmt.Sprintf(`username="%s"`, username),
+		fmt.Sprintf(`realm="%s"`, realm),
+		fmt.Sprintf(`nonce="%s"`, nonce),
+		fmt.Sprintf(`uri="%s"`, uri),
+		"algorithm=" + algorithm,
+		fmt.Sprintf(`response="%s"`, response),
+	}
+	if qop != "" {
+		parts = append(parts, "qop="+qop, "nc="+nc, fmt.Sprintf(`cnonce="%s"`, cnonce))
+	}
+	if opaque, ok := challenge["opaque"]; ok {
+		parts = append(parts, fmt.Sprintf(`opaque="%s"`, opaque))
+	}
+	return "Digest " + strings.Join(parts, ", "), nil
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
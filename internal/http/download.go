@@ -0,0 +1,249 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/styltsou/tapi/internal/storage"
+)
+
+// ErrDownload is returned when a body cannot be saved to its file
+var ErrDownload = errors.New("download failed")
+
+// progressInterval is how often a download reports how far it got
+const progressInterval = 100 * time.Millisecond
+
+// Download saves a response body to a file as it is received, without the
+// size limit of bodies read into memory
+type Download struct {
+	Path     string
+	Resume   bool           // continue a partial file with a Range request
+	Progress func(Progress) // called as the body is received, may be nil
+}
+
+// Progress is how far a download got
+type Progress struct {
+	Received int64         // bytes in the file, those of a resumed part included
+	Total    int64         // expected size of the file, -1 when unknown
+	Elapsed  time.Duration // since the body started arriving
+	Rate     float64       // bytes per second over this transfer
+}
+
+// Fraction returns the share of the file received, -1 when the size is unknown
+func (p Progress) Fraction() float64 {
+	if p.Total <= 0 {
+		return -1
+	}
+	return min(1, float64(p.Received)/float64(p.Total))
+}
+
+// ETA returns the time left at the current rate, -1 when unknown
+func (p Progress) ETA() time.Duration {
+	if p.Total < 0 || p.Rate <= 0 {
+		return -1
+	}
+	left := max(0, p.Total-p.Received)
+	return time.Duration(float64(left) / p.Rate * float64(time.Second))
+}
+
+// DownloadInfo is a response body saved to a file
+type DownloadInfo struct {
+	Path    string
+	Size    int64 // size of the file
+	Resumed bool  // the body was appended to a partial file
+}
+
+// prepare adds the headers of a download to req and returns the size of the
+// partial file it continues, 0 when it starts over. The body is asked for as
+// is, so that its size is known and byte ranges match the file. The range is
+// asked for If-Range the resource is unchanged since the partial file was
+// written, otherwise the server sends it whole.
+func (d *Download) prepare(req storage.Request) (storage.Request, int64, error) {
+	req.Headers = req.Headers.Clone()
+	if req.Headers.Get("Accept-Encoding") == "" {
+		req.Headers.Add("Accept-Encoding", "identity")
+	}
+	if !d.Resume {
+		return req, 0, nil
+	}
+	info, err := os.Stat(d.Path)
+	if os.IsNotExist(err) {
+		return req, 0, nil
+	}
+	if err != nil {
+		return req, 0, errors.Join(ErrDownload, err)
+	}
+	if info.Size() > 0 {
+		req.Headers.Add("Range", fmt.Sprintf("bytes=%d-", info.Size()))
+		if validator, err := os.ReadFile(validatorPath(d.Path)); err == nil && len(validator) > 0 {
+			req.Headers.Add("If-Range", strings.TrimSpace(string(validator)))
+		}
+	}
+	return req, info.Size(), nil
+}
+
+// validatorPath is the file next to a partial download holding the ETag or
+// Last-Modified of the resource it is part of
+func validatorPath(path string) string {
+	return path + ".validator"
+}
+
+// validator returns what identifies the version of the resource in resp for
+// If-Range: a strong ETag, or else its Last-Modified date
+func validator(resp *http.Response) string {
+	if etag := resp.Header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+	return resp.Header.Get("Last-Modified")
+}
+
+// readBody reads the body of resp into memory, or into the download file
+// when there is one and resp carries the body asked for
+func (c *Client) readBody(resp *http.Response, start time.Time, dl *Download, offset int64) (*ProcessedResponse, error) {
+	if dl != nil {
+		switch resp.StatusCode {
+		case http.StatusOK, http.StatusPartialContent:
+			return dl.save(resp, start, offset)
+		case http.StatusRequestedRangeNotSatisfiable:
+			// Nothing is left past the end of a partial file that is whole
+			if _, size, ok := parseContentRange(resp.Header.Get("Content-Range")); ok && offset > 0 && size == offset {
+				processed, err := ProcessResponse(resp, start, c.MaxBodySize)
+				if err == nil {
+					processed.Download = &DownloadInfo{Path: dl.Path, Size: offset, Resumed: true}
+				}
+				return processed, err
+			}
+		}
+	}
+	return ProcessResponse(resp, start, c.MaxBodySize)
+}
+
+// save streams the body of resp to the file. A 206 answer to a Range request
+// is appended to the partial file of offset bytes; anything else replaces it.
+// A transfer cut short leaves what was received, to be resumed.
+func (d *Download) save(resp *http.Response, start time.Time, offset int64) (*ProcessedResponse, error) {
+	defer resp.Body.Close()
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	total := resp.ContentLength
+	if resp.StatusCode == http.StatusPartialContent {
+		from, size, ok := parseContentRange(resp.Header.Get("Content-Range"))
+		if !ok || from != offset {
+			return nil, errors.Join(ErrDownload, fmt.Errorf("server sent a range starting at byte %d instead of %d", from, offset))
+		}
+		flags = os.O_WRONLY | os.O_APPEND
+		total = size
+	} else {
+		offset = 0
+	}
+
+	file, err := os.OpenFile(d.Path, flags, 0644)
+	if err != nil {
+		return nil, errors.Join(ErrDownload, err)
+	}
+	// The validator is kept while the file is partial, so that resuming it
+	// can tell whether the resource changed in the meantime
+	if v := validator(resp); v != "" {
+		if err := os.WriteFile(validatorPath(d.Path), []byte(v+"\n"), 0644); err != nil {
+			file.Close()
+			return nil, errors.Join(ErrDownload, err)
+		}
+	} else {
+		os.Remove(validatorPath(d.Path))
+	}
+	meter := &progressMeter{
+		report:   d.Progress,
+		progress: Progress{Received: offset, Total: total},
+		offset:   offset,
+		started:  time.Now(),
+	}
+	n, err := io.Copy(io.MultiWriter(file, meter), resp.Body)
+	if cerr := file.Close(); err == nil && cerr != nil {
+		err = errors.Join(ErrDownload, cerr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	os.Remove(validatorPath(d.Path))
+	meter.flush()
+
+	return &ProcessedResponse{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		Headers:    resp.Header.Clone(),
+		Duration:   time.Since(start),
+		Size:       n,
+		TLS:        newTLSInfo(resp),
+		Download:   &DownloadInfo{Path: d.Path, Size: offset + n, Resumed: offset > 0},
+	}, nil
+}
+
+// progressMeter counts the bytes written through it and reports them at
+// most every progressInterval
+type progressMeter struct {
+	report   func(Progress)
+	progress Progress
+	offset   int64 // bytes already in the file before this transfer
+	started  time.Time
+	reported time.Time
+}
+
+func (m *progressMeter) Write(p []byte) (int, error) {
+	m.progress.Received += int64(len(p))
+	if time.Since(m.reported) >= progressInterval {
+		m.flush()
+	}
+	return len(p), nil
+}
+
+// flush reports the progress so far
+func (m *progressMeter) flush() {
+	if m.report == nil {
+		return
+	}
+	now := time.Now()
+	m.progress.Elapsed = now.Sub(m.started)
+	if seconds := m.progress.Elapsed.Seconds(); seconds > 0 {
+		m.progress.Rate = float64(m.progress.Received-m.offset) / seconds
+	}
+	m.reported = now
+	m.report(m.progress)
+}
+
+// parseContentRange reads a Content-Range header, "bytes 100-199/200" or
+// "bytes */200". first is -1 for the latter and size -1 when unknown.
+func parseContentRange(header string) (first, size int64, ok bool) {
+	spec, found := strings.CutPrefix(header, "bytes ")
+	if !found {
+		return 0, 0, false
+	}
+	byteRange, length, found := strings.Cut(spec, "/")
+	if !found {
+		return 0, 0, false
+	}
+	size = -1
+	if length != "*" {
+		var err error
+		if size, err = strconv.ParseInt(length, 10, 64); err != nil {
+			return 0, 0, false
+		}
+	}
+	if byteRange == "*" {
+		return -1, size, true
+	}
+	from, _, found := strings.Cut(byteRange, "-")
+	if !found {
+		return 0, 0, false
+	}
+	first, err := strconv.ParseInt(from, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return first, size, true
+}
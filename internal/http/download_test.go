@@ -0,0 +1,306 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/styltsou/tapi/internal/storage"
+)
+
+// fileServer serves content with Range support, as a static file server does
+func fileServer(content []byte) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "file.bin", time.Time{}, bytes.NewReader(content))
+	}))
+}
+
+func TestClient_Download(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 100_000)
+	server := fileServer(content)
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "file.bin")
+	var reports []Progress
+	client := NewClient(5 * time.Second)
+	client.MaxBodySize = 1024
+	resp, err := client.Download(context.Background(), storage.Request{Method: "GET", URL: server.URL}, "", Download{
+		Path:     path,
+		Progress: func(p Progress) { reports = append(reports, p) },
+	})
+	if err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+
+	saved, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(saved, content) {
+		t.Errorf("Expected the whole body of %d bytes in the file, got %d", len(content), len(saved))
+	}
+	if resp.Download == nil || resp.Download.Path != path || resp.Download.Size != int64(len(content)) || resp.Download.Resumed {
+		t.Errorf("Expected the download of %d bytes to %s, got %+v", len(content), path, resp.Download)
+	}
+	if len(resp.Body) != 0 || resp.Truncated || resp.Size != int64(len(content)) {
+		t.Errorf("Expected no body in memory and the size received, got %d bytes (size %d, truncated %v)", len(resp.Body), resp.Size, resp.Truncated)
+	}
+	if len(reports) == 0 {
+		t.Fatal("Expected progress reports")
+	}
+	last := reports[len(reports)-1]
+	if last.Received != int64(len(content)) || last.Total != int64(len(content)) || last.Fraction() != 1 || last.ETA() != 0 {
+		t.Errorf("Expected the last report to be complete, got %+v", last)
+	}
+}
+
+func TestClient_Download_Resume(t *testing.T) {
+	content := []byte(strings.Repeat("abcdefghij", 1000))
+	server := fileServer(content)
+	defer server.Close()
+	client := NewClient(5 * time.Second)
+	req := storage.Request{Method: "GET", URL: server.URL}
+
+	// A partial file is continued
+	path := filepath.Join(t.TempDir(), "file.bin")
+	if err := os.WriteFile(path, content[:4000], 0644); err != nil {
+		t.Fatal(err)
+	}
+	var first Progress
+	resp, err := client.Download(context.Background(), req, "", Download{
+		Path:   path,
+		Resume: true,
+		Progress: func(p Progress) {
+			if first.Total == 0 {
+				first = p
+			}
+		},
+	})
+	if err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+	if resp.StatusCode != http.StatusPartialContent || resp.Size != 6000 || !resp.Download.Resumed {
+		t.Errorf("Expected the remaining 6000 bytes as partial content, got %d with %d bytes (%+v)", resp.StatusCode, resp.Size, resp.Download)
+	}
+	if got := resp.Request.Headers.Get("Range"); got != "bytes=4000-" {
+		t.Errorf("Expected Range bytes=4000-, got %q", got)
+	}
+	if first.Total != int64(len(content)) || first.Received < 4000 {
+		t.Errorf("Expected progress to count the partial file, got %+v", first)
+	}
+	if saved, _ := os.ReadFile(path); !bytes.Equal(saved, content) {
+		t.Errorf("Expected the file to be completed, got %d bytes", len(saved))
+	}
+
+	// A file that is already whole is left as is
+	resp, err = client.Download(context.Background(), req, "", Download{Path: path, Resume: true})
+	if err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+	if resp.StatusCode != http.StatusRequestedRangeNotSatisfiable || resp.Download == nil || resp.Download.Size != int64(len(content)) {
+		t.Errorf("Expected the complete file to be reported, got %d (%+v)", resp.StatusCode, resp.Download)
+	}
+	if saved, _ := os.ReadFile(path); !bytes.Equal(saved, content) {
+		t.Errorf("Expected the file to be unchanged, got %d bytes", len(saved))
+	}
+
+	// A server without Range support sends everything, which replaces the file
+	plain := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(content)
+	}))
+	defer plain.Close()
+	if err := os.WriteFile(path, []byte("stale"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	resp, err = client.Download(context.Background(), storage.Request{Method: "GET", URL: plain.URL}, "", Download{Path: path, Resume: true})
+	if err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+	if saved, _ := os.ReadFile(path); !bytes.Equal(saved, content) || resp.Download.Resumed {
+		t.Errorf("Expected the file to be replaced, got %d bytes (%+v)", len(saved), resp.Download)
+	}
+}
+
+func TestClient_Download_ResumeChanged(t *testing.T) {
+	versions := map[string][]byte{
+		`"v1"`: []byte(strings.Repeat("1", 5000)),
+		`"v2"`: []byte(strings.Repeat("2", 6000)),
+	}
+	etag, stall := `"v1"`, true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", etag)
+		if stall {
+			// Send part of the body, then wait for the client to give up
+			w.Header().Set("Content-Length", "5000")
+			w.Write(versions[etag][:1000])
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+			return
+		}
+		http.ServeContent(w, r, "file.bin", time.Time{}, bytes.NewReader(versions[etag]))
+	}))
+	defer server.Close()
+	client := NewClient(5 * time.Second)
+	req := storage.Request{Method: "GET", URL: server.URL}
+	path := filepath.Join(t.TempDir(), "file.bin")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	_, err := client.Download(ctx, req, "", Download{
+		Path: path,
+		Progress: func(p Progress) {
+			if p.Received >= 1000 {
+				cancel()
+			}
+		},
+	})
+	cancel()
+	if !errors.Is(err, ErrCancelled) {
+		t.Fatalf("Expected ErrCancelled, got %v", err)
+	}
+
+	// The resource changed since: the range doesn't apply and the file is rewritten
+	etag, stall = `"v2"`, false
+	resp, err := client.Download(context.Background(), req, "", Download{Path: path, Resume: true})
+	if err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+	if got := resp.Request.Headers.Get("If-Range"); got != `"v1"` {
+		t.Errorf("Expected If-Range \"v1\", got %q", got)
+	}
+	if resp.StatusCode != http.StatusOK || resp.Download.Resumed {
+		t.Errorf("Expected the whole new version, got %d (%+v)", resp.StatusCode, resp.Download)
+	}
+	if saved, _ := os.ReadFile(path); !bytes.Equal(saved, versions[`"v2"`]) {
+		t.Errorf("Expected the file to hold the new version, got %d bytes", len(saved))
+	}
+	if _, err := os.Stat(validatorPath(path)); !os.IsNotExist(err) {
+		t.Errorf("Expected the validator to be removed with the file complete, got %v", err)
+	}
+
+	// An unchanged resource is continued
+	if err := os.WriteFile(path, versions[`"v2"`][:2000], 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(validatorPath(path), []byte(`"v2"`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	resp, err = client.Download(context.Background(), req, "", Download{Path: path, Resume: true})
+	if err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+	if resp.StatusCode != http.StatusPartialContent || !resp.Download.Resumed {
+		t.Errorf("Expected the rest as partial content, got %d (%+v)", resp.StatusCode, resp.Download)
+	}
+	if saved, _ := os.ReadFile(path); !bytes.Equal(saved, versions[`"v2"`]) {
+		t.Errorf("Expected the file to be completed, got %d bytes", len(saved))
+	}
+}
+
+func TestClient_Download_Cancel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "1000000")
+		w.Write(bytes.Repeat([]byte("x"), 1000))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "file.bin")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_, err := NewClient(5*time.Second).Download(ctx, storage.Request{Method: "GET", URL: server.URL}, "", Download{
+		Path: path,
+		Progress: func(p Progress) {
+			if p.Received >= 1000 {
+				cancel()
+			}
+		},
+	})
+	if !errors.Is(err, ErrCancelled) {
+		t.Fatalf("Expected ErrCancelled, got %v", err)
+	}
+	if saved, _ := os.ReadFile(path); len(saved) != 1000 {
+		t.Errorf("Expected the partial file to be kept, got %d bytes", len(saved))
+	}
+}
+
+func TestClient_Download_Timeout(t *testing.T) {
+	// The timeout bounds the wait for the response, not the transfer
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("start "))
+		w.(http.Flusher).Flush()
+		time.Sleep(300 * time.Millisecond)
+		w.Write([]byte("end"))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "file.bin")
+	client := NewClient(100 * time.Millisecond)
+	req := storage.Request{Method: "GET", URL: server.URL}
+	if _, err := client.Download(context.Background(), req, "", Download{Path: path}); err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+	if saved, _ := os.ReadFile(path); string(saved) != "start end" {
+		t.Errorf("Expected the whole body, got %q", saved)
+	}
+
+	if _, err := client.Execute(context.Background(), req, ""); !errors.Is(err, ErrTimeout) {
+		t.Errorf("Expected Execute to time out, got %v", err)
+	}
+
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(300 * time.Millisecond)
+	}))
+	defer slow.Close()
+	if _, err := client.Download(context.Background(), storage.Request{Method: "GET", URL: slow.URL}, "", Download{Path: path}); !errors.Is(err, ErrTimeout) {
+		t.Errorf("Expected a download waiting too long for the response to time out, got %v", err)
+	}
+}
+
+func TestClient_Download_ErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "no such file", http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "file.bin")
+	resp, err := NewClient(5*time.Second).Download(context.Background(), storage.Request{Method: "GET", URL: server.URL}, "", Download{Path: path})
+	if err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+	if resp.Download != nil || !strings.Contains(resp.BodyString(), "no such file") {
+		t.Errorf("Expected the error body to be read, got %q (%+v)", resp.Body, resp.Download)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected no file for an error response, got %v", err)
+	}
+}
+
+func TestParseContentRange(t *testing.T) {
+	tests := []struct {
+		header string
+		first  int64
+		size   int64
+		ok     bool
+	}{
+		{"bytes 100-199/200", 100, 200, true},
+		{"bytes 0-99/*", 0, -1, true},
+		{"bytes */200", -1, 200, true},
+		{"bytes 100-199", 0, 0, false},
+		{"items 0-9/10", 0, 0, false},
+		{"", 0, 0, false},
+	}
+	for _, tt := range tests {
+		first, size, ok := parseContentRange(tt.header)
+		if first != tt.first || size != tt.size || ok != tt.ok {
+			t.Errorf("parseContentRange(%q) = %d, %d, %v, want %d, %d, %v", tt.header, first, size, ok, tt.first, tt.size, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"github.com/styltsou/tapi/internal/storage"
+)
+
+// Extract applies the extraction rules to the response and returns the captured
+// variables. Rules that cannot be satisfied are skipped and reported in the error.
+func (r *ProcessedResponse) Extract(rules []storage.Extraction) (map[string]string, error) {
+	vars := make(map[string]string, len(rules))
+	var errs []error
+	for _, rule := range rules {
+		val, err := r.extract(rule)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("extract %s: %w", rule.Var, err))
+			continue
+		}
+		vars[rule.Var] = val
+	}
+	return vars, errors.Join(errs...)
+}
+
+func (r *ProcessedResponse) extract(rule storage.Extraction) (string, error) {
+	if rule.Var == "" {
+		return "", errors.New("missing variable name")
+	}
+
+	switch rule.From {
+	case storage.ExtractJSON:
+		doc, err := r.JSON()
+		if err != nil {
+			return "", err
+		}
+		v, ok := LookupJSONPath(doc, rule.Property)
+		if !ok {
+			return "", fmt.Errorf("%s does not exist", rule.Property)
+		}
+		return FormatJSONValue(v), nil
+
+	case storage.ExtractHeader:
+		if len(r.Headers.Values(rule.Property)) == 0 {
+			return "", fmt.Errorf("header %s is missing", rule.Property)
+		}
+		return r.Headers.Get(rule.Property), nil
+
+	case storage.ExtractRegex:
+		re, err := regexp.Compile(rule.Property)
+		if err != nil {
+			return "", fmt.Errorf("invalid regex %q: %w", rule.Property, err)
+		}
+		m := re.FindStringSubmatch(r.BodyString())
+		if m == nil {
+			return "", fmt.Errorf("body does not match %q", rule.Property)
+		}
+		if len(m) > 1 {
+			return m[1], nil
+		}
+		return m[0], nil
+
+	case storage.ExtractCookie:
+		cookies := (&http.Response{Header: r.Headers}).Cookies()
+		for _, c := range cookies {
+			if c.Name == rule.Property {
+				return c.Value, nil
+			}
+		}
+		return "", fmt.Errorf("cookie %s is not set", rule.Property)
+	}
+
+	return "", fmt.Errorf("unknown source %q", rule.From)
+}
@@ -0,0 +1,67 @@
+package http
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/styltsou/tapi/internal/storage"
+)
+
+func TestProcessedResponse_Extract(t *testing.T) {
+	resp := &ProcessedResponse{
+		StatusCode: 200,
+		Headers: http.Header{
+			"X-Request-Id": []string{"abc-123"},
+			"Set-Cookie":   []string{"session=s3cr3t; Path=/; HttpOnly"},
+		},
+		Body: []byte(`{"data": {"token": "tok-1", "id": 7, "account": 9007199254740993}, "note": "next page: 3"}`),
+	}
+
+	vars, err := resp.Extract([]storage.Extraction{
+		{Var: "token", From: "json", Property: "$.data.token"},
+		{Var: "id", From: "json", Property: "data.id"},
+		{Var: "account", From: "json", Property: "data.account"},
+		{Var: "reqId", From: "header", Property: "x-request-id"},
+		{Var: "page", From: "regex", Property: `next page: (\d+)`},
+		{Var: "session", From: "cookie", Property: "session"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := map[string]string{
+		"token":   "tok-1",
+		"id":      "7",
+		"account": "9007199254740993",
+		"reqId":   "abc-123",
+		"page":    "3",
+		"session": "s3cr3t",
+	}
+	for k, want := range expected {
+		if got := vars[k]; got != want {
+			t.Errorf("Expected %s=%q, got %q", k, want, got)
+		}
+	}
+}
+
+func TestProcessedResponse_Extract_Missing(t *testing.T) {
+	resp := &ProcessedResponse{
+		Headers: http.Header{},
+		Body:    []byte(`{"data": {}}`),
+	}
+
+	vars, err := resp.Extract([]storage.Extraction{
+		{Var: "token", From: "json", Property: "$.data.token"},
+		{Var: "cookie", From: "cookie", Property: "session"},
+		{Var: "raw", From: "regex", Property: `"data"`},
+	})
+	if err == nil {
+		t.Fatal("Expected error for missing values")
+	}
+	if _, ok := vars["token"]; ok {
+		t.Error("Expected token to be skipped")
+	}
+	if vars["raw"] != `"data"` {
+		t.Errorf("Expected whole match without capture group, got %q", vars["raw"])
+	}
+}
@@ -0,0 +1,131 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// LookupJSONPath resolves a simple JSON path against a decoded JSON document.
+// Supported syntax: "$", "$.a.b", "a.b", "items[0].id", "$['a-b']", "[1]".
+func LookupJSONPath(doc interface{}, path string) (interface{}, bool) {
+	tokens, err := parseJSONPath(path)
+	if err != nil {
+		return nil, false
+	}
+
+	cur := doc
+	for _, tok := range tokens {
+		switch node := cur.(type) {
+		case map[string]interface{}:
+			v, ok := node[tok]
+			if !ok {
+				return nil, false
+			}
+			cur = v
+		case []interface{}:
+			idx, err := strconv.Atoi(tok)
+			if err != nil {
+				return nil, false
+			}
+			if idx < 0 {
+				idx += len(node)
+			}
+			if idx < 0 || idx >= len(node) {
+				return nil, false
+			}
+			cur = node[idx]
+		default:
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+// parseJSONPath splits a path into object keys and array indexes
+func parseJSONPath(path string) ([]string, error) {
+	path = strings.TrimSpace(path)
+	path = strings.TrimPrefix(path, "$")
+
+	var tokens []string
+	for i := 0; i < len(path); {
+		switch path[i] {
+		case '.':
+			i++
+		case '[':
+			end := strings.IndexByte(path[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed [ in path %q", path)
+			}
+			inner := strings.TrimSpace(path[i+1 : i+end])
+			inner = strings.Trim(inner, `'"`)
+			tokens = append(tokens, inner)
+			i += end + 1
+		default:
+			end := strings.IndexAny(path[i:], ".[")
+			if end < 0 {
+				end = len(path) - i
+			}
+			tokens = append(tokens, path[i:i+end])
+			i += end
+		}
+	}
+	return tokens, nil
+}
+
+// JSONTypeOf returns the JSON type name of a decoded value
+func JSONTypeOf(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64, json.Number:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return "unknown"
+	}
+}
+
+// FormatJSONValue renders a decoded value for comparison and display.
+// Strings are returned unquoted and numbers as written in the document;
+// everything else is encoded as compact JSON.
+func FormatJSONValue(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+// JSON decodes the response body, or returns an error if it isn't valid JSON.
+// Numbers are decoded as json.Number so that large integers such as IDs
+// keep all their digits.
+func (r *ProcessedResponse) JSON() (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(r.Body))
+	dec.UseNumber()
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("body is not valid JSON: %w", err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("body is not valid JSON: data after the top-level value")
+	}
+	return doc, nil
+}
@@ -0,0 +1,75 @@
+package http
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestLookupJSONPath(t *testing.T) {
+	var doc interface{}
+	_ = json.Unmarshal([]byte(`{"data": {"items": [{"id": 1}, {"id": 2}], "a-b": "dash"}, "ok": true}`), &doc)
+
+	tests := []struct {
+		path   string
+		want   string
+		wantOK bool
+	}{
+		{"$.ok", "true", true},
+		{"ok", "true", true},
+		{"$.data.items[1].id", "2", true},
+		{"data.items[-1].id", "2", true},
+		{"$.data['a-b']", "dash", true},
+		{"$.data.items[5]", "", false},
+		{"$.missing", "", false},
+		{"$.ok.nested", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			v, ok := LookupJSONPath(doc, tt.path)
+			if ok != tt.wantOK {
+				t.Fatalf("LookupJSONPath(%q) ok = %v, want %v", tt.path, ok, tt.wantOK)
+			}
+			if ok && FormatJSONValue(v) != tt.want {
+				t.Errorf("LookupJSONPath(%q) = %s, want %s", tt.path, FormatJSONValue(v), tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONTypeOf(t *testing.T) {
+	var doc map[string]interface{}
+	_ = json.Unmarshal([]byte(`{"s": "x", "n": 1, "b": false, "o": {}, "a": [], "z": null}`), &doc)
+
+	want := map[string]string{"s": "string", "n": "number", "b": "boolean", "o": "object", "a": "array", "z": "null"}
+	for key, typ := range want {
+		if got := JSONTypeOf(doc[key]); got != typ {
+			t.Errorf("JSONTypeOf(%s) = %s, want %s", key, got, typ)
+		}
+	}
+}
+
+func TestProcessedResponse_JSON_LargeNumbers(t *testing.T) {
+	// Above 2^53, where float64 can't hold every integer
+	resp := &ProcessedResponse{Body: []byte(`{"id": 9007199254740993, "ids": [12345678901234567890], "price": 1.10}`)}
+	doc, err := resp.JSON()
+	if err != nil {
+		t.Fatalf("JSON failed: %v", err)
+	}
+
+	tests := map[string]string{"id": "9007199254740993", "ids[0]": "12345678901234567890", "price": "1.10", "ids": "[12345678901234567890]"}
+	for path, want := range tests {
+		v, _ := LookupJSONPath(doc, path)
+		if got := FormatJSONValue(v); got != want {
+			t.Errorf("FormatJSONValue(%s) = %s, want %s", path, got, want)
+		}
+	}
+	if v, _ := LookupJSONPath(doc, "id"); JSONTypeOf(v) != "number" {
+		t.Errorf("Expected id to be a number, got %s", JSONTypeOf(v))
+	}
+
+	resp.Body = []byte(`{"a": 1} {"b": 2}`)
+	if _, err := resp.JSON(); err == nil {
+		t.Error("Expected an error for data after the document")
+	}
+}
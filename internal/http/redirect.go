@@ -0,0 +1,90 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/styltsou/tapi/internal/storage"
+)
+
+// RedirectHop is a redirect response on the way to the final one
+type RedirectHop struct {
+	Method     string
+	URL        string // the URL that answered with the redirect
+	StatusCode int
+	Status     string
+	Location   string
+	Headers    http.Header
+	Duration   time.Duration // from sending the hop's request to its response
+}
+
+// bodyHeaders are the headers net/http drops along with the body when a
+// redirect switches to GET
+var bodyHeaders = []string{"Content-Type", "Content-Encoding", "Content-Language", "Content-Location"}
+
+// redirectRecorder applies the redirect settings of a request and records
+// each hop it follows
+type redirectRecorder struct {
+	req  storage.Request
+	hops []RedirectHop
+	last time.Time // when the current hop was sent
+}
+
+// start begins a new attempt: hops of an earlier attempt (before a digest or
+// oauth2 retry) are dropped
+func (r *redirectRecorder) start(now time.Time) {
+	r.hops = nil
+	r.last = now
+}
+
+// check is the http.Client CheckRedirect. req is the next request, carrying
+// the redirect response; via holds the requests sent so far.
+func (r *redirectRecorder) check(req *http.Request, via []*http.Request) error {
+	if r.req.NoFollow {
+		return http.ErrUseLastResponse
+	}
+
+	prev := via[len(via)-1]
+	if resp := req.Response; resp != nil {
+		now := time.Now()
+		r.hops = append(r.hops, RedirectHop{
+			Method:     prev.Method,
+			URL:        prev.URL.String(),
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			Location:   resp.Header.Get("Location"),
+			Headers:    resp.Header.Clone(),
+			Duration:   now.Sub(r.last),
+		})
+		r.last = now
+	}
+
+	if max := r.req.EffectiveMaxRedirects(); len(via) > max {
+		return fmt.Errorf("stopped after %d redirects", max)
+	}
+
+	// 301, 302 and 303 switch to GET and drop the body unless the request
+	// asks to keep them; once dropped, net/http leaves them out of later 307
+	// and 308 hops too
+	if !r.req.KeepMethod {
+		return nil
+	}
+	first := via[0]
+	req.Method = first.Method
+	if first.GetBody != nil && (req.Body == nil || req.Body == http.NoBody) {
+		body, err := first.GetBody()
+		if err != nil {
+			return err
+		}
+		req.Body = body
+		req.GetBody = first.GetBody
+		req.ContentLength = first.ContentLength
+	}
+	for _, key := range bodyHeaders {
+		if values, ok := first.Header[key]; ok && req.Header.Get(key) == "" {
+			req.Header[key] = values
+		}
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package http
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/styltsou/tapi/internal/storage"
+)
+
+func redirectServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/old":
+			w.Header().Set("X-Hop", "1")
+			http.Redirect(w, r, "/moved", http.StatusMovedPermanently)
+		case "/moved":
+			http.Redirect(w, r, "/final", http.StatusSeeOther)
+		default:
+			body, _ := io.ReadAll(r.Body)
+			w.Write([]byte(r.Method + " " + r.Header.Get("Content-Type") + " " + string(body)))
+		}
+	}))
+}
+
+func TestClient_Execute_RedirectChain(t *testing.T) {
+	server := redirectServer()
+	defer server.Close()
+	client := NewClient(5 * time.Second)
+
+	resp, err := client.Execute(context.Background(), storage.Request{Method: "GET", URL: server.URL + "/old"}, "")
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || len(resp.Redirects) != 2 {
+		t.Fatalf("Expected 200 after 2 hops, got %d with %+v", resp.StatusCode, resp.Redirects)
+	}
+	first, second := resp.Redirects[0], resp.Redirects[1]
+	if first.StatusCode != 301 || first.Location != "/moved" || first.URL != server.URL+"/old" || first.Headers.Get("X-Hop") != "1" {
+		t.Errorf("Unexpected first hop %+v", first)
+	}
+	if second.StatusCode != 303 || second.Location != "/final" || second.Method != "GET" {
+		t.Errorf("Unexpected second hop %+v", second)
+	}
+	if first.Duration <= 0 {
+		t.Error("Expected the hop to be timed")
+	}
+	if resp.Request.URL != server.URL+"/final" {
+		t.Errorf("Expected the final URL to be recorded, got %s", resp.Request.URL)
+	}
+}
+
+func TestClient_Execute_RedirectPolicy(t *testing.T) {
+	server := redirectServer()
+	defer server.Close()
+	client := NewClient(5 * time.Second)
+
+	post := storage.Request{
+		Method:  "POST",
+		URL:     server.URL + "/old",
+		Headers: storage.Params{{Key: "Content-Type", Value: "application/json", Enabled: true}},
+		Body:    `{"a":1}`,
+	}
+	tests := []struct {
+		name       string
+		modify     func(*storage.Request)
+		wantStatus int
+		wantBody   string
+		wantHops   int
+		wantErr    bool
+	}{
+		{"switches to GET", func(*storage.Request) {}, 200, "GET  ", 2, false},
+		{"keeps the method and body", func(r *storage.Request) { r.KeepMethod = true }, 200, `POST application/json {"a":1}`, 2, false},
+		{"does not follow", func(r *storage.Request) { r.NoFollow = true }, 301, "", 0, false},
+		{"too many hops", func(r *storage.Request) { r.MaxRedirects = 1 }, 0, "", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := post
+			tt.modify(&req)
+			resp, err := client.Execute(context.Background(), req, "")
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected an error, got %d", resp.StatusCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Execute failed: %v", err)
+			}
+			if resp.StatusCode != tt.wantStatus || len(resp.Redirects) != tt.wantHops {
+				t.Errorf("Expected %d after %d hops, got %d after %d", tt.wantStatus, tt.wantHops, resp.StatusCode, len(resp.Redirects))
+			}
+			if tt.wantBody != "" && string(resp.Body) != tt.wantBody {
+				t.Errorf("Expected %q, got %q", tt.wantBody, resp.Body)
+			}
+		})
+	}
+}
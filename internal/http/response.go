@@ -4,11 +4,15 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"sort"
+	"strings"
 	"time"
 )
 
 const (
-	// MaxResponseBodySize limits response body to 10MB to prevent memory exhaustion
+	// MaxResponseBodySize limits response body to 10MB to prevent memory
+	// exhaustion, unless the client sets its own limit
 	MaxResponseBodySize = 10 * 1024 * 1024 // 10MB
 )
 
@@ -21,15 +25,76 @@ type ProcessedResponse struct {
 	Duration   time.Duration
 	Size       int64
 	Truncated  bool // Indicates if body was truncated due to size limit
+
+	Request *SentRequest // the request that got this response, nil if unknown
+	Timings Timings      // DNS, connect, TLS, wait and transfer phases
+	Wire    *WireDump    // the exchange as on the wire, nil unless the client captures it
+
+	Redirects []RedirectHop // the redirects followed to get this response, in order
+	TLS       *TLSInfo      // the TLS connection of the response, nil over plain HTTP
+	Download  *DownloadInfo // the file the body was saved to instead of Body, nil unless downloaded
+}
+
+// SentRequest is a request as it went out: after variable substitution,
+// default headers, auth and signing
+type SentRequest struct {
+	Method  string
+	URL     string
+	Headers http.Header
+	Body    string
+}
+
+// newSentRequest records the final request of a response
+func newSentRequest(httpReq *http.Request, body string) *SentRequest {
+	if httpReq == nil {
+		return nil
+	}
+	return &SentRequest{
+		Method:  httpReq.Method,
+		URL:     httpReq.URL.String(),
+		Headers: httpReq.Header.Clone(),
+		Body:    body,
+	}
+}
+
+// Raw formats the request the way it is written on the wire: request line,
+// Host, the headers sorted by name, then the body
+func (s *SentRequest) Raw() string {
+	var sb strings.Builder
+	target, host := s.URL, ""
+	if u, err := url.Parse(s.URL); err == nil {
+		target, host = u.RequestURI(), u.Host
+	}
+	sb.WriteString(s.Method + " " + target + " HTTP/1.1\n")
+	if host != "" {
+		sb.WriteString("Host: " + host + "\n")
+	}
+	keys := make([]string, 0, len(s.Headers))
+	for key := range s.Headers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		for _, value := range s.Headers[key] {
+			sb.WriteString(key + ": " + value + "\n")
+		}
+	}
+	if s.Body != "" {
+		sb.WriteString("\n" + s.Body)
+	}
+	return sb.String()
 }
 
 // ProcessResponse transforms raw http.Response into a TUI-friendly format
-func ProcessResponse(resp *http.Response, start time.Time) (*ProcessedResponse, error) {
+func ProcessResponse(resp *http.Response, start time.Time, limit int64) (*ProcessedResponse, error) {
 	// Ensure body is closed
 	defer resp.Body.Close()
 
 	// Read body with size limit to prevent memory exhaustion
-	limitedReader := io.LimitReader(resp.Body, MaxResponseBodySize)
+	if limit <= 0 {
+		limit = MaxResponseBodySize
+	}
+	limitedReader := io.LimitReader(resp.Body, limit)
 	bodyBytes, err := io.ReadAll(limitedReader)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read response body: %w", err)
@@ -37,7 +102,7 @@ func ProcessResponse(resp *http.Response, start time.Time) (*ProcessedResponse,
 
 	// Check if body was truncated
 	truncated := false
-	if int64(len(bodyBytes)) >= MaxResponseBodySize {
+	if int64(len(bodyBytes)) >= limit {
 		// Check if there's more data
 		oneByte := make([]byte, 1)
 		n, _ := resp.Body.Read(oneByte)
@@ -63,6 +128,7 @@ func ProcessResponse(resp *http.Response, start time.Time) (*ProcessedResponse,
 		Duration:   duration,
 		Size:       int64(len(bodyBytes)),
 		Truncated:  truncated,
+		TLS:        newTLSInfo(resp),
 	}
 
 	return response, nil
@@ -90,7 +156,12 @@ func (r *ProcessedResponse) GetHeader(key string) string {
 
 // FormatSize returns a human-readable size (e.g., "1.5 KB", "2.3 MB")
 func (r *ProcessedResponse) FormatSize() string {
-	size := float64(r.Size)
+	return FormatBytes(r.Size)
+}
+
+// FormatBytes returns a human-readable byte count
+func FormatBytes(n int64) string {
+	size := float64(n)
 	units := []string{"B", "KB", "MB", "GB"}
 
 	unitIndex := 0
@@ -20,7 +20,7 @@ func TestProcessResponse(t *testing.T) {
 	}
 
 	start := time.Now().Add(-1 * time.Second)
-	processed, err := ProcessResponse(resp, start)
+	processed, err := ProcessResponse(resp, start, 0)
 
 	if err != nil {
 		t.Fatalf("ProcessResponse failed: %v", err)
@@ -86,7 +86,7 @@ func TestMaxBodySize(t *testing.T) {
 		Body:       io.NopCloser(bytes.NewReader(largeBody)),
 	}
 
-	processed, err := ProcessResponse(resp, time.Now())
+	processed, err := ProcessResponse(resp, time.Now(), 0)
 	if err != nil {
 		t.Fatalf("ProcessResponse failed: %v", err)
 	}
@@ -98,4 +98,14 @@ func TestMaxBodySize(t *testing.T) {
 	if int64(len(processed.Body)) != MaxResponseBodySize {
 		t.Errorf("Expected body size %d, got %d", MaxResponseBodySize, len(processed.Body))
 	}
+
+	// A limit of the client's own
+	resp.Body = io.NopCloser(bytes.NewReader(largeBody))
+	processed, err = ProcessResponse(resp, time.Now(), 1024)
+	if err != nil {
+		t.Fatalf("ProcessResponse failed: %v", err)
+	}
+	if !processed.Truncated || len(processed.Body) != 1024 {
+		t.Errorf("Expected the body truncated to 1024 bytes, got %d (truncated %v)", len(processed.Body), processed.Truncated)
+	}
 }
@@ -0,0 +1,213 @@
+package http
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/styltsou/tapi/internal/storage"
+)
+
+// signRequest signs the request for the aws and hmac auth types. It is the
+// last change made to the request, so every header sent is covered.
+func signRequest(httpReq *http.Request, auth *storage.Auth, body string, now time.Time) error {
+	if auth == nil {
+		return nil
+	}
+	switch auth.EffectiveType() {
+	case storage.AuthAWS:
+		return signAWS(httpReq, *auth, body, now)
+	case storage.AuthHMAC:
+		return signHMAC(httpReq, *auth, body, now)
+	}
+	return nil
+}
+
+// signAWS adds the AWS Signature Version 4 Authorization header. All the
+// headers set on the request are signed, together with Host.
+func signAWS(httpReq *http.Request, auth storage.Auth, body string, now time.Time) error {
+	if auth.AccessKey == "" || auth.SecretKey == "" {
+		return errors.New("aws auth needs access_key and secret_key")
+	}
+	if auth.Region == "" || auth.Service == "" {
+		return errors.New("aws auth needs region and service")
+	}
+
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+
+	httpReq.Header.Set("X-Amz-Date", amzDate)
+	if auth.SessionToken != "" {
+		httpReq.Header.Set("X-Amz-Security-Token", auth.SessionToken)
+	}
+	if auth.Service == "s3" {
+		httpReq.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	}
+
+	// Canonical headers: lowercased names, sorted, values trimmed
+	headers := map[string]string{"host": httpReq.Host}
+	if httpReq.Host == "" {
+		headers["host"] = httpReq.URL.Host
+	}
+	for name, values := range httpReq.Header {
+		name = strings.ToLower(name)
+		if name == "authorization" {
+			continue
+		}
+		trimmed := make([]string, len(values))
+		for i, v := range values {
+			trimmed[i] = strings.Join(strings.Fields(v), " ")
+		}
+		headers[name] = strings.Join(trimmed, ",")
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		httpReq.Method,
+		awsCanonicalPath(httpReq.URL.EscapedPath(), auth.Service != "s3"),
+		awsCanonicalQuery(httpReq.URL.RawQuery),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + auth.Region + "/" + auth.Service + "/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		sha256Hex(canonicalRequest),
+	}, "\n")
+
+	key := hmacSum(sha256.New, []byte("AWS4"+auth.SecretKey), date)
+	key = hmacSum(sha256.New, key, auth.Region)
+	key = hmacSum(sha256.New, key, auth.Service)
+	key = hmacSum(sha256.New, key, "aws4_request")
+	signature := hex.EncodeToString(hmacSum(sha256.New, key, stringToSign))
+
+	httpReq.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		auth.AccessKey, scope, signedHeaders, signature))
+	return nil
+}
+
+// awsCanonicalPath returns the URI-encoded path. Services other than S3
+// expect each segment to be encoded twice.
+func awsCanonicalPath(escapedPath string, twice bool) string {
+	if escapedPath == "" {
+		return "/"
+	}
+	if !twice {
+		return escapedPath
+	}
+	segments := strings.Split(escapedPath, "/")
+	for i, s := range segments {
+		segments[i] = awsEscape(s)
+	}
+	return strings.Join(segments, "/")
+}
+
+// awsCanonicalQuery returns the query params encoded and sorted by name,
+// then by value
+func awsCanonicalQuery(rawQuery string) string {
+	if rawQuery == "" {
+		return ""
+	}
+	var pairs []string
+	for _, part := range strings.Split(rawQuery, "&") {
+		if part == "" {
+			continue
+		}
+		key, value, _ := strings.Cut(part, "=")
+		pairs = append(pairs, awsEscape(queryUnescape(key))+"="+awsEscape(queryUnescape(value)))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "&")
+}
+
+func queryUnescape(s string) string {
+	if unescaped, err := url.QueryUnescape(s); err == nil {
+		return unescaped
+	}
+	return s
+}
+
+// awsEscape percent-encodes everything but the RFC 3986 unreserved characters
+func awsEscape(s string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' {
+			sb.WriteByte(c)
+		} else {
+			fmt.Fprintf(&sb, "%%%02X", c)
+		}
+	}
+	return sb.String()
+}
+
+// signHMAC sets the timestamp header and the signature header. The signature
+// is the HMAC of the method, the path with its query, the timestamp (Unix
+// seconds) and the body, joined by newlines.
+func signHMAC(httpReq *http.Request, auth storage.Auth, body string, now time.Time) error {
+	if auth.SecretKey == "" {
+		return errors.New("hmac auth needs secret_key")
+	}
+	var newHash func() hash.Hash
+	switch auth.EffectiveAlgorithm() {
+	case storage.HMACSHA256:
+		newHash = sha256.New
+	case storage.HMACSHA512:
+		newHash = sha512.New
+	case storage.HMACSHA1:
+		newHash = sha1.New
+	default:
+		return fmt.Errorf("unsupported hmac algorithm %q", auth.Algorithm)
+	}
+
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	message := strings.Join([]string{httpReq.Method, httpReq.URL.RequestURI(), timestamp, body}, "\n")
+	sum := hmacSum(newHash, []byte(auth.SecretKey), message)
+
+	signature := hex.EncodeToString(sum)
+	if auth.Encoding == "base64" {
+		signature = base64.StdEncoding.EncodeToString(sum)
+	}
+	httpReq.Header.Set(auth.EffectiveTimestampHeader(), timestamp)
+	httpReq.Header.Set(auth.EffectiveHeader(), signature)
+	return nil
+}
+
+func hmacSum(newHash func() hash.Hash, key []byte, data string) []byte {
+	mac := hmac.New(newHash, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
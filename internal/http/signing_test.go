@@ -0,0 +1,116 @@
+package http
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/styltsou/tapi/internal/storage"
+)
+
+// Cases from the AWS Signature Version 4 test suite
+func TestSignAWS(t *testing.T) {
+	auth := storage.Auth{
+		Type:      storage.AuthAWS,
+		AccessKey: "AKIDEXAMPLE",
+		SecretKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+		Region:    "us-east-1",
+		Service:   "service",
+	}
+	now := time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		url           string
+		wantSignature string
+	}{
+		{"get-vanilla", "https://example.amazonaws.com/", "5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"},
+		{"get-vanilla-query-order-key-case", "https://example.amazonaws.com/?Param2=value2&Param1=value1", "b97d918cfa904a5beff61c982a1b6f458b799221646efd99d3219ec94cdf2500"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			httpReq, _ := http.NewRequest("GET", tt.url, nil)
+			if err := signRequest(httpReq, &auth, "", now); err != nil {
+				t.Fatalf("signRequest failed: %v", err)
+			}
+			want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=" + tt.wantSignature
+			if got := httpReq.Header.Get("Authorization"); got != want {
+				t.Errorf("Expected Authorization %q, got %q", want, got)
+			}
+			if got := httpReq.Header.Get("X-Amz-Date"); got != "20150830T123600Z" {
+				t.Errorf("Expected X-Amz-Date 20150830T123600Z, got %q", got)
+			}
+		})
+	}
+
+	t.Run("missing region", func(t *testing.T) {
+		incomplete := auth
+		incomplete.Region = ""
+		httpReq, _ := http.NewRequest("GET", "https://example.amazonaws.com/", nil)
+		if err := signRequest(httpReq, &incomplete, "", now); err == nil {
+			t.Error("Expected an error without a region")
+		}
+	})
+}
+
+func TestClient_Execute_HMACSigning(t *testing.T) {
+	secret := "s3cret"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		message := strings.Join([]string{r.Method, r.URL.RequestURI(), r.Header.Get("X-Timestamp"), string(body)}, "\n")
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(message))
+		if r.Header.Get("X-Signature") != hex.EncodeToString(mac.Sum(nil)) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewClient(10 * time.Second)
+	req := storage.Request{
+		Method:  "POST",
+		URL:     server.URL + "/orders",
+		Query:   storage.Params{{Key: "dry_run", Value: "true", Enabled: true}},
+		Headers: storage.Params{{Key: "Content-Type", Value: "application/json", Enabled: true}},
+		Body:    `{"item":1}`,
+		Auth:    &storage.Auth{Type: storage.AuthHMAC, SecretKey: secret},
+	}
+	resp, err := client.Execute(context.Background(), req, "")
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected the server to accept the signature, got %d", resp.StatusCode)
+	}
+
+	// The raw request shows the computed headers
+	if resp.Request == nil {
+		t.Fatal("Expected the sent request to be recorded")
+	}
+	if _, err := strconv.ParseInt(resp.Request.Headers.Get("X-Timestamp"), 10, 64); err != nil {
+		t.Errorf("Expected a Unix timestamp, got %q", resp.Request.Headers.Get("X-Timestamp"))
+	}
+	raw := resp.Request.Raw()
+	for _, want := range []string{"POST /orders?dry_run=true HTTP/1.1", "X-Signature: " + resp.Request.Headers.Get("X-Signature"), `{"item":1}`} {
+		if !strings.Contains(raw, want) {
+			t.Errorf("Expected raw request to contain %q, got:\n%s", want, raw)
+		}
+	}
+
+	// Without a secret nothing is sent
+	req.Auth = &storage.Auth{Type: storage.AuthHMAC}
+	if _, err := client.Execute(context.Background(), req, ""); err == nil {
+		t.Error("Expected an error for hmac auth without a secret")
+	}
+}
@@ -0,0 +1,125 @@
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Span is a phase of a request, as an offset from the start of the request
+// and a length
+type Span struct {
+	Start    time.Duration
+	Duration time.Duration
+}
+
+// End returns the offset at which the phase ended
+func (s Span) End() time.Duration {
+	return s.Start + s.Duration
+}
+
+// Timings breaks the duration of a request down into its phases. DNS,
+// Connect and TLS are zero when an open connection was reused. With retries
+// (digest, oauth2) they describe the last attempt.
+type Timings struct {
+	DNS        Span
+	Connect    Span
+	TLS        Span
+	Wait       Span // from the request being written to the first response byte
+	Transfer   Span // reading the body
+	Total      time.Duration
+	Reused     bool   // the request went over an open connection
+	RemoteAddr string // address connected to
+}
+
+// FirstByte returns the time to the first response byte
+func (t Timings) FirstByte() time.Duration {
+	return t.Wait.End()
+}
+
+// tracer collects the httptrace events of a request
+type tracer struct {
+	mu    sync.Mutex
+	start time.Time
+
+	dnsStart, dnsDone         time.Time
+	connectStart, connectDone time.Time
+	tlsStart, tlsDone         time.Time
+	wroteRequest, firstByte   time.Time
+	reused                    bool
+	remoteAddr                string
+}
+
+// withTrace returns ctx with the tracer hooked in
+func (t *tracer) withTrace(ctx context.Context) context.Context {
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GetConn: func(string) {
+			// A retry or redirect starts over
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.dnsStart, t.dnsDone = time.Time{}, time.Time{}
+			t.connectStart, t.connectDone = time.Time{}, time.Time{}
+			t.tlsStart, t.tlsDone = time.Time{}, time.Time{}
+			t.wroteRequest, t.firstByte = time.Time{}, time.Time{}
+		},
+		DNSStart: func(httptrace.DNSStartInfo) { t.set(&t.dnsStart) },
+		DNSDone:  func(httptrace.DNSDoneInfo) { t.set(&t.dnsDone) },
+		ConnectStart: func(string, string) {
+			// Dual-stack dialing may start several connects; the first counts
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			if t.connectStart.IsZero() {
+				t.connectStart = time.Now()
+			}
+		},
+		ConnectDone: func(_, _ string, err error) {
+			if err == nil {
+				t.set(&t.connectDone)
+			}
+		},
+		TLSHandshakeStart: func() { t.set(&t.tlsStart) },
+		TLSHandshakeDone:  func(tls.ConnectionState, error) { t.set(&t.tlsDone) },
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.reused = info.Reused
+			if info.Conn != nil && info.Conn.RemoteAddr() != nil {
+				t.remoteAddr = info.Conn.RemoteAddr().String()
+			}
+		},
+		WroteRequest:         func(httptrace.WroteRequestInfo) { t.set(&t.wroteRequest) },
+		GotFirstResponseByte: func() { t.set(&t.firstByte) },
+	})
+}
+
+func (t *tracer) set(field *time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	*field = time.Now()
+}
+
+// timings returns the phases of a request whose body was read by end
+func (t *tracer) timings(end time.Time) Timings {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return Timings{
+		DNS:        t.span(t.dnsStart, t.dnsDone),
+		Connect:    t.span(t.connectStart, t.connectDone),
+		TLS:        t.span(t.tlsStart, t.tlsDone),
+		Wait:       t.span(t.wroteRequest, t.firstByte),
+		Transfer:   t.span(t.firstByte, end),
+		Total:      end.Sub(t.start),
+		Reused:     t.reused,
+		RemoteAddr: t.remoteAddr,
+	}
+}
+
+// span is the phase between two events, zero if either did not happen
+func (t *tracer) span(from, to time.Time) Span {
+	if from.IsZero() || to.IsZero() || to.Before(from) {
+		return Span{}
+	}
+	return Span{Start: from.Sub(t.start), Duration: to.Sub(from)}
+}
@@ -0,0 +1,58 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/styltsou/tapi/internal/storage"
+)
+
+func TestClient_Execute_Timings(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	client := NewClient(5 * time.Second)
+	client.HTTPClient = server.Client()
+	req := storage.Request{Method: "GET", URL: server.URL}
+
+	first, err := client.Execute(context.Background(), req, "")
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	timings := first.Timings
+	if timings.Reused {
+		t.Error("Expected a new connection for the first request")
+	}
+	if timings.Connect.Duration <= 0 || timings.TLS.Duration <= 0 {
+		t.Errorf("Expected connect and TLS phases, got %+v", timings)
+	}
+	if timings.TLS.Start < timings.Connect.End() {
+		t.Errorf("Expected the TLS handshake after the connect, got %+v", timings)
+	}
+	if timings.Wait.Duration < 20*time.Millisecond {
+		t.Errorf("Expected the wait to include the server delay, got %v", timings.Wait.Duration)
+	}
+	if timings.FirstByte() > timings.Total || timings.Total != first.Duration {
+		t.Errorf("Expected the phases within the total of %v, got %+v", first.Duration, timings)
+	}
+	if timings.RemoteAddr != server.Listener.Addr().String() {
+		t.Errorf("Expected remote address %s, got %q", server.Listener.Addr(), timings.RemoteAddr)
+	}
+
+	second, err := client.Execute(context.Background(), req, "")
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if !second.Timings.Reused {
+		t.Error("Expected the second request to reuse the connection")
+	}
+	if second.Timings.Connect != (Span{}) || second.Timings.TLS != (Span{}) {
+		t.Errorf("Expected no connect or TLS phase on a reused connection, got %+v", second.Timings)
+	}
+}
@@ -0,0 +1,77 @@
+package http
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"time"
+)
+
+// CertExpiryWarning is how close to its expiry a certificate is flagged
+const CertExpiryWarning = 30 * 24 * time.Hour
+
+// TLSInfo describes the TLS connection a response came over
+type TLSInfo struct {
+	Protocol     string // negotiated application protocol: h2 or http/1.1
+	Version      string // TLS 1.2, TLS 1.3, ...
+	CipherSuite  string
+	ServerName   string     // SNI sent in the handshake
+	Resumed      bool       // the session was resumed from an earlier connection
+	Certificates []CertInfo // the chain the server presented, leaf first
+}
+
+// CertInfo is a certificate of the chain presented by a server
+type CertInfo struct {
+	Subject   string
+	Issuer    string
+	SANs      []string // DNS names and IP addresses
+	NotBefore time.Time
+	NotAfter  time.Time
+}
+
+// ExpiresWithin reports whether the certificate is expired by now+d
+func (c CertInfo) ExpiresWithin(now time.Time, d time.Duration) bool {
+	return now.Add(d).After(c.NotAfter)
+}
+
+// Expired reports whether the certificate is no longer valid at now
+func (c CertInfo) Expired(now time.Time) bool {
+	return now.After(c.NotAfter)
+}
+
+// newTLSInfo summarises the TLS state of resp, nil for plain HTTP
+func newTLSInfo(resp *http.Response) *TLSInfo {
+	state := resp.TLS
+	if state == nil {
+		return nil
+	}
+	info := &TLSInfo{
+		Protocol:    state.NegotiatedProtocol,
+		Version:     tls.VersionName(state.Version),
+		CipherSuite: tls.CipherSuiteName(state.CipherSuite),
+		ServerName:  state.ServerName,
+		Resumed:     state.DidResume,
+	}
+	// Without ALPN the connection speaks HTTP/1.1
+	if info.Protocol == "" {
+		info.Protocol = "http/1.1"
+	}
+	for _, cert := range state.PeerCertificates {
+		info.Certificates = append(info.Certificates, newCertInfo(cert))
+	}
+	return info
+}
+
+func newCertInfo(cert *x509.Certificate) CertInfo {
+	sans := append([]string(nil), cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	return CertInfo{
+		Subject:   cert.Subject.String(),
+		Issuer:    cert.Issuer.String(),
+		SANs:      sans,
+		NotBefore: cert.NotBefore,
+		NotAfter:  cert.NotAfter,
+	}
+}
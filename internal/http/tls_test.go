@@ -0,0 +1,115 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/styltsou/tapi/internal/storage"
+)
+
+func TestClient_Execute_TLSInfo(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+	server := httptest.NewUnstartedServer(handler)
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	client := NewClient(5 * time.Second)
+	client.HTTPClient = server.Client()
+	resp, err := client.Execute(context.Background(), storage.Request{Method: "GET", URL: server.URL}, "")
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	info := resp.TLS
+	if info == nil {
+		t.Fatal("Expected TLS details for an HTTPS response")
+	}
+	if info.Protocol != "h2" {
+		t.Errorf("Expected protocol h2, got %q", info.Protocol)
+	}
+	if info.Version != "TLS 1.3" {
+		t.Errorf("Expected TLS 1.3, got %q", info.Version)
+	}
+	if info.CipherSuite == "" {
+		t.Error("Expected a cipher suite")
+	}
+	if len(info.Certificates) != 1 {
+		t.Fatalf("Expected the test certificate, got %d certificates", len(info.Certificates))
+	}
+	leaf := info.Certificates[0]
+	cert := server.Certificate()
+	if leaf.Subject != cert.Subject.String() || leaf.Issuer != cert.Issuer.String() {
+		t.Errorf("Expected subject %q issued by %q, got %q by %q", cert.Subject, cert.Issuer, leaf.Subject, leaf.Issuer)
+	}
+	for _, san := range []string{"example.com", "127.0.0.1"} {
+		if !slices.Contains(leaf.SANs, san) {
+			t.Errorf("Expected SAN %s, got %v", san, leaf.SANs)
+		}
+	}
+	if !leaf.NotAfter.Equal(cert.NotAfter) {
+		t.Errorf("Expected expiry %v, got %v", cert.NotAfter, leaf.NotAfter)
+	}
+
+	// Plain HTTP has none
+	plain := httptest.NewServer(handler)
+	defer plain.Close()
+	resp, err = NewClient(5*time.Second).Execute(context.Background(), storage.Request{Method: "GET", URL: plain.URL}, "")
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if resp.TLS != nil {
+		t.Errorf("Expected no TLS details over plain HTTP, got %+v", resp.TLS)
+	}
+}
+
+func TestClient_Execute_TLSInfoHTTP1(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(5 * time.Second).WithTransport(storage.Transport{Insecure: insecure(), HTTPVersion: storage.HTTP1})
+	if err != nil {
+		t.Fatalf("WithTransport failed: %v", err)
+	}
+	resp, err := client.Execute(context.Background(), storage.Request{Method: "GET", URL: server.URL}, "")
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if resp.TLS == nil || resp.TLS.Protocol != "http/1.1" {
+		t.Errorf("Expected protocol http/1.1, got %+v", resp.TLS)
+	}
+}
+
+func TestCertInfo_Expiry(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		notAfter time.Time
+		soon     bool
+		expired  bool
+	}{
+		{"valid for a year", now.AddDate(1, 0, 0), false, false},
+		{"expires in 10 days", now.AddDate(0, 0, 10), true, false},
+		{"expires in 31 days", now.AddDate(0, 0, 31), false, false},
+		{"expired yesterday", now.AddDate(0, 0, -1), true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cert := CertInfo{NotAfter: tt.notAfter}
+			if got := cert.ExpiresWithin(now, CertExpiryWarning); got != tt.soon {
+				t.Errorf("Expected ExpiresWithin %v, got %v", tt.soon, got)
+			}
+			if got := cert.Expired(now); got != tt.expired {
+				t.Errorf("Expected Expired %v, got %v", tt.expired, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,309 @@
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/styltsou/tapi/internal/storage"
+)
+
+// ErrTransport is returned when the proxy or TLS settings can't be used
+var ErrTransport = errors.New("invalid transport settings")
+
+// tlsVersions maps the min_tls_version setting to its crypto/tls constant
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// transportCache keeps one transport per distinct settings, so connections
+// are reused across requests
+type transportCache struct {
+	mu         sync.Mutex
+	transports map[string]http.RoundTripper
+}
+
+// WithTransport returns a copy of the client sending its requests with the
+// given proxy, TLS, HTTP version and host override settings. Without settings the client is
+// returned as is.
+func (c *Client) WithTransport(settings storage.Transport) (*Client, error) {
+	if settings.IsZero() {
+		return c, nil
+	}
+	rt, err := c.transportFor(settings)
+	if err != nil {
+		return nil, errors.Join(ErrTransport, err)
+	}
+	clone := *c
+	httpClient := *c.HTTPClient
+	httpClient.Transport = rt
+	clone.HTTPClient = &httpClient
+	return &clone, nil
+}
+
+func (c *Client) transportFor(settings storage.Transport) (http.RoundTripper, error) {
+	if c.transports == nil {
+		return newTransport(settings)
+	}
+	// The settings are plain data, their JSON is a stable key
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return nil, err
+	}
+	key := string(data)
+
+	c.transports.mu.Lock()
+	defer c.transports.mu.Unlock()
+	if rt, ok := c.transports.transports[key]; ok {
+		return rt, nil
+	}
+	rt, err := newTransport(settings)
+	if err != nil {
+		return nil, err
+	}
+	c.transports.transports[key] = rt
+	return rt, nil
+}
+
+// newTransport builds a transport from the settings. Client certificates need
+// a transport of their own each, since the TLS handshake doesn't tell which
+// host the certificate is asked for.
+func newTransport(settings storage.Transport) (http.RoundTripper, error) {
+	base := http.DefaultTransport.(*http.Transport).Clone()
+
+	proxy, err := proxyFunc(settings)
+	if err != nil {
+		return nil, err
+	}
+	base.Proxy = proxy
+
+	tlsConfig, err := tlsConfigFor(settings)
+	if err != nil {
+		return nil, err
+	}
+	base.TLSClientConfig = tlsConfig
+
+	if len(settings.Resolve) > 0 {
+		overrides, err := parseResolve(settings.Resolve)
+		if err != nil {
+			return nil, err
+		}
+		dial := base.DialContext
+		base.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			if to, ok := overrides[strings.ToLower(addr)]; ok {
+				addr = to
+			}
+			return dial(ctx, network, addr)
+		}
+	}
+
+	switch settings.HTTPVersion {
+	case "":
+	case storage.HTTP1:
+		base.Protocols = new(http.Protocols)
+		base.Protocols.SetHTTP1(true)
+	case storage.HTTP2:
+		base.Protocols = new(http.Protocols)
+		base.Protocols.SetHTTP2(true)
+		base.Protocols.SetUnencryptedHTTP2(true)
+	default:
+		return nil, fmt.Errorf("unsupported http_version %q, use %s or %s", settings.HTTPVersion, storage.HTTP1, storage.HTTP2)
+	}
+
+	if len(settings.ClientCerts) == 0 {
+		return base, nil
+	}
+	router := &certRouter{base: base}
+	for _, cc := range settings.ClientCerts {
+		cert, err := tls.LoadX509KeyPair(storage.ExpandHome(cc.Cert), storage.ExpandHome(cc.Key))
+		if err != nil {
+			return nil, fmt.Errorf("client certificate for %s: %w", cc.Host, err)
+		}
+		t := base.Clone()
+		t.TLSClientConfig.Certificates = []tls.Certificate{cert}
+		router.routes = append(router.routes, certRoute{cert: cc, transport: t})
+	}
+	return router, nil
+}
+
+// parseResolve reads host overrides in curl's --resolve format,
+// host:port:addr, into a map from host:port to addr:port. The first entry
+// for a host:port wins.
+func parseResolve(entries []string) (map[string]string, error) {
+	overrides := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		host, rest, _ := strings.Cut(strings.TrimSpace(entry), ":")
+		port, addr, _ := strings.Cut(rest, ":")
+		addr = strings.TrimSuffix(strings.TrimPrefix(addr, "["), "]")
+		if host == "" || addr == "" {
+			return nil, fmt.Errorf("invalid resolve %q, use host:port:addr", entry)
+		}
+		if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+			return nil, fmt.Errorf("invalid port in resolve %q", entry)
+		}
+		key := net.JoinHostPort(strings.ToLower(host), port)
+		if _, ok := overrides[key]; !ok {
+			overrides[key] = net.JoinHostPort(addr, port)
+		}
+	}
+	return overrides, nil
+}
+
+// unixTransport returns a transport like rt whose connections all go to the
+// Unix domain socket
+func (c *Client) unixTransport(rt http.RoundTripper, socket string) http.RoundTripper {
+	var base *http.Transport
+	switch t := rt.(type) {
+	case *http.Transport:
+		base = t
+	case *certRouter:
+		base = t.base
+	default:
+		base = http.DefaultTransport.(*http.Transport)
+	}
+
+	key := fmt.Sprintf("%p unix:%s", base, socket)
+	if c.transports != nil {
+		c.transports.mu.Lock()
+		defer c.transports.mu.Unlock()
+		if t, ok := c.transports.transports[key]; ok {
+			return t
+		}
+	}
+
+	t := base.Clone()
+	t.Proxy = nil
+	t.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+		var dialer net.Dialer
+		return dialer.DialContext(ctx, "unix", socket)
+	}
+	if c.transports != nil {
+		c.transports.transports[key] = t
+	}
+	return t
+}
+
+// proxyFunc returns the proxy of the settings, or the one from the
+// HTTP_PROXY/HTTPS_PROXY variables when none is set
+func proxyFunc(settings storage.Transport) (func(*http.Request) (*url.URL, error), error) {
+	proxy := http.ProxyFromEnvironment
+	if settings.Proxy != "" {
+		proxyURL, err := url.Parse(settings.Proxy)
+		if err != nil || proxyURL.Host == "" {
+			return nil, fmt.Errorf("invalid proxy %q", settings.Proxy)
+		}
+		switch proxyURL.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return nil, fmt.Errorf("unsupported proxy scheme %q, use http, https or socks5", proxyURL.Scheme)
+		}
+		proxy = http.ProxyURL(proxyURL)
+	}
+	if len(settings.NoProxy) == 0 {
+		return proxy, nil
+	}
+	return func(r *http.Request) (*url.URL, error) {
+		if bypassProxy(r.URL.Hostname(), settings.NoProxy) {
+			return nil, nil
+		}
+		return proxy(r)
+	}, nil
+}
+
+// bypassProxy reports whether host is reached directly. Entries are host
+// names, domains (example.com and .example.com match the subdomains too),
+// CIDRs, or * for every host.
+func bypassProxy(host string, noProxy []string) bool {
+	host = strings.ToLower(host)
+	ip := net.ParseIP(host)
+	for _, entry := range noProxy {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "*" {
+			return true
+		}
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			if ip != nil && network.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		domain := strings.TrimPrefix(entry, ".")
+		if domain != "" && (host == domain || strings.HasSuffix(host, "."+domain)) {
+			return true
+		}
+	}
+	return false
+}
+
+// tlsConfigFor returns the TLS settings: the CA bundle trusted on top of the
+// system roots, certificate verification and the minimum version
+func tlsConfigFor(settings storage.Transport) (*tls.Config, error) {
+	config := &tls.Config{InsecureSkipVerify: settings.InsecureSkipVerify()}
+
+	if settings.MinTLS != "" {
+		version, ok := tlsVersions[settings.MinTLS]
+		if !ok {
+			return nil, fmt.Errorf("unsupported min_tls_version %q, use 1.0, 1.1, 1.2 or 1.3", settings.MinTLS)
+		}
+		config.MinVersion = version
+	}
+
+	if settings.CABundle != "" {
+		pem, err := os.ReadFile(storage.ExpandHome(settings.CABundle))
+		if err != nil {
+			return nil, fmt.Errorf("ca bundle: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("ca bundle %s: no certificates found", settings.CABundle)
+		}
+		config.RootCAs = pool
+	}
+	return config, nil
+}
+
+// certRouter sends requests through the transport holding the client
+// certificate for their host, the first that matches
+type certRouter struct {
+	base   *http.Transport
+	routes []certRoute
+}
+
+type certRoute struct {
+	cert      storage.ClientCert
+	transport *http.Transport
+}
+
+func (r *certRouter) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := req.URL.Hostname()
+	for _, route := range r.routes {
+		if route.cert.Matches(host) {
+			return route.transport.RoundTrip(req)
+		}
+	}
+	return r.base.RoundTrip(req)
+}
+
+// CloseIdleConnections lets http.Client close the connections of every route
+func (r *certRouter) CloseIdleConnections() {
+	r.base.CloseIdleConnections()
+	for _, route := range r.routes {
+		route.transport.CloseIdleConnections()
+	}
+}
@@ -0,0 +1,356 @@
+package http
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/styltsou/tapi/internal/storage"
+)
+
+// writePEM writes a PEM block to a file in dir and returns its path
+func writePEM(t *testing.T, dir, name, blockType string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func insecure() *bool {
+	v := true
+	return &v
+}
+
+func TestClient_WithTransport_CABundle(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	bundle := writePEM(t, t.TempDir(), "ca.pem", "CERTIFICATE", server.Certificate().Raw)
+
+	client := NewClient(5 * time.Second)
+	req := storage.Request{Method: "GET", URL: server.URL}
+
+	if _, err := client.Execute(context.Background(), req, ""); err == nil {
+		t.Fatal("Expected the self-signed certificate to be rejected by default")
+	}
+
+	for name, settings := range map[string]storage.Transport{
+		"ca bundle":            {CABundle: bundle},
+		"insecure skip verify": {Insecure: insecure()},
+	} {
+		withTLS, err := client.WithTransport(settings)
+		if err != nil {
+			t.Fatalf("%s: WithTransport failed: %v", name, err)
+		}
+		resp, err := withTLS.Execute(context.Background(), req, "")
+		if err != nil {
+			t.Fatalf("%s: Execute failed: %v", name, err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("%s: expected 200, got %d", name, resp.StatusCode)
+		}
+	}
+}
+
+func TestClient_WithTransport_ClientCert(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "tapi test client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	certFile := writePEM(t, dir, "client.pem", "CERTIFICATE", certDER)
+	keyFile := writePEM(t, dir, "client-key.pem", "EC PRIVATE KEY", keyDER)
+
+	clientCert, _ := x509.ParseCertificate(certDER)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCert)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.TLS.PeerCertificates[0].Subject.CommonName)
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	server.StartTLS()
+	defer server.Close()
+
+	req := storage.Request{Method: "GET", URL: server.URL}
+	tests := []struct {
+		name    string
+		host    string
+		wantErr bool
+	}{
+		{"certificate for the host", "127.0.0.1", false},
+		{"certificate for another host", "api.example.com", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewClient(5 * time.Second).WithTransport(storage.Transport{
+				Insecure:    insecure(),
+				ClientCerts: []storage.ClientCert{{Host: tt.host, Cert: certFile, Key: keyFile}},
+			})
+			if err != nil {
+				t.Fatalf("WithTransport failed: %v", err)
+			}
+			resp, err := client.Execute(context.Background(), req, "")
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Expected the handshake to fail without a client certificate")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Execute failed: %v", err)
+			}
+			if string(resp.Body) != "tapi test client" {
+				t.Errorf("Expected the server to see the client certificate, got %q", resp.Body)
+			}
+		})
+	}
+}
+
+func TestClient_WithTransport_Proxy(t *testing.T) {
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "proxied "+r.URL.String())
+	}))
+	defer proxy.Close()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "direct")
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		noProxy []string
+		want    string
+	}{
+		{"through the proxy", nil, "proxied " + server.URL + "/"},
+		{"no_proxy cidr", []string{"127.0.0.0/8"}, "direct"},
+		{"no_proxy other host", []string{"example.com"}, "proxied " + server.URL + "/"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewClient(5 * time.Second).WithTransport(storage.Transport{Proxy: proxy.URL, NoProxy: tt.noProxy})
+			if err != nil {
+				t.Fatalf("WithTransport failed: %v", err)
+			}
+			resp, err := client.Execute(context.Background(), storage.Request{Method: "GET", URL: server.URL + "/"}, "")
+			if err != nil {
+				t.Fatalf("Execute failed: %v", err)
+			}
+			if string(resp.Body) != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, resp.Body)
+			}
+		})
+	}
+}
+
+func TestClient_WithTransport_HTTPVersion(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.Proto)
+	})
+	tlsServer := httptest.NewUnstartedServer(handler)
+	tlsServer.EnableHTTP2 = true
+	tlsServer.StartTLS()
+	defer tlsServer.Close()
+
+	h2cServer := httptest.NewUnstartedServer(handler)
+	h2cServer.Config.Protocols = new(http.Protocols)
+	h2cServer.Config.Protocols.SetHTTP1(true)
+	h2cServer.Config.Protocols.SetUnencryptedHTTP2(true)
+	h2cServer.Start()
+	defer h2cServer.Close()
+
+	tests := []struct {
+		name    string
+		url     string
+		version string
+		want    string
+	}{
+		{"negotiated over tls", tlsServer.URL, "", "HTTP/2.0"},
+		{"forced 1.1 over tls", tlsServer.URL, storage.HTTP1, "HTTP/1.1"},
+		{"plain http", h2cServer.URL, "", "HTTP/1.1"},
+		{"prior knowledge h2c", h2cServer.URL, storage.HTTP2, "HTTP/2.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewClient(5 * time.Second).WithTransport(storage.Transport{Insecure: insecure(), HTTPVersion: tt.version})
+			if err != nil {
+				t.Fatalf("WithTransport failed: %v", err)
+			}
+			resp, err := client.Execute(context.Background(), storage.Request{Method: "GET", URL: tt.url}, "")
+			if err != nil {
+				t.Fatalf("Execute failed: %v", err)
+			}
+			if string(resp.Body) != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, resp.Body)
+			}
+		})
+	}
+}
+
+func TestClient_WithTransport_Resolve(t *testing.T) {
+	// The test certificate is issued for example.com, so verification passes
+	// only if the request keeps its host name while connecting to 127.0.0.1
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.Host)
+	}))
+	defer server.Close()
+	bundle := writePEM(t, t.TempDir(), "ca.pem", "CERTIFICATE", server.Certificate().Raw)
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	client, err := NewClient(5 * time.Second).WithTransport(storage.Transport{
+		CABundle: bundle,
+		Resolve:  []string{"example.com:" + port + ":127.0.0.1"},
+	})
+	if err != nil {
+		t.Fatalf("WithTransport failed: %v", err)
+	}
+	resp, err := client.Execute(context.Background(), storage.Request{Method: "GET", URL: "https://example.com:" + port + "/"}, "")
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if want := "example.com:" + port; string(resp.Body) != want {
+		t.Errorf("Expected Host %s, got %s", want, resp.Body)
+	}
+}
+
+func TestClient_Execute_UnixSocket(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "app.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("Unix sockets not available: %v", err)
+	}
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.Host+" "+r.URL.RequestURI())
+	}))
+	server.Listener.Close()
+	server.Listener = listener
+	server.Start()
+	defer server.Close()
+
+	client := NewClient(5 * time.Second)
+	tests := []struct {
+		name    string
+		baseURL string
+		url     string
+	}{
+		{"unix base URL", "unix://" + socket + ":/v1", "containers/json?all=1"},
+		{"unix request URL", "", "unix://" + socket + ":/v1/containers/json?all=1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := client.Execute(context.Background(), storage.Request{Method: "GET", URL: tt.url}, tt.baseURL)
+			if err != nil {
+				t.Fatalf("Execute failed: %v", err)
+			}
+			if want := "localhost /v1/containers/json?all=1"; string(resp.Body) != want {
+				t.Errorf("Expected %q, got %q", want, resp.Body)
+			}
+		})
+	}
+
+	// The socket is used even with a proxy set
+	withProxy, err := client.WithTransport(storage.Transport{Proxy: "http://127.0.0.1:1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := withProxy.Execute(context.Background(), storage.Request{Method: "GET", URL: "unix://" + socket + ":/"}, ""); err != nil {
+		t.Errorf("Expected the request to go over the socket, got %v", err)
+	}
+}
+
+func TestClient_WithTransport_Settings(t *testing.T) {
+	client := NewClient(5 * time.Second)
+
+	if got, _ := client.WithTransport(storage.Transport{}); got != client {
+		t.Error("Expected the client itself without settings")
+	}
+
+	// The same settings share a transport, so connections are reused
+	settings := storage.Transport{Proxy: "socks5://127.0.0.1:1080", MinTLS: "1.2"}
+	a, err := client.WithTransport(settings)
+	if err != nil {
+		t.Fatalf("WithTransport failed: %v", err)
+	}
+	b, _ := client.WithTransport(settings)
+	if a.HTTPClient.Transport != b.HTTPClient.Transport {
+		t.Error("Expected the transport to be reused for the same settings")
+	}
+	if a.HTTPClient == client.HTTPClient {
+		t.Error("Expected the original client to be left unchanged")
+	}
+
+	invalid := map[string]storage.Transport{
+		"proxy scheme": {Proxy: "ftp://proxy:21"},
+		"tls version":  {MinTLS: "1.4"},
+		"http version": {HTTPVersion: "3"},
+		"ca bundle":    {CABundle: filepath.Join(t.TempDir(), "missing.pem")},
+		"resolve":      {Resolve: []string{"api.example.com:https:127.0.0.1"}},
+		"client cert":  {ClientCerts: []storage.ClientCert{{Host: "api.example.com", Cert: "missing.pem", Key: "missing-key.pem"}}},
+	}
+	for name, settings := range invalid {
+		_, err := client.WithTransport(settings)
+		if !errors.Is(err, ErrTransport) {
+			t.Errorf("%s: expected ErrTransport, got %v", name, err)
+		}
+	}
+}
+
+func TestBypassProxy(t *testing.T) {
+	noProxy := []string{"localhost", ".internal.example.com", "corp.example", "10.0.0.0/8"}
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{"localhost", true},
+		{"api.internal.example.com", true},
+		{"internal.example.com", true},
+		{"corp.example", true},
+		{"git.corp.example", true},
+		{"notcorp.example", false},
+		{"10.1.2.3", true},
+		{"11.1.2.3", false},
+		{"api.example.com", false},
+	}
+	for _, tt := range tests {
+		if got := bypassProxy(tt.host, noProxy); got != tt.want {
+			t.Errorf("bypassProxy(%q): expected %v, got %v", tt.host, tt.want, got)
+		}
+	}
+	if !bypassProxy("anything", []string{"*"}) {
+		t.Error("Expected * to bypass the proxy for every host")
+	}
+}
@@ -4,12 +4,15 @@ import (
 	"fmt"
 	"net/url"
 	"strings"
+
+	"github.com/styltsou/tapi/internal/storage"
 )
 
 // ResolveURL combines a base URL and a relative request URL.
 // It ensures that paths are joined correctly, adding a slash if needed.
+// A unix:// base URL gives a unix:// URL on the same socket.
 func ResolveURL(baseURL, reqURL string) (string, error) {
-	if strings.HasPrefix(reqURL, "http://") || strings.HasPrefix(reqURL, "https://") {
+	if strings.HasPrefix(reqURL, "http://") || strings.HasPrefix(reqURL, "https://") || strings.HasPrefix(reqURL, storage.UnixScheme) {
 		return reqURL, nil
 	}
 
@@ -17,6 +20,14 @@ func ResolveURL(baseURL, reqURL string) (string, error) {
 		return reqURL, nil
 	}
 
+	if socket, httpBase, ok := storage.SplitUnixURL(baseURL); ok {
+		resolved, err := ResolveURL(httpBase, reqURL)
+		if err != nil {
+			return "", err
+		}
+		return storage.JoinUnixURL(socket, resolved)
+	}
+
 	// Ensure baseURL ends with a slash to treat it as a directory
 	// This prevents "http://api.com/v1" + "users" becoming "http://api.com/users"
 	if !strings.HasSuffix(baseURL, "/") {
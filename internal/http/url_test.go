@@ -35,6 +35,24 @@ func TestBaseURLResolution(t *testing.T) {
 			reqURL:   "http://other.com/foo",
 			expected: "http://other.com/foo",
 		},
+		{
+			name:     "Unix socket base, req relative",
+			baseURL:  "unix:///var/run/docker.sock:/v1.43",
+			reqURL:   "containers/json?all=1",
+			expected: "unix:///var/run/docker.sock:/v1.43/containers/json?all=1",
+		},
+		{
+			name:     "Unix socket base without path",
+			baseURL:  "unix:///var/run/docker.sock",
+			reqURL:   "/_ping",
+			expected: "unix:///var/run/docker.sock:/_ping",
+		},
+		{
+			name:     "Request is a unix URL",
+			baseURL:  "http://api.com/v1",
+			reqURL:   "unix:///tmp/app.sock:/health",
+			expected: "unix:///tmp/app.sock:/health",
+		},
 	}
 
 	// client := NewClient()
@@ -0,0 +1,95 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httputil"
+	"strings"
+	"sync"
+)
+
+// WireDump is an exchange as it went over the wire, captured at the
+// transport: the request with the headers added by the client and transport
+// (cookies, User-Agent, Accept-Encoding, Content-Length), and the status
+// line and headers of the response as received
+type WireDump struct {
+	Request  string
+	Response string
+}
+
+// String returns the request, then the response headers
+func (w *WireDump) String() string {
+	return strings.TrimRight(w.Request, "\n") + "\n\n" + w.Response
+}
+
+// wireRecorder is a RoundTripper that dumps the exchanges going through it.
+// Only the last one is kept: after a retry or redirect it is the exchange
+// that got the response.
+type wireRecorder struct {
+	next http.RoundTripper
+
+	mu   sync.Mutex
+	last *WireDump
+}
+
+func (w *wireRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	request, err := dumpRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := w.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	response, err := httputil.DumpResponse(resp, false)
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.last = &WireDump{Request: crlfToLF(request), Response: crlfToLF(response)}
+	return resp, nil
+}
+
+// dumpRequest writes req the way the transport does. DumpRequestOut makes a
+// round trip of its own, so it gets a copy without the timing trace and
+// with a fresh body, leaving req untouched.
+func dumpRequest(req *http.Request) ([]byte, error) {
+	dumped := req.WithContext(context.Background())
+	withBody := req.Body == nil || req.Body == http.NoBody
+	if !withBody && req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		dumped.Body = body
+		withBody = true
+	}
+	return httputil.DumpRequestOut(dumped, withBody)
+}
+
+func (w *wireRecorder) dump() *WireDump {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.last
+}
+
+// withWireRecorder returns a copy of httpClient whose exchanges are dumped
+// by the returned recorder
+func withWireRecorder(httpClient *http.Client) (*http.Client, *wireRecorder) {
+	next := httpClient.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	recorder := &wireRecorder{next: next}
+	recording := *httpClient
+	recording.Transport = recorder
+	return &recording, recorder
+}
+
+// crlfToLF turns the line endings of a dump into plain newlines for display
+func crlfToLF(dump []byte) string {
+	return strings.ReplaceAll(string(dump), "\r\n", "\n")
+}
@@ -0,0 +1,69 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/styltsou/tapi/internal/storage"
+)
+
+func TestClient_Execute_CaptureWire(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			w.Header().Set("WWW-Authenticate", `Digest realm="test", nonce="abc", qop="auth"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("X-Trace", "t-1")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id":1}`))
+	}))
+	defer server.Close()
+
+	req := storage.Request{
+		Method:  "POST",
+		URL:     server.URL + "/items?dry_run=1",
+		Headers: storage.Params{{Key: "Content-Type", Value: "application/json", Enabled: true}},
+		Body:    `{"name":"a"}`,
+		Auth:    &storage.Auth{Type: storage.AuthDigest, Username: "user", Password: "pass"},
+	}
+
+	client := NewClient(5 * time.Second)
+	resp, err := client.Execute(context.Background(), req, "")
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if resp.Wire != nil {
+		t.Error("Expected no capture unless enabled")
+	}
+
+	client.CaptureWire = true
+	resp, err = client.Execute(context.Background(), req, "")
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if resp.Wire == nil {
+		t.Fatal("Expected the exchange to be captured")
+	}
+	// The capture is the digest retry, with the headers the transport adds
+	for _, want := range []string{"POST /items?dry_run=1 HTTP/1.1", "Authorization: Digest ", "Content-Length: 12", "User-Agent: Go-http-client/1.1", `{"name":"a"}`} {
+		if !strings.Contains(resp.Wire.Request, want) {
+			t.Errorf("Expected the request dump to contain %q, got:\n%s", want, resp.Wire.Request)
+		}
+	}
+	for _, want := range []string{"HTTP/1.1 201 Created", "X-Trace: t-1"} {
+		if !strings.Contains(resp.Wire.Response, want) {
+			t.Errorf("Expected the response dump to contain %q, got:\n%s", want, resp.Wire.Response)
+		}
+	}
+	if strings.Contains(resp.Wire.Response, `{"id":1}`) || strings.Contains(resp.Wire.String(), "\r") {
+		t.Errorf("Expected headers only with plain newlines, got:\n%q", resp.Wire.Response)
+	}
+	if string(resp.Body) != `{"id":1}` {
+		t.Errorf("Expected the body to still be read, got %q", resp.Body)
+	}
+}
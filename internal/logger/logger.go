@@ -2,27 +2,115 @@
 package logger
 
 import (
+	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/charmbracelet/log"
 )
 
 var Logger *log.Logger
 
+// Options controls where and how the application log is written
+type Options struct {
+	Level      string // debug, info, warn, error
+	Path       string // log file, defaults to ~/.tapi/logs/tapi.log
+	Format     string // text or json
+	MaxSizeMB  int    // rotate once the file grows past this size
+	MaxBackups int    // rotated files kept (tapi.log.1, tapi.log.2, ...)
+}
+
+// DefaultOptions returns the options used when nothing is configured
+func DefaultOptions() Options {
+	return Options{
+		Level:      "info",
+		Format:     "text",
+		MaxSizeMB:  5,
+		MaxBackups: 3,
+	}
+}
+
+// DefaultPath returns the log file location inside the tapi data directory
+func DefaultPath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".tapi", "logs", "tapi.log"), nil
+}
+
 func Init() error {
-	// Create/open log file
-	logFile, err := os.OpenFile("app.log", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o666)
+	return InitWithOptions(DefaultOptions())
+}
+
+// InitWithOptions opens the log file and sets up Logger. Everything written goes
+// through the redaction layer (see Redact). An unknown level or format falls
+// back to the default, with a warning in the log.
+func InitWithOptions(opts Options) error {
+	defaults := DefaultOptions()
+	if opts.Path == "" {
+		path, err := DefaultPath()
+		if err != nil {
+			return err
+		}
+		opts.Path = path
+	}
+	if opts.MaxSizeMB <= 0 {
+		opts.MaxSizeMB = defaults.MaxSizeMB
+	}
+	if opts.MaxBackups < 0 {
+		opts.MaxBackups = 0
+	}
+
+	var warnings []string
+	level := log.InfoLevel
+	if opts.Level != "" {
+		parsed, err := log.ParseLevel(strings.ToLower(opts.Level))
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("Unknown log level %q, using %s", opts.Level, defaults.Level))
+		} else {
+			level = parsed
+		}
+	}
+
+	formatter := log.TextFormatter
+	switch strings.ToLower(opts.Format) {
+	case "", "text":
+	case "json":
+		formatter = log.JSONFormatter
+	default:
+		warnings = append(warnings, fmt.Sprintf("Unknown log format %q, using %s", opts.Format, defaults.Format))
+	}
+
+	if err := os.MkdirAll(filepath.Dir(opts.Path), 0o700); err != nil {
+		return err
+	}
+	file, err := newRotatingFile(opts.Path, int64(opts.MaxSizeMB)*1024*1024, opts.MaxBackups)
 	if err != nil {
 		return err
 	}
 
-	// Create logger that writes to file
-	Logger = log.NewWithOptions(logFile, log.Options{
+	Logger = newLogger(&redactingWriter{w: file}, level, formatter)
+	Logger.Info("Logger initialized", "path", opts.Path)
+	for _, w := range warnings {
+		Logger.Warn(w)
+	}
+	return nil
+}
+
+// InitDiscard sets up a logger that drops everything, for tests
+func InitDiscard() {
+	Logger = newLogger(io.Discard, log.InfoLevel, log.TextFormatter)
+}
+
+func newLogger(w io.Writer, level log.Level, formatter log.Formatter) *log.Logger {
+	return log.NewWithOptions(w, log.Options{
 		ReportTimestamp: true,
 		TimeFormat:      "15:04:05",
 		Prefix:          "API Client",
+		Level:           level,
+		Formatter:       formatter,
 	})
-
-	Logger.Info("Logger initialized")
-	return nil
 }
@@ -0,0 +1,122 @@
+package logger
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/log"
+)
+
+func TestInitWithOptions_JSONAndRedaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "tapi.log")
+	if err := InitWithOptions(Options{Path: path, Format: "json", Level: "warn"}); err != nil {
+		t.Fatalf("InitWithOptions failed: %v", err)
+	}
+	t.Cleanup(InitDiscard)
+
+	Logger.Info("dropped below level")
+	Logger.Warn("Request failed", "url", "https://api.test/?token=abc123")
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read log: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("Expected only the warning to be logged, got %q", data)
+	}
+	var record map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &record); err != nil {
+		t.Fatalf("Expected JSON log record, got %q: %v", lines[0], err)
+	}
+	if url, _ := record["url"].(string); url != "https://api.test/?token="+Redacted {
+		t.Errorf("Expected redacted url, got %q", url)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0o600 {
+		t.Errorf("Expected log mode 0600, got %v", info.Mode().Perm())
+	}
+}
+
+func TestInitWithOptions_RedactsEscapedSecrets(t *testing.T) {
+	// The formats escape these characters, so the secret isn't in the line as is
+	secret := `Zq<a>"w\o&K`
+	RegisterSecrets(secret)
+	for _, format := range []string{"json", "text"} {
+		t.Run(format, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "tapi.log")
+			if err := InitWithOptions(Options{Path: path, Format: format}); err != nil {
+				t.Fatalf("InitWithOptions failed: %v", err)
+			}
+			t.Cleanup(InitDiscard)
+
+			Logger.Info("Request sent", "body", "password="+secret+" end")
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("Failed to read log: %v", err)
+			}
+			if strings.Contains(string(data), "Zq") || strings.Contains(string(data), "K end") {
+				t.Errorf("Expected the secret to be redacted, got %s", data)
+			}
+			if !strings.Contains(string(data), Redacted) {
+				t.Errorf("Expected the redaction marker, got %s", data)
+			}
+		})
+	}
+}
+
+func TestInitWithOptions_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.log")
+	if err := InitWithOptions(Options{Path: path, Level: "loud", Format: "xml"}); err != nil {
+		t.Fatalf("Expected the defaults to be used, got %v", err)
+	}
+	if Logger.GetLevel() != log.InfoLevel {
+		t.Errorf("Expected the info level, got %v", Logger.GetLevel())
+	}
+	Logger.Debug("hidden")
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	for _, want := range []string{`WARN API Client: Unknown log level "loud"`, `WARN API Client: Unknown log format "xml"`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("Expected %q in the text log, got %s", want, data)
+		}
+	}
+	if strings.Contains(string(data), "hidden") {
+		t.Errorf("Expected debug messages to be dropped, got %s", data)
+	}
+}
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tapi.log")
+	r, err := newRotatingFile(path, 10, 2)
+	if err != nil {
+		t.Fatalf("newRotatingFile failed: %v", err)
+	}
+
+	for _, line := range []string{"first-1\n", "second\n", "third-3\n", "fourth\n"} {
+		if _, err := r.Write([]byte(line)); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+
+	expected := map[string]string{
+		path:        "fourth\n",
+		path + ".1": "third-3\n",
+		path + ".2": "second\n",
+	}
+	for file, want := range expected {
+		got, err := os.ReadFile(file)
+		if err != nil || string(got) != want {
+			t.Errorf("Expected %s to contain %q, got %q (%v)", filepath.Base(file), want, got, err)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Error("Expected only 2 backups to be kept")
+	}
+}
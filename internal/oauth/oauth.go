@@ -0,0 +1,406 @@
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/styltsou/tapi/internal/logger"
+	"github.com/styltsou/tapi/internal/storage"
+)
+
+// ErrNoRefreshToken is returned by Refresh when there is nothing to refresh with
+var ErrNoRefreshToken = errors.New("no refresh token")
+
+// SignInTimeout bounds the wait for the user to sign in in the browser
+// during the authorization code grant
+const SignInTimeout = 2 * time.Minute
+
+// Manager hands out access tokens for OAuth2 auth configurations, from the
+// cache of the active environment when possible
+type Manager struct {
+	Store       *Store
+	HTTPClient  *http.Client           // token requests, unless the context carries a client, see WithHTTPClient
+	OpenBrowser func(url string) error // authorization_code; defaults to the system browser
+
+	envMu sync.RWMutex
+	env   string
+
+	// Fetches in flight, by environment and cache key, so parallel requests
+	// share a new token instead of each running the grant
+	flightsMu sync.Mutex
+	flights   map[string]*flight
+}
+
+// flight is a token fetch that callers for the same token wait on
+type flight struct {
+	done  chan struct{}
+	token Token
+	err   error
+}
+
+// NewManager returns a manager caching tokens in store
+func NewManager(store *Store) *Manager {
+	return &Manager{
+		Store:       store,
+		HTTPClient:  &http.Client{Timeout: 30 * time.Second},
+		OpenBrowser: openBrowser,
+	}
+}
+
+// httpClientKey is the context key of the client set by WithHTTPClient
+type httpClientKey struct{}
+
+// WithHTTPClient returns ctx with the client the token requests made under it
+// are sent with, e.g. one with the proxy and TLS settings of the request the
+// token is for
+func WithHTTPClient(ctx context.Context, client *http.Client) context.Context {
+	return context.WithValue(ctx, httpClientKey{}, client)
+}
+
+// httpClient returns the client to send token requests with under ctx
+func (m *Manager) httpClient(ctx context.Context) *http.Client {
+	if client, ok := ctx.Value(httpClientKey{}).(*http.Client); ok && client != nil {
+		return client
+	}
+	return m.HTTPClient
+}
+
+// SetEnvironment selects the environment whose tokens are used
+func (m *Manager) SetEnvironment(name string) {
+	m.envMu.Lock()
+	defer m.envMu.Unlock()
+	m.env = name
+}
+
+// Environment returns the environment whose tokens are used
+func (m *Manager) Environment() string {
+	m.envMu.RLock()
+	defer m.envMu.RUnlock()
+	return m.env
+}
+
+// Token returns a usable access token. A cached token is used unless it has
+// expired or forceRefresh is set (e.g. the server answered 401); otherwise
+// the cached refresh token is tried before running the grant again.
+func (m *Manager) Token(ctx context.Context, auth storage.Auth, forceRefresh bool) (Token, error) {
+	env, key := m.Environment(), CacheKey(auth)
+
+	// A fetch of the same token already running is waited for
+	id := env + "\x00" + key
+	m.flightsMu.Lock()
+	if f, ok := m.flights[id]; ok {
+		m.flightsMu.Unlock()
+		select {
+		case <-f.done:
+			return f.token, f.err
+		case <-ctx.Done():
+			return Token{}, ctx.Err()
+		}
+	}
+	f := &flight{done: make(chan struct{})}
+	if m.flights == nil {
+		m.flights = make(map[string]*flight)
+	}
+	m.flights[id] = f
+	m.flightsMu.Unlock()
+
+	f.token, f.err = m.token(ctx, env, key, auth, forceRefresh)
+
+	m.flightsMu.Lock()
+	delete(m.flights, id)
+	m.flightsMu.Unlock()
+	close(f.done)
+	return f.token, f.err
+}
+
+// token is Token for the cache entry key of env
+func (m *Manager) token(ctx context.Context, env, key string, auth storage.Auth, forceRefresh bool) (Token, error) {
+	cached, ok, err := m.Store.Get(env, key)
+	if err != nil {
+		logger.Logger.Warn("Cannot read the token cache", "error", err)
+	}
+	if ok && !forceRefresh && cached.Valid() {
+		return cached, nil
+	}
+
+	var token Token
+	if ok && cached.RefreshToken != "" {
+		token, err = m.Refresh(ctx, auth, cached.RefreshToken)
+		if err != nil {
+			logger.Logger.Info("Token refresh failed, requesting a new token", "error", err)
+		}
+	}
+	if token.AccessToken == "" {
+		token, err = m.fetch(ctx, auth)
+		if err != nil {
+			return Token{}, err
+		}
+	}
+
+	if err := m.Store.Set(env, key, token); err != nil {
+		logger.Logger.Warn("Cannot write the token cache", "error", err)
+	}
+	return token, nil
+}
+
+// Cached returns the cached token of an auth configuration, if any
+func (m *Manager) Cached(auth storage.Auth) (Token, bool, error) {
+	return m.Store.Get(m.Environment(), CacheKey(auth))
+}
+
+// Clear removes the cached token of an auth configuration
+func (m *Manager) Clear(auth storage.Auth) error {
+	return m.Store.Delete(m.Environment(), CacheKey(auth))
+}
+
+// fetch runs the configured grant
+func (m *Manager) fetch(ctx context.Context, auth storage.Auth) (Token, error) {
+	switch auth.EffectiveGrant() {
+	case storage.GrantClientCredentials:
+		form := url.Values{"grant_type": {"client_credentials"}}
+		return m.exchange(ctx, auth, form)
+	case storage.GrantPassword:
+		form := url.Values{
+			"grant_type": {"password"},
+			"username":   {auth.Username},
+			"password":   {auth.Password},
+		}
+		return m.exchange(ctx, auth, form)
+	case storage.GrantRefreshToken:
+		return m.Refresh(ctx, auth, auth.RefreshToken)
+	case storage.GrantAuthorizationCode:
+		return m.authorize(ctx, auth)
+	default:
+		return Token{}, fmt.Errorf("unsupported oauth2 grant %q", auth.Grant)
+	}
+}
+
+// Refresh exchanges a refresh token for a new access token. Servers that do
+// not rotate refresh tokens omit it from the answer, the old one is kept.
+func (m *Manager) Refresh(ctx context.Context, auth storage.Auth, refreshToken string) (Token, error) {
+	if refreshToken == "" {
+		return Token{}, ErrNoRefreshToken
+	}
+	form := url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {refreshToken},
+	}
+	token, err := m.exchange(ctx, auth, form)
+	if err != nil {
+		return Token{}, err
+	}
+	if token.RefreshToken == "" {
+		token.RefreshToken = refreshToken
+	}
+	return token, nil
+}
+
+// tokenResponse is the JSON answer of a token endpoint (RFC 6749 section 5)
+type tokenResponse struct {
+	AccessToken      string          `json:"access_token"`
+	TokenType        string          `json:"token_type"`
+	RefreshToken     string          `json:"refresh_token"`
+	Scope            string          `json:"scope"`
+	ExpiresIn        json.RawMessage `json:"expires_in"` // a number, sent as a string by some servers
+	Error            string          `json:"error"`
+	ErrorDescription string          `json:"error_description"`
+}
+
+// exchange posts a grant to the token endpoint. A client secret is sent with
+// HTTP Basic auth; public clients send their ID in the form.
+func (m *Manager) exchange(ctx context.Context, auth storage.Auth, form url.Values) (Token, error) {
+	if auth.TokenURL == "" {
+		return Token{}, errors.New("oauth2 token URL is not set")
+	}
+	if auth.Scope != "" && form.Get("grant_type") != "authorization_code" {
+		form.Set("scope", auth.Scope)
+	}
+	if auth.ClientSecret == "" {
+		form.Set("client_id", auth.ClientID)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, auth.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return Token{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if auth.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(auth.ClientID), url.QueryEscape(auth.ClientSecret))
+	}
+
+	resp, err := m.httpClient(ctx).Do(req)
+	if err != nil {
+		return Token{}, fmt.Errorf("token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return Token{}, fmt.Errorf("token request failed: %w", err)
+	}
+
+	var tr tokenResponse
+	if err := json.Unmarshal(body, &tr); err != nil {
+		return Token{}, fmt.Errorf("token endpoint returned %s and no JSON token", resp.Status)
+	}
+	if tr.Error != "" {
+		if tr.ErrorDescription != "" {
+			return Token{}, fmt.Errorf("token endpoint: %s: %s", tr.Error, tr.ErrorDescription)
+		}
+		return Token{}, fmt.Errorf("token endpoint: %s", tr.Error)
+	}
+	if resp.StatusCode >= 300 || tr.AccessToken == "" {
+		return Token{}, fmt.Errorf("token endpoint returned %s without an access token", resp.Status)
+	}
+
+	token := Token{
+		AccessToken:  tr.AccessToken,
+		TokenType:    tr.TokenType,
+		RefreshToken: tr.RefreshToken,
+		Scope:        tr.Scope,
+	}
+	if secs, err := strconv.Atoi(strings.Trim(string(tr.ExpiresIn), `"`)); err == nil && secs > 0 {
+		token.Expiry = time.Now().Add(time.Duration(secs) * time.Second)
+	}
+	logger.Logger.Info("OAuth2 token obtained", "grant", form.Get("grant_type"), "token_url", auth.TokenURL, "expiry", token.Expiry)
+	return token, nil
+}
+
+// authorize runs the authorization code grant with PKCE (RFC 7636): the user
+// signs in in the browser, which is redirected to a listener on the loopback
+// interface with the code
+func (m *Manager) authorize(ctx context.Context, auth storage.Auth) (Token, error) {
+	if auth.AuthURL == "" {
+		return Token{}, errors.New("oauth2 authorization URL is not set")
+	}
+	verifier, err := randomString(32)
+	if err != nil {
+		return Token{}, err
+	}
+	state, err := randomString(16)
+	if err != nil {
+		return Token{}, err
+	}
+	challenge := sha256.Sum256([]byte(verifier))
+
+	listener, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(auth.RedirectPort)))
+	if err != nil {
+		return Token{}, fmt.Errorf("cannot listen for the oauth2 redirect: %w", err)
+	}
+	defer listener.Close()
+	redirectURI := "http://" + listener.Addr().String() + "/callback"
+
+	type result struct {
+		code string
+		err  error
+	}
+	results := make(chan result, 1)
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/callback" {
+			http.NotFound(w, r)
+			return
+		}
+		q := r.URL.Query()
+		var res result
+		switch {
+		case q.Get("state") != state:
+			res.err = errors.New("oauth2 redirect with an unexpected state")
+		case q.Get("error") != "":
+			res.err = fmt.Errorf("authorization failed: %s %s", q.Get("error"), q.Get("error_description"))
+		case q.Get("code") == "":
+			res.err = errors.New("oauth2 redirect without a code")
+		default:
+			res.code = q.Get("code")
+		}
+		if res.err != nil {
+			http.Error(w, res.err.Error(), http.StatusBadRequest)
+		} else {
+			fmt.Fprintln(w, "Signed in, you can close this window and go back to tapi.")
+		}
+		select {
+		case results <- res:
+		default:
+		}
+	})}
+	go server.Serve(listener)
+	defer server.Close()
+
+	authURL, err := url.Parse(auth.AuthURL)
+	if err != nil {
+		return Token{}, fmt.Errorf("invalid oauth2 authorization URL: %w", err)
+	}
+	q := authURL.Query()
+	q.Set("response_type", "code")
+	q.Set("client_id", auth.ClientID)
+	q.Set("redirect_uri", redirectURI)
+	q.Set("state", state)
+	q.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	q.Set("code_challenge_method", "S256")
+	if auth.Scope != "" {
+		q.Set("scope", auth.Scope)
+	}
+	authURL.RawQuery = q.Encode()
+
+	logger.Logger.Info("Waiting for oauth2 authorization in the browser", "redirect_uri", redirectURI)
+	if err := m.OpenBrowser(authURL.String()); err != nil {
+		return Token{}, fmt.Errorf("cannot open the browser: %w", err)
+	}
+
+	wait := time.NewTimer(SignInTimeout)
+	defer wait.Stop()
+	var res result
+	select {
+	case res = <-results:
+	case <-wait.C:
+		return Token{}, fmt.Errorf("no oauth2 sign-in in the browser within %s", SignInTimeout)
+	case <-ctx.Done():
+		return Token{}, ctx.Err()
+	}
+	if res.err != nil {
+		return Token{}, res.err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {res.code},
+		"redirect_uri":  {redirectURI},
+		"code_verifier": {verifier},
+	}
+	return m.exchange(ctx, auth, form)
+}
+
+// randomString returns n random bytes, base64url encoded
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// openBrowser opens url with the system's default browser
+func openBrowser(url string) error {
+	switch runtime.GOOS {
+	case "darwin":
+		return exec.Command("open", url).Start()
+	case "windows":
+		return exec.Command("rundll32", "url.dll,FileProtocolHandler", url).Start()
+	default:
+		return exec.Command("xdg-open", url).Start()
+	}
+}
@@ -0,0 +1,296 @@
+package oauth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/styltsou/tapi/internal/logger"
+	"github.com/styltsou/tapi/internal/storage"
+)
+
+func TestMain(m *testing.M) {
+	logger.InitDiscard()
+	os.Exit(m.Run())
+}
+
+// tokenServer is a stand-in token endpoint. It issues numbered tokens and
+// records the grants it received.
+type tokenServer struct {
+	*httptest.Server
+	mu        sync.Mutex
+	grants    []string
+	issued    int
+	expiresIn int
+	verifiers map[string]string // authorization code -> PKCE challenge
+}
+
+func newTokenServer(t *testing.T) *tokenServer {
+	ts := &tokenServer{expiresIn: 3600, verifiers: map[string]string{}}
+	ts.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ts.mu.Lock()
+		defer ts.mu.Unlock()
+
+		if r.URL.Path == "/authorize" {
+			// Sign the user in right away and send them back with a code
+			q := r.URL.Query()
+			code := fmt.Sprintf("code-%d", len(ts.verifiers)+1)
+			ts.verifiers[code] = q.Get("code_challenge")
+			redirect := q.Get("redirect_uri") + "?" + url.Values{"code": {code}, "state": {q.Get("state")}}.Encode()
+			http.Redirect(w, r, redirect, http.StatusFound)
+			return
+		}
+
+		r.ParseForm()
+		grant := r.Form.Get("grant_type")
+		ts.grants = append(ts.grants, grant)
+		w.Header().Set("Content-Type", "application/json")
+
+		fail := func(code string) {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": code, "error_description": "rejected by the test server"})
+		}
+		switch grant {
+		case "client_credentials":
+			if user, pass, ok := r.BasicAuth(); !ok || user != "app" || pass != "s3cret" {
+				fail("invalid_client")
+				return
+			}
+		case "password":
+			if r.Form.Get("username") != "alice" || r.Form.Get("password") != "pw" {
+				fail("invalid_grant")
+				return
+			}
+		case "refresh_token":
+			if r.Form.Get("refresh_token") == "revoked" {
+				fail("invalid_grant")
+				return
+			}
+		case "authorization_code":
+			sum := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+			if ts.verifiers[r.Form.Get("code")] != base64.RawURLEncoding.EncodeToString(sum[:]) || r.Form.Get("client_id") != "cli" {
+				fail("invalid_grant")
+				return
+			}
+		}
+
+		ts.issued++
+		json.NewEncoder(w).Encode(map[string]any{
+			"access_token":  fmt.Sprintf("token-%d", ts.issued),
+			"token_type":    "Bearer",
+			"refresh_token": fmt.Sprintf("refresh-%d", ts.issued),
+			"expires_in":    ts.expiresIn,
+		})
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func (ts *tokenServer) grantLog() []string {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	return append([]string(nil), ts.grants...)
+}
+
+func newTestManager(t *testing.T) *Manager {
+	m := NewManager(NewStore(t.TempDir()))
+	m.SetEnvironment("dev")
+	return m
+}
+
+func TestManager_ClientCredentialsCache(t *testing.T) {
+	ts := newTokenServer(t)
+	m := newTestManager(t)
+	auth := storage.Auth{Type: storage.AuthOAuth2, TokenURL: ts.URL + "/token", ClientID: "app", ClientSecret: "s3cret", Scope: "read"}
+
+	first, err := m.Token(context.Background(), auth, false)
+	if err != nil {
+		t.Fatalf("Token failed: %v", err)
+	}
+	second, err := m.Token(context.Background(), auth, false)
+	if err != nil {
+		t.Fatalf("Token failed: %v", err)
+	}
+	if first.AccessToken != "token-1" || second.AccessToken != "token-1" || len(ts.grantLog()) != 1 {
+		t.Errorf("Expected the cached token to be reused, got %q then %q after %v", first.AccessToken, second.AccessToken, ts.grantLog())
+	}
+
+	// Tokens are cached per environment, in a file only the user can read
+	path := filepath.Join(m.Store.dir, "dev.yaml")
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Expected the token cache at %s: %v", path, err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("Expected mode 0600, got %v", info.Mode().Perm())
+	}
+	m.SetEnvironment("prod")
+	if _, ok, _ := m.Cached(auth); ok {
+		t.Error("Expected no token cached for another environment")
+	}
+
+	// A new manager reads the cache from disk
+	other := NewManager(m.Store)
+	other.SetEnvironment("dev")
+	if token, ok, _ := other.Cached(auth); !ok || token.AccessToken != "token-1" {
+		t.Errorf("Expected token-1 from disk, got %+v", token)
+	}
+}
+
+func TestManager_Refresh(t *testing.T) {
+	ts := newTokenServer(t)
+	ts.expiresIn = 1 // expires within the skew, so it is renewed at once
+	m := newTestManager(t)
+	auth := storage.Auth{Type: storage.AuthOAuth2, TokenURL: ts.URL + "/token", ClientID: "app", ClientSecret: "s3cret"}
+
+	if _, err := m.Token(context.Background(), auth, false); err != nil {
+		t.Fatalf("Token failed: %v", err)
+	}
+	token, err := m.Token(context.Background(), auth, false)
+	if err != nil {
+		t.Fatalf("Token failed: %v", err)
+	}
+	if token.AccessToken != "token-2" {
+		t.Errorf("Expected a renewed token, got %q", token.AccessToken)
+	}
+
+	// forceRefresh renews a valid token, e.g. after a 401
+	ts.expiresIn = 3600
+	if token, _ = m.Token(context.Background(), auth, true); token.AccessToken != "token-3" {
+		t.Errorf("Expected a forced renewal, got %q", token.AccessToken)
+	}
+	want := []string{"client_credentials", "refresh_token", "refresh_token"}
+	if got := ts.grantLog(); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Expected grants %v, got %v", want, got)
+	}
+
+	// A rejected refresh token falls back to the grant
+	m.Store.Set("dev", CacheKey(auth), Token{AccessToken: "old", RefreshToken: "revoked", Expiry: time.Now().Add(-time.Hour)})
+	if token, err = m.Token(context.Background(), auth, false); err != nil || token.AccessToken != "token-4" {
+		t.Errorf("Expected a new token after the refresh failed, got %q (%v)", token.AccessToken, err)
+	}
+}
+
+func TestManager_Grants(t *testing.T) {
+	ts := newTokenServer(t)
+
+	tests := []struct {
+		name    string
+		auth    storage.Auth
+		wantErr bool
+	}{
+		{"password", storage.Auth{Grant: storage.GrantPassword, ClientID: "cli", Username: "alice", Password: "pw"}, false},
+		{"wrong password", storage.Auth{Grant: storage.GrantPassword, ClientID: "cli", Username: "alice", Password: "nope"}, true},
+		{"refresh token", storage.Auth{Grant: storage.GrantRefreshToken, ClientID: "cli", RefreshToken: "long-lived"}, false},
+		{"wrong client secret", storage.Auth{ClientID: "app", ClientSecret: "nope"}, true},
+		{"authorization code", storage.Auth{Grant: storage.GrantAuthorizationCode, ClientID: "cli", AuthURL: ts.URL + "/authorize"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestManager(t)
+			// The "browser" follows the authorization URL, which redirects to the listener
+			m.OpenBrowser = func(u string) error {
+				go http.Get(u)
+				return nil
+			}
+			auth := tt.auth
+			auth.Type = storage.AuthOAuth2
+			auth.TokenURL = ts.URL + "/token"
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			token, err := m.Token(ctx, auth, false)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected an error, got token %+v", token)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Token failed: %v", err)
+			}
+			if token.AccessToken == "" || token.TokenType != "Bearer" {
+				t.Errorf("Unexpected token: %+v", token)
+			}
+		})
+	}
+}
+
+func TestManager_Clear(t *testing.T) {
+	ts := newTokenServer(t)
+	m := newTestManager(t)
+	auth := storage.Auth{Type: storage.AuthOAuth2, TokenURL: ts.URL + "/token", ClientID: "app", ClientSecret: "s3cret"}
+
+	if _, err := m.Token(context.Background(), auth, false); err != nil {
+		t.Fatalf("Token failed: %v", err)
+	}
+	if err := m.Clear(auth); err != nil {
+		t.Fatalf("Clear failed: %v", err)
+	}
+	if _, ok, _ := m.Cached(auth); ok {
+		t.Error("Expected the token to be removed")
+	}
+}
+
+func TestManager_ParallelFetch(t *testing.T) {
+	release := make(chan struct{})
+	var mu sync.Mutex
+	grants := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		grants++
+		mu.Unlock()
+		<-release
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"access_token": "slow", "expires_in": 3600})
+	}))
+	defer server.Close()
+	m := newTestManager(t)
+	auth := storage.Auth{Type: storage.AuthOAuth2, TokenURL: server.URL, ClientID: "app", ClientSecret: "s3cret"}
+
+	var wg sync.WaitGroup
+	tokens := make([]string, 3)
+	for i := range tokens {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if token, err := m.Token(context.Background(), auth, false); err == nil {
+				tokens[i] = token.AccessToken
+			}
+		}()
+	}
+
+	// The environment stays readable while the token is fetched
+	done := make(chan struct{})
+	go func() {
+		m.Environment()
+		m.Cached(auth)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected Environment and Cached not to wait for the fetch")
+	}
+
+	close(release)
+	wg.Wait()
+	for i, token := range tokens {
+		if token != "slow" {
+			t.Errorf("Expected caller %d to get the fetched token, got %q", i, token)
+		}
+	}
+	if grants != 1 {
+		t.Errorf("Expected the callers to share one fetch, got %d", grants)
+	}
+}
@@ -0,0 +1,141 @@
+// Package oauth obtains, caches and refreshes OAuth 2.0 access tokens
+package oauth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gosimple/slug"
+	"github.com/styltsou/tapi/internal/storage"
+	"gopkg.in/yaml.v3"
+)
+
+// expirySkew renews tokens slightly before they expire, so a token is not
+// rejected while the request is in flight
+const expirySkew = 30 * time.Second
+
+// Token is an access token returned by a token endpoint
+type Token struct {
+	AccessToken  string    `yaml:"access_token"`
+	TokenType    string    `yaml:"token_type,omitempty"`
+	RefreshToken string    `yaml:"refresh_token,omitempty"`
+	Scope        string    `yaml:"scope,omitempty"`
+	Expiry       time.Time `yaml:"expiry,omitempty"` // zero when the server gave no lifetime
+}
+
+// Valid reports whether the token can still be used
+func (t Token) Valid() bool {
+	if t.AccessToken == "" {
+		return false
+	}
+	return t.Expiry.IsZero() || time.Now().Add(expirySkew).Before(t.Expiry)
+}
+
+// CacheKey identifies the tokens of an OAuth2 configuration: the same token
+// endpoint, client, grant, scope and user share a token
+func CacheKey(auth storage.Auth) string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{
+		auth.EffectiveGrant(), auth.TokenURL, auth.ClientID, auth.Scope, auth.Username,
+	}, "\n")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Store caches tokens on disk, one file per environment
+type Store struct {
+	mu  sync.Mutex
+	dir string
+}
+
+// NewStore returns a store keeping its files in dir
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// DefaultStore returns the store in ~/.tapi/tokens
+func DefaultStore() (*Store, error) {
+	dir, err := storage.GetStoragePath("tokens")
+	if err != nil {
+		return nil, err
+	}
+	return NewStore(dir), nil
+}
+
+// Get returns the cached token, valid or not
+func (s *Store) Get(env, key string) (Token, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tokens, err := s.load(env)
+	if err != nil {
+		return Token{}, false, err
+	}
+	t, ok := tokens[key]
+	return t, ok, nil
+}
+
+// Set caches a token
+func (s *Store) Set(env, key string, t Token) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tokens, err := s.load(env)
+	if err != nil {
+		return err
+	}
+	tokens[key] = t
+	return s.save(env, tokens)
+}
+
+// Delete removes a cached token
+func (s *Store) Delete(env, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tokens, err := s.load(env)
+	if err != nil {
+		return err
+	}
+	if _, ok := tokens[key]; !ok {
+		return nil
+	}
+	delete(tokens, key)
+	return s.save(env, tokens)
+}
+
+func (s *Store) path(env string) string {
+	name := slug.Make(env)
+	if name == "" {
+		name = "default"
+	}
+	return filepath.Join(s.dir, name+".yaml")
+}
+
+func (s *Store) load(env string) (map[string]Token, error) {
+	tokens := make(map[string]Token)
+	data, err := os.ReadFile(s.path(env))
+	if errors.Is(err, os.ErrNotExist) {
+		return tokens, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, &tokens); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// save writes the tokens readable by the user only, they are credentials
+func (s *Store) save(env string, tokens map[string]Token) error {
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return err
+	}
+	data, err := yaml.Marshal(tokens)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path(env), data, 0o600)
+}
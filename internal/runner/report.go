@@ -0,0 +1,282 @@
+package runner
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Supported report formats
+const (
+	FormatJUnit = "junit"
+	FormatJSON  = "json"
+	FormatTAP   = "tap"
+)
+
+// reportEntry is the flattened, serializable view of a Result shared by all formats
+type reportEntry struct {
+	Collection    string  `json:"collection"`
+	Name          string  `json:"name"`
+	Method        string  `json:"method"`
+	URL           string  `json:"url"`
+	StatusCode    int     `json:"status_code,omitempty"`
+	DurationMs    float64 `json:"duration_ms"`
+	Size          int64   `json:"size"`
+	Truncated     bool    `json:"truncated"`
+	Passed        bool    `json:"passed"`
+	FailureKind   string  `json:"failure_kind,omitempty"`
+	FailureReason string  `json:"failure_reason,omitempty"`
+
+	Assertions []assertionEntry `json:"assertions,omitempty"`
+}
+
+type assertionEntry struct {
+	Assertion string `json:"assertion"`
+	Passed    bool   `json:"passed"`
+	Message   string `json:"message,omitempty"`
+}
+
+func newReportEntry(r Result) reportEntry {
+	e := reportEntry{
+		Collection:    r.Collection,
+		Name:          r.Request.Name,
+		Method:        r.Request.Method,
+		URL:           r.URL,
+		DurationMs:    float64(r.Duration.Microseconds()) / 1000,
+		Passed:        !r.Failed(),
+		FailureKind:   r.FailureKind(),
+		FailureReason: r.FailureReason(),
+	}
+	if r.Response != nil {
+		e.StatusCode = r.Response.StatusCode
+		e.Size = r.Response.Size
+		e.Truncated = r.Response.Truncated
+	}
+	for _, a := range r.Assertions {
+		e.Assertions = append(e.Assertions, assertionEntry{
+			Assertion: a.Assertion.String(),
+			Passed:    a.Passed,
+			Message:   a.Message,
+		})
+	}
+	return e
+}
+
+// WriteReportFile writes the results in the given format to path
+func WriteReportFile(path, format string, results []Result) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create report: %w", err)
+	}
+	defer f.Close()
+
+	switch format {
+	case FormatJUnit:
+		err = WriteJUnit(f, results)
+	case FormatJSON:
+		err = WriteJSON(f, results)
+	case FormatTAP:
+		err = WriteTAP(f, results)
+	default:
+		err = fmt.Errorf("unknown report format %q", format)
+	}
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// ========================================
+// JSON
+// ========================================
+
+type jsonReport struct {
+	Total   int           `json:"total"`
+	Passed  int           `json:"passed"`
+	Failed  int           `json:"failed"`
+	Results []reportEntry `json:"results"`
+}
+
+// WriteJSON writes a JSON report with totals and one entry per request
+func WriteJSON(w io.Writer, results []Result) error {
+	passed, failed := Count(results)
+	report := jsonReport{
+		Total:   len(results),
+		Passed:  passed,
+		Failed:  failed,
+		Results: make([]reportEntry, 0, len(results)),
+	}
+	for _, r := range results {
+		report.Results = append(report.Results, newReportEntry(r))
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
+
+// ========================================
+// JUnit XML
+// ========================================
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name       string          `xml:"name,attr"`
+	Classname  string          `xml:"classname,attr"`
+	Time       string          `xml:"time,attr"`
+	Properties []junitProperty `xml:"properties>property"`
+	Failure    *junitFailure   `xml:"failure,omitempty"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+func junitSeconds(ms float64) string {
+	return fmt.Sprintf("%.3f", ms/1000)
+}
+
+// WriteJUnit writes a JUnit XML report with one test suite per collection
+func WriteJUnit(w io.Writer, results []Result) error {
+	root := junitTestSuites{}
+	suiteIdx := map[string]int{}
+	var totalMs float64
+	suiteMs := map[string]float64{}
+
+	for _, r := range results {
+		e := newReportEntry(r)
+		idx, ok := suiteIdx[e.Collection]
+		if !ok {
+			idx = len(root.Suites)
+			suiteIdx[e.Collection] = idx
+			root.Suites = append(root.Suites, junitTestSuite{Name: e.Collection})
+		}
+
+		tc := junitTestCase{
+			Name:      e.Name,
+			Classname: e.Collection,
+			Time:      junitSeconds(e.DurationMs),
+			Properties: []junitProperty{
+				{Name: "method", Value: e.Method},
+				{Name: "url", Value: e.URL},
+				{Name: "status_code", Value: fmt.Sprint(e.StatusCode)},
+				{Name: "size", Value: fmt.Sprint(e.Size)},
+				{Name: "truncated", Value: fmt.Sprint(e.Truncated)},
+			},
+		}
+		for _, a := range e.Assertions {
+			result := "passed"
+			if !a.Passed {
+				result = "failed: " + a.Message
+			}
+			tc.Properties = append(tc.Properties, junitProperty{Name: "assertion " + a.Assertion, Value: result})
+		}
+		if !e.Passed {
+			tc.Failure = &junitFailure{
+				Message: e.FailureReason,
+				Type:    e.FailureKind,
+				Text:    fmt.Sprintf("%s %s\n%s", e.Method, e.URL, e.FailureReason),
+			}
+			root.Suites[idx].Failures++
+			root.Failures++
+		}
+
+		root.Suites[idx].Cases = append(root.Suites[idx].Cases, tc)
+		root.Suites[idx].Tests++
+		root.Tests++
+		suiteMs[e.Collection] += e.DurationMs
+		totalMs += e.DurationMs
+	}
+
+	for i := range root.Suites {
+		root.Suites[i].Time = junitSeconds(suiteMs[root.Suites[i].Name])
+	}
+	root.Time = junitSeconds(totalMs)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(root); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// ========================================
+// TAP
+// ========================================
+
+// WriteTAP writes a TAP version 13 report with YAML diagnostics per request
+func WriteTAP(w io.Writer, results []Result) error {
+	var sb strings.Builder
+	sb.WriteString("TAP version 13\n")
+	fmt.Fprintf(&sb, "1..%d\n", len(results))
+
+	for i, r := range results {
+		e := newReportEntry(r)
+		status := "ok"
+		if !e.Passed {
+			status = "not ok"
+		}
+		fmt.Fprintf(&sb, "%s %d - %s / %s\n", status, i+1, tapEscape(e.Collection), tapEscape(e.Name))
+
+		sb.WriteString("  ---\n")
+		fmt.Fprintf(&sb, "  method: %s\n", e.Method)
+		fmt.Fprintf(&sb, "  url: %q\n", e.URL)
+		fmt.Fprintf(&sb, "  status_code: %d\n", e.StatusCode)
+		fmt.Fprintf(&sb, "  duration_ms: %.3f\n", e.DurationMs)
+		fmt.Fprintf(&sb, "  size: %d\n", e.Size)
+		fmt.Fprintf(&sb, "  truncated: %t\n", e.Truncated)
+		if !e.Passed {
+			fmt.Fprintf(&sb, "  failure_kind: %s\n", e.FailureKind)
+			fmt.Fprintf(&sb, "  message: %q\n", e.FailureReason)
+		}
+		if len(e.Assertions) > 0 {
+			sb.WriteString("  assertions:\n")
+			for _, a := range e.Assertions {
+				fmt.Fprintf(&sb, "    - assertion: %q\n", a.Assertion)
+				fmt.Fprintf(&sb, "      passed: %t\n", a.Passed)
+				if !a.Passed {
+					fmt.Fprintf(&sb, "      message: %q\n", a.Message)
+				}
+			}
+		}
+		sb.WriteString("  ...\n")
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// tapEscape escapes characters with special meaning in a TAP description
+func tapEscape(s string) string {
+	return strings.NewReplacer("#", `\#`, "\n", " ").Replace(s)
+}
@@ -0,0 +1,106 @@
+package runner
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/styltsou/tapi/internal/http"
+	"github.com/styltsou/tapi/internal/storage"
+)
+
+func sampleResults() []Result {
+	return []Result{
+		{
+			Collection: "API",
+			Request:    storage.Request{Name: "List", Method: "GET"},
+			URL:        "http://x/list",
+			Duration:   120 * time.Millisecond,
+			Response:   &http.ProcessedResponse{StatusCode: 200, Status: "200 OK", Size: 2048, Truncated: true},
+		},
+		{
+			Collection: "API",
+			Request:    storage.Request{Name: "Slow", Method: "POST"},
+			URL:        "http://x/slow",
+			Duration:   time.Second,
+			Err:        http.ErrTimeout,
+		},
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteJSON(&buf, sampleResults()); err != nil {
+		t.Fatalf("WriteJSON failed: %v", err)
+	}
+
+	var report jsonReport
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if report.Total != 2 || report.Failed != 1 {
+		t.Errorf("Unexpected totals: %+v", report)
+	}
+	first := report.Results[0]
+	if first.URL != "http://x/list" || first.StatusCode != 200 || first.Size != 2048 || !first.Truncated || first.DurationMs != 120 {
+		t.Errorf("Unexpected first entry: %+v", first)
+	}
+	if report.Results[1].FailureKind != "timeout" || report.Results[1].FailureReason != "request timeout" {
+		t.Errorf("Unexpected failure: %+v", report.Results[1])
+	}
+}
+
+func TestWriteJUnit(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteJUnit(&buf, sampleResults()); err != nil {
+		t.Fatalf("WriteJUnit failed: %v", err)
+	}
+
+	var suites junitTestSuites
+	if err := xml.Unmarshal(buf.Bytes(), &suites); err != nil {
+		t.Fatalf("Invalid XML: %v\n%s", err, buf.String())
+	}
+	if suites.Tests != 2 || suites.Failures != 1 || len(suites.Suites) != 1 {
+		t.Fatalf("Unexpected suites: %+v", suites)
+	}
+	cases := suites.Suites[0].Cases
+	if cases[0].Failure != nil || cases[0].Time != "0.120" {
+		t.Errorf("Unexpected passing case: %+v", cases[0])
+	}
+	if cases[1].Failure == nil || cases[1].Failure.Type != "timeout" {
+		t.Errorf("Expected timeout failure, got %+v", cases[1].Failure)
+	}
+}
+
+func TestWriteTAP(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteTAP(&buf, sampleResults()); err != nil {
+		t.Fatalf("WriteTAP failed: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{"TAP version 13", "1..2", "ok 1 - API / List", "not ok 2 - API / Slow", "truncated: true", "failure_kind: timeout"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected TAP output to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestWriteReportFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.xml")
+	if err := WriteReportFile(path, FormatJUnit, sampleResults()); err != nil {
+		t.Fatalf("WriteReportFile failed: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || !strings.HasPrefix(string(data), "<?xml") {
+		t.Errorf("Unexpected report file: %v %q", err, data)
+	}
+
+	if err := WriteReportFile(path, "html", nil); err == nil {
+		t.Error("Expected error for unknown format")
+	}
+}
@@ -0,0 +1,281 @@
+// Package runner executes collections non-interactively (e.g. in CI)
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/styltsou/tapi/internal/http"
+	"github.com/styltsou/tapi/internal/storage"
+)
+
+// Options controls which requests are run and how
+type Options struct {
+	Collection     string            // only run the collection with this name (empty = all)
+	Request        string            // only run requests whose name contains this (case-insensitive)
+	Variables      map[string]string // environment variables used for substitution
+	Globals        map[string]string // global variables (lowest precedence)
+	DefaultHeaders map[string]string // headers added to every request (from config)
+	Bail           bool              // stop after the first failure
+	Concurrency    int               // number of requests executed in parallel
+	Transport      storage.Transport // proxy and TLS settings from config, under the collection's
+	EnvTransport   storage.Transport // proxy and TLS settings from the environment, over the collection's
+	Secrets        map[string]string // secret values, replaced by their {{name}} in results
+}
+
+// Result is the outcome of a single request
+type Result struct {
+	Collection string
+	Request    storage.Request
+	URL        string // resolved URL after substitution
+	Response   *http.ProcessedResponse
+	Duration   time.Duration
+	Err        error
+	Assertions []http.AssertionResult
+	Extracted  map[string]string // runtime variables captured by the request's extract rules
+	ExtractErr error             // extract rules that could not be satisfied
+
+	secrets map[string]string // hidden from FailureReason
+}
+
+// Failed reports whether the request errored or did not meet expectations.
+// Requests with assertions are judged by them; others fail on a 4xx/5xx status.
+func (r Result) Failed() bool {
+	if r.Err != nil || r.Response == nil || r.ExtractErr != nil {
+		return true
+	}
+	if len(r.Assertions) > 0 {
+		return !http.AllPassed(r.Assertions)
+	}
+	return r.Response.IsError()
+}
+
+// FailureKind classifies a failure for reports ("timeout", "network", "status", ...).
+// It returns "" for passing results.
+func (r Result) FailureKind() string {
+	switch {
+	case !r.Failed():
+		return ""
+	case errors.Is(r.Err, http.ErrTimeout):
+		return "timeout"
+	case errors.Is(r.Err, http.ErrNetwork):
+		return "network"
+	case errors.Is(r.Err, http.ErrCancelled):
+		return "cancelled"
+	case errors.As(r.Err, new(*storage.UnresolvedError)):
+		return "unresolved"
+	case r.Err != nil:
+		return "error"
+	case len(r.Assertions) > 0 && !http.AllPassed(r.Assertions):
+		return "assertion"
+	case r.ExtractErr != nil:
+		return "extract"
+	default:
+		return "status"
+	}
+}
+
+// FailureReason returns a human-readable reason for a failure, or "" if the request
+// passed. Secret values in it are replaced by their {{name}}.
+func (r Result) FailureReason() string {
+	return storage.RedactSecretValues(r.failureReason(), r.secrets)
+}
+
+func (r Result) failureReason() string {
+	if !r.Failed() {
+		return ""
+	}
+	if r.Err != nil {
+		return r.Err.Error()
+	}
+	if r.Response == nil {
+		return "no response"
+	}
+	var failures []string
+	for _, a := range r.Assertions {
+		if !a.Passed {
+			failures = append(failures, a.Message)
+		}
+	}
+	if r.ExtractErr != nil {
+		failures = append(failures, strings.ReplaceAll(r.ExtractErr.Error(), "\n", "; "))
+	}
+	if len(failures) > 0 {
+		return strings.Join(failures, "; ")
+	}
+	return fmt.Sprintf("unexpected status %s", r.Response.Status)
+}
+
+// job is a request scheduled for execution, with its position in the results
+type job struct {
+	index      int
+	collection storage.Collection
+	request    storage.Request
+	inherited  storage.Inherited // headers and auth from the request's collection and folders
+}
+
+// Select returns the requests matched by the options, in collection order
+func Select(collections []storage.Collection, opts Options) []storage.Collection {
+	var selected []storage.Collection
+	for _, col := range collections {
+		if opts.Collection != "" && !strings.EqualFold(col.Name, opts.Collection) {
+			continue
+		}
+		filtered := col.FilterRequests(func(e storage.RequestEntry) bool {
+			return opts.Request == "" || strings.Contains(strings.ToLower(e.Request.Name), strings.ToLower(opts.Request))
+		})
+		if filtered.RequestCount() > 0 {
+			selected = append(selected, filtered)
+		}
+	}
+	return selected
+}
+
+// scope holds the runtime variables extracted while a run is in progress
+type scope struct {
+	mu   sync.Mutex
+	vars map[string]string
+}
+
+// snapshot returns the variables visible to a request of the given collection
+func (s *scope) snapshot(opts Options, col storage.Collection, req storage.Request) map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return storage.Scopes{
+		Global:      opts.Globals,
+		Collection:  col.Variables,
+		Environment: opts.Variables,
+		Runtime:     s.vars,
+		Request:     req.Variables,
+	}.Merged()
+}
+
+func (s *scope) set(vars map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k, v := range vars {
+		s.vars[k] = v
+	}
+}
+
+// Run executes every selected request and returns the results in collection order.
+// With Bail set, no new requests are started once one has failed, and requests
+// that never ran are left out of the results.
+//
+// Values captured by extract rules are visible to requests started afterwards,
+// so chained requests (login, then use the token) need a concurrency of 1.
+func Run(ctx context.Context, client *http.Client, collections []storage.Collection, opts Options) []Result {
+	var jobs []job
+	for _, col := range Select(collections, opts) {
+		for _, e := range col.Entries() {
+			jobs = append(jobs, job{index: len(jobs), collection: col, request: e.Request, inherited: e.Inherited})
+		}
+	}
+
+	workers := opts.Concurrency
+	if workers < 1 {
+		workers = 1
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	extracted := &scope{vars: make(map[string]string)}
+	results := make([]*Result, len(jobs))
+	queue := make(chan job)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range queue {
+				// A job may still be handed over after a bail; drop it
+				if ctx.Err() != nil {
+					continue
+				}
+				res := execute(ctx, client, j, opts, extracted.snapshot(opts, j.collection, j.request))
+				extracted.set(res.Extracted)
+				mu.Lock()
+				results[j.index] = &res
+				mu.Unlock()
+				if opts.Bail && res.Failed() {
+					cancel()
+				}
+			}
+		}()
+	}
+
+dispatch:
+	for _, j := range jobs {
+		select {
+		case <-ctx.Done():
+			break dispatch
+		case queue <- j:
+		}
+	}
+	close(queue)
+	wg.Wait()
+
+	var out []Result
+	for _, res := range results {
+		if res != nil {
+			out = append(out, *res)
+		}
+	}
+	return out
+}
+
+// execute runs one request through the same substitution and client path as the TUI
+func execute(ctx context.Context, client *http.Client, j job, opts Options, vars map[string]string) Result {
+	req, err := storage.ResolveRequest(j.inherited.Apply(j.request), vars)
+	req = req.WithDefaultHeaders(opts.DefaultHeaders)
+
+	res := Result{
+		Collection: j.collection.Name,
+		Request:    j.request,
+		secrets:    opts.Secrets,
+	}
+	if err != nil {
+		res.Err = err
+		return res
+	}
+
+	resolved, err := http.ResolveURL(j.collection.BaseURL, req.FullURL())
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	// Results end up in reports, which must not show secrets sent in the URL
+	res.URL = storage.RedactSecretValues(resolved, opts.Secrets)
+
+	client, err = client.WithTransport(storage.MergeTransports(opts.Transport, j.inherited.Transport, opts.EnvTransport))
+	if err != nil {
+		res.Err = err
+		return res
+	}
+
+	start := time.Now()
+	resp, err := client.Execute(ctx, req, j.collection.BaseURL)
+	res.Duration = time.Since(start)
+	res.Response = resp
+	res.Err = err
+	if resp != nil {
+		res.Duration = resp.Duration
+		res.Assertions = resp.Evaluate(req.Assertions)
+		for i := range res.Assertions {
+			a := &res.Assertions[i]
+			a.Assertion.Value = storage.RedactSecretValues(a.Assertion.Value, opts.Secrets)
+			a.Message = storage.RedactSecretValues(a.Message, opts.Secrets)
+		}
+		if len(req.Extract) > 0 {
+			res.Extracted, res.ExtractErr = resp.Extract(req.Extract)
+		}
+	}
+	return res
+}
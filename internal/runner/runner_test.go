@@ -0,0 +1,347 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	tapihttp "github.com/styltsou/tapi/internal/http"
+	"github.com/styltsou/tapi/internal/logger"
+	"github.com/styltsou/tapi/internal/storage"
+)
+
+func TestMain(m *testing.M) {
+	logger.InitDiscard()
+	os.Exit(m.Run())
+}
+
+func newTestServer(hits *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hits != nil {
+			atomic.AddInt32(hits, 1)
+		}
+		switch r.URL.Path {
+		case "/fail":
+			w.WriteHeader(http.StatusInternalServerError)
+		case "/login":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"token": "t-42"}`))
+		case "/echo":
+			w.Header().Set("X-Token", r.Header.Get("Authorization"))
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte("ok"))
+		}
+	}))
+}
+
+func TestRun_SubstitutesAndReportsInOrder(t *testing.T) {
+	server := newTestServer(nil)
+	defer server.Close()
+
+	cols := []storage.Collection{{
+		Name:    "API",
+		BaseURL: server.URL,
+		Requests: []storage.Request{
+			{Name: "One", Method: "GET", URL: "/one"},
+			{Name: "Echo", Method: "GET", URL: "/echo", Headers: storage.Params{{Key: "Authorization", Value: "Bearer {{token}}", Enabled: true}}},
+			{Name: "Three", Method: "GET", URL: "/three"},
+		},
+	}}
+
+	opts := Options{
+		Variables:   map[string]string{"token": "abc"},
+		Concurrency: 3,
+	}
+	results := Run(context.Background(), tapihttp.NewClient(5*time.Second), cols, opts)
+
+	if len(results) != 3 {
+		t.Fatalf("Expected 3 results, got %d", len(results))
+	}
+	for i, name := range []string{"One", "Echo", "Three"} {
+		if results[i].Request.Name != name {
+			t.Errorf("results[%d] = %q, want %q", i, results[i].Request.Name, name)
+		}
+		if results[i].Failed() {
+			t.Errorf("results[%d] failed: %v", i, results[i].Err)
+		}
+	}
+	if got := results[1].Response.GetHeader("X-Token"); got != "Bearer abc" {
+		t.Errorf("Expected substituted header, got %q", got)
+	}
+	if results[0].URL != server.URL+"/one" {
+		t.Errorf("Expected resolved URL, got %q", results[0].URL)
+	}
+}
+
+func TestRun_Bail(t *testing.T) {
+	var hits int32
+	server := newTestServer(&hits)
+	defer server.Close()
+
+	cols := []storage.Collection{{
+		Name:    "API",
+		BaseURL: server.URL,
+		Requests: []storage.Request{
+			{Name: "Fail", Method: "GET", URL: "/fail"},
+			{Name: "Never", Method: "GET", URL: "/never"},
+		},
+	}}
+
+	results := Run(context.Background(), tapihttp.NewClient(5*time.Second), cols, Options{Bail: true})
+	if len(results) != 1 || !results[0].Failed() {
+		t.Fatalf("Expected a single failed result, got %+v", results)
+	}
+	if hits != 1 {
+		t.Errorf("Expected 1 request to hit the server, got %d", hits)
+	}
+}
+
+func TestRun_Assertions(t *testing.T) {
+	server := newTestServer(nil)
+	defer server.Close()
+
+	cols := []storage.Collection{{
+		Name:    "API",
+		BaseURL: server.URL,
+		Requests: []storage.Request{
+			{
+				Name: "Expected 500", Method: "GET", URL: "/fail",
+				Assertions: []storage.Assertion{{Target: "status", Op: "equals", Value: "500"}},
+			},
+			{
+				Name: "Wrong body", Method: "GET", URL: "/ok",
+				Assertions: []storage.Assertion{
+					{Target: "status", Op: "in_range", Value: "200-299"},
+					{Target: "body", Op: "contains", Value: "{{expected}}"},
+				},
+			},
+		},
+	}}
+
+	opts := Options{Variables: map[string]string{"expected": "nope"}}
+	results := Run(context.Background(), tapihttp.NewClient(5*time.Second), cols, opts)
+
+	if results[0].Failed() {
+		t.Errorf("Expected 500 to pass its status assertion: %s", results[0].FailureReason())
+	}
+	if !results[1].Failed() || results[1].FailureKind() != "assertion" {
+		t.Fatalf("Expected assertion failure, got kind %q", results[1].FailureKind())
+	}
+	if reason := results[1].FailureReason(); !strings.Contains(reason, `"nope"`) {
+		t.Errorf("Expected substituted assertion value in reason, got %q", reason)
+	}
+}
+
+func TestRun_RedactsSecrets(t *testing.T) {
+	server := newTestServer(nil)
+	defer server.Close()
+
+	cols := []storage.Collection{{
+		Name:    "API",
+		BaseURL: server.URL,
+		Requests: []storage.Request{
+			{
+				Name: "Keyed", Method: "GET", URL: "/ok?api_key={{key}}",
+				Assertions: []storage.Assertion{{Target: "body", Op: "equals", Value: "{{key}}"}},
+			},
+			{Name: "Down", Method: "GET", URL: "http://127.0.0.1:1/?api_key={{key}}"},
+		},
+	}}
+
+	const secret = "s3cr3t-value"
+	opts := Options{
+		Variables: map[string]string{"key": secret},
+		Secrets:   map[string]string{"key": secret},
+	}
+	results := Run(context.Background(), tapihttp.NewClient(5*time.Second), cols, opts)
+	if len(results) != 2 || !results[0].Failed() || !results[1].Failed() {
+		t.Fatalf("Expected two failed results, got %+v", results)
+	}
+	if results[0].URL != server.URL+"/ok?api_key={{key}}" {
+		t.Errorf("Expected the secret replaced by its name in the URL, got %q", results[0].URL)
+	}
+
+	var summary, junit, jsonReport bytes.Buffer
+	PrintSummary(&summary, results)
+	if err := WriteJUnit(&junit, results); err != nil {
+		t.Fatalf("WriteJUnit failed: %v", err)
+	}
+	if err := WriteJSON(&jsonReport, results); err != nil {
+		t.Fatalf("WriteJSON failed: %v", err)
+	}
+	for name, out := range map[string]string{"summary": summary.String(), "JUnit": junit.String(), "JSON": jsonReport.String()} {
+		if strings.Contains(out, secret) {
+			t.Errorf("Expected the secret to be left out of the %s, got:\n%s", name, out)
+		}
+	}
+}
+
+func TestRun_ChainsExtractedVariables(t *testing.T) {
+	server := newTestServer(nil)
+	defer server.Close()
+
+	cols := []storage.Collection{{
+		Name:    "API",
+		BaseURL: server.URL,
+		Requests: []storage.Request{
+			{
+				Name: "Login", Method: "POST", URL: "/login",
+				Extract: []storage.Extraction{{Var: "token", From: "json", Property: "$.token"}},
+			},
+			{Name: "Echo", Method: "GET", URL: "/echo", Headers: storage.Params{{Key: "Authorization", Value: "Bearer {{token}}", Enabled: true}}},
+			{
+				Name: "Missing", Method: "GET", URL: "/ok",
+				Extract: []storage.Extraction{{Var: "id", From: "json", Property: "$.id"}},
+			},
+		},
+	}}
+
+	opts := Options{Variables: map[string]string{"token": "stale"}}
+	results := Run(context.Background(), tapihttp.NewClient(5*time.Second), cols, opts)
+
+	if results[0].Extracted["token"] != "t-42" {
+		t.Errorf("Expected extracted token, got %v", results[0].Extracted)
+	}
+	if got := results[1].Response.GetHeader("X-Token"); got != "Bearer t-42" {
+		t.Errorf("Expected extracted token to override env, got %q", got)
+	}
+	if !results[2].Failed() || results[2].FailureKind() != "extract" {
+		t.Errorf("Expected extract failure, got kind %q", results[2].FailureKind())
+	}
+}
+
+func TestRun_VariableScopes(t *testing.T) {
+	server := newTestServer(nil)
+	defer server.Close()
+
+	cols := []storage.Collection{{
+		Name:      "API",
+		BaseURL:   server.URL,
+		Variables: map[string]string{"scheme": "Token", "token": "from-collection"},
+		Requests: []storage.Request{
+			{Name: "Env wins", Method: "GET", URL: "/echo", Headers: storage.Params{{Key: "Authorization", Value: "{{scheme}} {{token}}", Enabled: true}}},
+			{
+				Name: "Request wins", Method: "GET", URL: "/echo",
+				Headers:   storage.Params{{Key: "Authorization", Value: "{{scheme}} {{token}}", Enabled: true}},
+				Variables: map[string]string{"token": "from-request"},
+			},
+		},
+	}}
+
+	opts := Options{
+		Globals:   map[string]string{"scheme": "Global", "token": "from-global"},
+		Variables: map[string]string{"token": "from-env"},
+	}
+	results := Run(context.Background(), tapihttp.NewClient(5*time.Second), cols, opts)
+
+	if got := results[0].Response.GetHeader("X-Token"); got != "Token from-env" {
+		t.Errorf("Expected collection and env scopes to apply, got %q", got)
+	}
+	if got := results[1].Response.GetHeader("X-Token"); got != "Token from-request" {
+		t.Errorf("Expected request scope to win, got %q", got)
+	}
+}
+
+func TestRun_FolderInheritance(t *testing.T) {
+	server := newTestServer(nil)
+	defer server.Close()
+
+	cols := []storage.Collection{{
+		Name:    "API",
+		BaseURL: server.URL,
+		Folders: []storage.Folder{{
+			Name:    "Secured",
+			Headers: storage.Params{{Key: "Authorization", Value: "Bearer {{token}}", Enabled: true}},
+			Requests: []storage.Request{
+				{Name: "Inherits", Method: "GET", URL: "/echo"},
+				{Name: "Overrides", Method: "GET", URL: "/echo", Headers: storage.Params{{Key: "authorization", Value: "Token own", Enabled: true}}},
+			},
+		}},
+		Requests: []storage.Request{{Name: "Public", Method: "GET", URL: "/echo"}},
+	}}
+
+	opts := Options{Variables: map[string]string{"token": "abc"}}
+	results := Run(context.Background(), tapihttp.NewClient(5*time.Second), cols, opts)
+
+	if len(results) != 3 {
+		t.Fatalf("Expected 3 results, got %d", len(results))
+	}
+	for i, want := range []string{"Bearer abc", "Token own", ""} {
+		if got := results[i].Response.GetHeader("X-Token"); got != want {
+			t.Errorf("%s: expected Authorization %q, got %q", results[i].Request.Name, want, got)
+		}
+	}
+}
+
+func TestRun_UnresolvedVariables(t *testing.T) {
+	var hits int32
+	server := newTestServer(&hits)
+	defer server.Close()
+
+	cols := []storage.Collection{{
+		Name:    "API",
+		BaseURL: server.URL,
+		Requests: []storage.Request{
+			{Name: "Broken", Method: "GET", URL: "/users/{{userId}}", Headers: storage.Params{{Key: "X-Key", Value: "{{apiKey}}", Enabled: true}}},
+		},
+	}}
+
+	results := Run(context.Background(), tapihttp.NewClient(5*time.Second), cols, Options{})
+
+	if atomic.LoadInt32(&hits) != 0 {
+		t.Error("Expected request with unresolved variables not to be sent")
+	}
+	if kind := results[0].FailureKind(); kind != "unresolved" {
+		t.Errorf("Expected unresolved failure, got %q", kind)
+	}
+	if reason := results[0].FailureReason(); !strings.Contains(reason, "userId, apiKey") {
+		t.Errorf("Expected reason to name each missing variable, got %q", reason)
+	}
+}
+
+func TestSelect(t *testing.T) {
+	cols := []storage.Collection{
+		{Name: "Users", Requests: []storage.Request{{Name: "Login"}, {Name: "List Users"}}},
+		{Name: "Admin", Requests: []storage.Request{{Name: "Login"}}},
+		{Name: "Nested", Folders: []storage.Folder{{Name: "Auth", Requests: []storage.Request{{Name: "Login"}}}, {Name: "Other", Requests: []storage.Request{{Name: "Ping"}}}}},
+	}
+
+	got := Select(cols, Options{Collection: "users", Request: "login"})
+	if len(got) != 1 || got[0].Name != "Users" || len(got[0].Requests) != 1 {
+		t.Errorf("Unexpected selection: %+v", got)
+	}
+
+	got = Select(cols, Options{Collection: "nested", Request: "login"})
+	if len(got) != 1 || len(got[0].Folders) != 1 || got[0].Folders[0].Name != "Auth" {
+		t.Errorf("Expected folders without matches to be dropped, got %+v", got)
+	}
+
+	if got := Select(cols, Options{Request: "nothing"}); len(got) != 0 {
+		t.Errorf("Expected empty selection, got %+v", got)
+	}
+}
+
+func TestPrintSummary(t *testing.T) {
+	results := []Result{
+		{Request: storage.Request{Name: "Ok", Method: "GET"}, URL: "http://x/ok", Response: &tapihttp.ProcessedResponse{StatusCode: 200, Size: 10}},
+		{Request: storage.Request{Name: "Down", Method: "POST"}, URL: "http://x/down", Err: tapihttp.ErrNetwork},
+	}
+
+	var buf bytes.Buffer
+	PrintSummary(&buf, results)
+	out := buf.String()
+
+	for _, want := range []string{"http://x/ok", "200", "network error", "2 requests, 1 passed, 1 failed"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected summary to contain %q, got:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package runner
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+)
+
+// PrintSummary writes a per-request table followed by the totals
+func PrintSummary(w io.Writer, results []Result) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, r := range results {
+		mark := "✓"
+		if r.Failed() {
+			mark = "✗"
+		}
+
+		status, size := "-", "-"
+		if r.Response != nil {
+			status = fmt.Sprintf("%d", r.Response.StatusCode)
+			size = r.Response.FormatSize()
+		}
+
+		name := r.Request.Name
+		if r.Failed() {
+			name += fmt.Sprintf(" (%s)", r.FailureReason())
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			mark, r.Request.Method, r.URL, status, r.Duration.Round(time.Millisecond), size, name)
+	}
+	tw.Flush()
+
+	passed, failed := Count(results)
+	fmt.Fprintf(w, "\n%d requests, %d passed, %d failed\n", len(results), passed, failed)
+}
+
+// Count returns the number of passed and failed results
+func Count(results []Result) (passed, failed int) {
+	for _, r := range results {
+		if r.Failed() {
+			failed++
+		} else {
+			passed++
+		}
+	}
+	return passed, failed
+}
@@ -0,0 +1,162 @@
+package storage
+
+// Auth types
+const (
+	AuthBasic  = "basic"
+	AuthBearer = "bearer"
+	AuthAPIKey = "apikey" // a header or query param carrying a key
+	AuthDigest = "digest" // answered after the server's 401 challenge
+	AuthOAuth2 = "oauth2" // bearer token obtained from a token endpoint
+	AuthAWS    = "aws"    // AWS Signature Version 4
+	AuthHMAC   = "hmac"   // HMAC over method, path, timestamp and body
+)
+
+// AuthTypes lists the auth types in the order the request builder cycles them
+var AuthTypes = []string{AuthBasic, AuthBearer, AuthAPIKey, AuthDigest, AuthOAuth2, AuthAWS, AuthHMAC}
+
+// OAuth2 grants
+const (
+	GrantClientCredentials = "client_credentials"
+	GrantPassword          = "password"
+	GrantRefreshToken      = "refresh_token"
+	GrantAuthorizationCode = "authorization_code" // with PKCE, through a loopback redirect
+)
+
+// OAuth2Grants lists the grants in the order the request builder cycles them
+var OAuth2Grants = []string{GrantClientCredentials, GrantPassword, GrantRefreshToken, GrantAuthorizationCode}
+
+// HMAC hash algorithms
+const (
+	HMACSHA256 = "sha256"
+	HMACSHA512 = "sha512"
+	HMACSHA1   = "sha1"
+)
+
+// HMACAlgorithms lists the algorithms in the order the request builder cycles them
+var HMACAlgorithms = []string{HMACSHA256, HMACSHA512, HMACSHA1}
+
+// API key locations
+const (
+	InHeader = "header"
+	InQuery  = "query"
+)
+
+// Auth holds the credentials of a request, folder or collection. Type selects
+// the fields that are used; files written before types existed are basic auth.
+type Auth struct {
+	Type     string `yaml:"type,omitempty"`
+	Username string `yaml:"username,omitempty"` // basic, digest, oauth2 password grant
+	Password string `yaml:"password,omitempty"` // basic, digest, oauth2 password grant
+	Token    string `yaml:"token,omitempty"`    // bearer
+	Key      string `yaml:"key,omitempty"`      // apikey: header or query param name
+	Value    string `yaml:"value,omitempty"`    // apikey
+	In       string `yaml:"in,omitempty"`       // apikey: header (default) or query
+
+	// OAuth2
+	Grant        string `yaml:"grant,omitempty"` // client_credentials (default), password, refresh_token, authorization_code
+	TokenURL     string `yaml:"token_url,omitempty"`
+	AuthURL      string `yaml:"auth_url,omitempty"` // authorization_code
+	ClientID     string `yaml:"client_id,omitempty"`
+	ClientSecret string `yaml:"client_secret,omitempty"`
+	Scope        string `yaml:"scope,omitempty"`         // space separated
+	RefreshToken string `yaml:"refresh_token,omitempty"` // refresh_token grant
+	RedirectPort int    `yaml:"redirect_port,omitempty"` // authorization_code loopback listener, any free port when 0
+
+	// Request signing
+	AccessKey       string `yaml:"access_key,omitempty"`       // aws
+	SecretKey       string `yaml:"secret_key,omitempty"`       // aws, hmac
+	SessionToken    string `yaml:"session_token,omitempty"`    // aws, temporary credentials
+	Region          string `yaml:"region,omitempty"`           // aws
+	Service         string `yaml:"service,omitempty"`          // aws, e.g. execute-api or s3
+	Algorithm       string `yaml:"algorithm,omitempty"`        // hmac: sha256 (default), sha512, sha1
+	Header          string `yaml:"header,omitempty"`           // hmac: signature header, X-Signature by default
+	TimestampHeader string `yaml:"timestamp_header,omitempty"` // hmac: X-Timestamp by default
+	Encoding        string `yaml:"encoding,omitempty"`         // hmac: hex (default) or base64
+}
+
+// EffectiveType returns Type, or basic when it is not set
+func (a Auth) EffectiveType() string {
+	if a.Type == "" {
+		return AuthBasic
+	}
+	return a.Type
+}
+
+// EffectiveIn returns where an API key is sent, the header by default
+func (a Auth) EffectiveIn() string {
+	if a.In == InQuery {
+		return InQuery
+	}
+	return InHeader
+}
+
+// EffectiveGrant returns Grant, or client_credentials when it is not set
+func (a Auth) EffectiveGrant() string {
+	if a.Grant == "" {
+		return GrantClientCredentials
+	}
+	return a.Grant
+}
+
+// EffectiveAlgorithm returns the HMAC algorithm, sha256 when it is not set
+func (a Auth) EffectiveAlgorithm() string {
+	if a.Algorithm == "" {
+		return HMACSHA256
+	}
+	return a.Algorithm
+}
+
+// EffectiveHeader returns the header carrying the HMAC signature
+func (a Auth) EffectiveHeader() string {
+	if a.Header == "" {
+		return "X-Signature"
+	}
+	return a.Header
+}
+
+// EffectiveTimestampHeader returns the header carrying the HMAC timestamp
+func (a Auth) EffectiveTimestampHeader() string {
+	if a.TimestampHeader == "" {
+		return "X-Timestamp"
+	}
+	return a.TimestampHeader
+}
+
+// fields returns the values that may contain {{variables}}
+func (a *Auth) fields() []*string {
+	return []*string{
+		&a.Username, &a.Password, &a.Token, &a.Key, &a.Value,
+		&a.TokenURL, &a.AuthURL, &a.ClientID, &a.ClientSecret, &a.Scope, &a.RefreshToken,
+		&a.AccessKey, &a.SecretKey, &a.SessionToken, &a.Region, &a.Service,
+		&a.Header, &a.TimestampHeader,
+	}
+}
+
+// Values returns the values that may contain {{variables}}, e.g. to list the
+// variables an auth uses
+func (a Auth) Values() []string {
+	var values []string
+	for _, f := range a.fields() {
+		values = append(values, *f)
+	}
+	return values
+}
+
+// Auth modes: where a request takes its auth from
+const (
+	AuthInherit  = "inherit"  // the nearest folder or the collection
+	AuthNone     = "none"     // no auth, even if a parent sets one
+	AuthOverride = "override" // the request's own auth
+)
+
+// EffectiveAuthMode returns AuthMode, or the mode implied when it is not set:
+// override when the request has auth of its own, inherit otherwise
+func (r Request) EffectiveAuthMode() string {
+	if r.AuthMode != "" {
+		return r.AuthMode
+	}
+	if r.Auth != nil {
+		return AuthOverride
+	}
+	return AuthInherit
+}
@@ -18,14 +18,14 @@ func CreateDemoCollection() error {
 				Method: "POST",
 				URL:    "/post",
 				Body:   `{"message": "Hello TAPI!"}`,
-				Headers: map[string]string{
-					"Content-Type": "application/json",
+				Headers: Params{
+					{Key: "Content-Type", Value: "application/json", Enabled: true},
 				},
 			},
 		},
 	}
 
-	if err := SaveCollection(demo); err != nil {
+	if err := SaveCollection(&demo); err != nil {
 		logger.Logger.Error("Failed to create demo collection", "error", err)
 		return err
 	}
@@ -36,6 +36,13 @@ func LoadEnvironments() ([]Environment, error) {
 			logger.Logger.Error("Failed to parse environment file", "file", file, "error", err)
 			continue
 		}
+		if env.Variables == nil {
+			env.Variables = map[string]string{}
+		}
+		if err := openEnvironment(&env); err != nil {
+			logger.Logger.Warn("Environment secrets are locked", "name", env.Name, "error", err)
+		}
+		registerSecrets(env)
 
 		envs = append(envs, env)
 		logger.Logger.Info("Loaded environment", "name", env.Name, "file", file)
@@ -54,12 +61,19 @@ func SaveEnvironment(env Environment) error {
 	// Sanitize name for filename
 	filename := filepath.Join(envsPath, slug.Make(env.Name)+".yaml")
 
-	data, err := yaml.Marshal(env)
+	registerSecrets(env)
+	sealed, err := sealEnvironment(env)
+	if err != nil {
+		return err
+	}
+
+	data, err := yaml.Marshal(sealed)
 	if err != nil {
 		return err
 	}
 
-	if err := os.WriteFile(filename, data, 0o644); err != nil {
+	// Environments hold credentials: keep them private to the user
+	if err := writePrivateFile(filename, data); err != nil {
 		return err
 	}
 
@@ -83,3 +97,74 @@ func DeleteEnvironment(name string) error {
 	logger.Logger.Info("Deleted environment", "name", name, "file", filename)
 	return nil
 }
+
+// registerSecrets keeps the values of secret variables out of the log
+func registerSecrets(env Environment) {
+	for _, v := range env.SecretValues() {
+		logger.RegisterSecrets(v)
+	}
+}
+
+// globalsFile is the on-disk layout of ~/.tapi/globals.yaml
+type globalsFile struct {
+	Variables map[string]string `yaml:"variables"`
+}
+
+// LoadGlobals loads the global variables shared by every collection and environment.
+// A missing file is not an error.
+func LoadGlobals() (map[string]string, error) {
+	dir, err := GetStoragePath("")
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "globals.yaml"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return map[string]string{}, nil
+		}
+		return nil, err
+	}
+
+	var globals globalsFile
+	if err := yaml.Unmarshal(data, &globals); err != nil {
+		return nil, err
+	}
+	if globals.Variables == nil {
+		globals.Variables = map[string]string{}
+	}
+	return globals.Variables, nil
+}
+
+// SaveGlobals writes the global variables to ~/.tapi/globals.yaml
+func SaveGlobals(vars map[string]string) error {
+	dir, err := GetStoragePath("")
+	if err != nil {
+		return err
+	}
+	if err := EnsureDir(dir); err != nil {
+		return err
+	}
+
+	data, err := yaml.Marshal(globalsFile{Variables: vars})
+	if err != nil {
+		return err
+	}
+
+	filename := filepath.Join(dir, "globals.yaml")
+	if err := writePrivateFile(filename, data); err != nil {
+		return err
+	}
+
+	logger.Logger.Info("Saved global variables", "file", filename)
+	return nil
+}
+
+// writePrivateFile writes data with mode 0600, also tightening the mode of an
+// existing file (os.WriteFile keeps the permissions of existing files)
+func writePrivateFile(filename string, data []byte) error {
+	if err := os.WriteFile(filename, data, 0o600); err != nil {
+		return err
+	}
+	return os.Chmod(filename, 0o600)
+}
@@ -84,3 +84,20 @@ func TestSaveEnvironment(t *testing.T) {
 		t.Errorf("Expected sanitized file %s to exist", expectedFile)
 	}
 }
+
+func TestGlobals_RoundTrip(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	vars, err := LoadGlobals()
+	if err != nil || len(vars) != 0 {
+		t.Fatalf("Expected no globals without a file, got %v, %v", vars, err)
+	}
+
+	if err := SaveGlobals(map[string]string{"api_key": "k"}); err != nil {
+		t.Fatalf("SaveGlobals failed: %v", err)
+	}
+	vars, err = LoadGlobals()
+	if err != nil || vars["api_key"] != "k" {
+		t.Errorf("Expected saved globals, got %v, %v", vars, err)
+	}
+}
@@ -3,7 +3,6 @@ package exporter
 import (
 	"fmt"
 	"net/url"
-	"sort"
 	"strings"
 
 	"github.com/styltsou/tapi/internal/storage"
@@ -20,21 +19,29 @@ func ExportCurl(req storage.Request, baseURL string) string {
 		parts = append(parts, "-X", req.Method)
 	}
 
-	// Resolve URL
-	resolvedURL := resolveURL(req.URL, baseURL)
+	// Resolve URL; an API key sent in the query goes with the other params
+	fullURL := req.FullURL()
+	if req.Auth != nil && req.Auth.EffectiveType() == storage.AuthAPIKey && req.Auth.EffectiveIn() == storage.InQuery && req.Auth.Key != "" {
+		sep := "?"
+		if strings.Contains(fullURL, "?") {
+			sep = "&"
+		}
+		fullURL += sep + url.QueryEscape(req.Auth.Key) + "=" + url.QueryEscape(req.Auth.Value)
+	}
+	resolvedURL := resolveURL(fullURL, baseURL)
+	if socket, httpURL, ok := storage.SplitUnixURL(resolvedURL); ok {
+		parts = append(parts, "--unix-socket", shellQuote(socket))
+		resolvedURL = httpURL
+	}
 	parts = append(parts, shellQuote(resolvedURL))
 
-	// Headers (sorted for deterministic output)
-	if len(req.Headers) > 0 {
-		keys := make([]string, 0, len(req.Headers))
-		for k := range req.Headers {
-			keys = append(keys, k)
-		}
-		sort.Strings(keys)
+	// Headers, in request order
+	for _, h := range req.Headers.Enabled() {
+		parts = append(parts, "-H", shellQuote(fmt.Sprintf("%s: %s", h.Key, h.Value)))
+	}
 
-		for _, k := range keys {
-			parts = append(parts, "-H", shellQuote(fmt.Sprintf("%s: %s", k, req.Headers[k])))
-		}
+	if req.Auth != nil {
+		parts = append(parts, authFlags(*req.Auth)...)
 	}
 
 	// Body
@@ -42,17 +49,70 @@ func ExportCurl(req storage.Request, baseURL string) string {
 		parts = append(parts, "-d", shellQuote(req.Body))
 	}
 
+	// Redirects: tapi follows them unless told not to, curl only with -L
+	if !req.NoFollow {
+		parts = append(parts, "-L")
+		if req.MaxRedirects > 0 {
+			parts = append(parts, "--max-redirs", fmt.Sprint(req.MaxRedirects))
+		}
+		if req.KeepMethod {
+			parts = append(parts, "--post301", "--post302", "--post303")
+		}
+	}
+
 	return strings.Join(parts, " ")
 }
 
+// authFlags returns the curl options sending the request's auth
+func authFlags(auth storage.Auth) []string {
+	switch auth.EffectiveType() {
+	case storage.AuthBasic:
+		if auth.Username != "" {
+			return []string{"-u", shellQuote(auth.Username + ":" + auth.Password)}
+		}
+	case storage.AuthDigest:
+		if auth.Username != "" {
+			return []string{"--digest", "-u", shellQuote(auth.Username + ":" + auth.Password)}
+		}
+	case storage.AuthBearer:
+		if auth.Token != "" {
+			return []string{"-H", shellQuote("Authorization: Bearer " + auth.Token)}
+		}
+	case storage.AuthAPIKey:
+		if auth.Key != "" && auth.EffectiveIn() == storage.InHeader {
+			return []string{"-H", shellQuote(auth.Key + ": " + auth.Value)}
+		}
+	case storage.AuthAWS:
+		if auth.AccessKey != "" {
+			flags := []string{
+				"--aws-sigv4", shellQuote("aws:amz:" + auth.Region + ":" + auth.Service),
+				"-u", shellQuote(auth.AccessKey + ":" + auth.SecretKey),
+			}
+			if auth.SessionToken != "" {
+				flags = append(flags, "-H", shellQuote("X-Amz-Security-Token: "+auth.SessionToken))
+			}
+			return flags
+		}
+	}
+	// HMAC signatures depend on the time of sending and can't be exported
+	return nil
+}
+
 // resolveURL resolves a potentially relative URL against a base URL.
 func resolveURL(rawURL, baseURL string) string {
 	if baseURL == "" {
 		return rawURL
 	}
-	if strings.HasPrefix(rawURL, "http://") || strings.HasPrefix(rawURL, "https://") {
+	if strings.HasPrefix(rawURL, "http://") || strings.HasPrefix(rawURL, "https://") || strings.HasPrefix(rawURL, storage.UnixScheme) {
 		return rawURL
 	}
+	if socket, httpBase, ok := storage.SplitUnixURL(baseURL); ok {
+		unixURL, err := storage.JoinUnixURL(socket, resolveURL(rawURL, httpBase))
+		if err != nil {
+			return rawURL
+		}
+		return unixURL
+	}
 
 	base, err := url.Parse(baseURL)
 	if err != nil {
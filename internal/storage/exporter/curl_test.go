@@ -28,9 +28,9 @@ func TestExportCurl(t *testing.T) {
 				Method: "POST",
 				URL:    "/users",
 				Body:   `{"name":"Alice"}`,
-				Headers: map[string]string{
-					"Content-Type": "application/json",
-					"Authorization": "Bearer token",
+				Headers: storage.Params{
+					{Key: "Content-Type", Value: "application/json", Enabled: true},
+					{Key: "Authorization", Value: "Bearer token", Enabled: true},
 				},
 			},
 			baseURL: "https://api.example.com",
@@ -43,6 +43,73 @@ func TestExportCurl(t *testing.T) {
 				"-d '{\"name\":\"Alice\"}'",
 			},
 		},
+		{
+			name: "Basic auth",
+			req: storage.Request{
+				Method: "GET",
+				URL:    "https://example.com/me",
+				Auth:   &storage.Auth{Username: "admin", Password: "s3cret"},
+			},
+			expected: []string{"-u 'admin:s3cret'"},
+		},
+		{
+			name: "Digest auth",
+			req: storage.Request{
+				Method: "GET",
+				URL:    "https://example.com/me",
+				Auth:   &storage.Auth{Type: storage.AuthDigest, Username: "admin", Password: "s3cret"},
+			},
+			expected: []string{"--digest -u 'admin:s3cret'"},
+		},
+		{
+			name: "Bearer auth",
+			req: storage.Request{
+				Method: "GET",
+				URL:    "https://example.com/me",
+				Auth:   &storage.Auth{Type: storage.AuthBearer, Token: "abc"},
+			},
+			expected: []string{"-H 'Authorization: Bearer abc'"},
+		},
+		{
+			name: "API key in query",
+			req: storage.Request{
+				Method: "GET",
+				URL:    "https://example.com/me",
+				Query:  storage.Params{{Key: "page", Value: "2", Enabled: true}},
+				Auth:   &storage.Auth{Type: storage.AuthAPIKey, Key: "api_key", Value: "k1", In: storage.InQuery},
+			},
+			expected: []string{"'https://example.com/me?page=2&api_key=k1'"},
+		},
+		{
+			name: "AWS signature",
+			req: storage.Request{
+				Method: "GET",
+				URL:    "https://api.example.com/items",
+				Auth:   &storage.Auth{Type: storage.AuthAWS, AccessKey: "AKID", SecretKey: "secret", SessionToken: "tok", Region: "eu-west-1", Service: "execute-api"},
+			},
+			expected: []string{"--aws-sigv4 'aws:amz:eu-west-1:execute-api' -u 'AKID:secret'", "-H 'X-Amz-Security-Token: tok'"},
+		},
+		{
+			name:     "Unix socket base URL",
+			req:      storage.Request{Method: "GET", URL: "/containers/json"},
+			baseURL:  "unix:///var/run/docker.sock",
+			expected: []string{"curl --unix-socket '/var/run/docker.sock' 'http://localhost/containers/json'"},
+		},
+		{
+			name:     "Unix socket request URL",
+			req:      storage.Request{Method: "GET", URL: "unix:///tmp/app.sock:/health?deep=1"},
+			expected: []string{"--unix-socket '/tmp/app.sock' 'http://localhost/health?deep=1'"},
+		},
+		{
+			name:     "Follows redirects",
+			req:      storage.Request{Method: "GET", URL: "https://example.com/old"},
+			expected: []string{"'https://example.com/old' -L"},
+		},
+		{
+			name:     "Redirect policy",
+			req:      storage.Request{Method: "POST", URL: "https://example.com/old", MaxRedirects: 3, KeepMethod: true},
+			expected: []string{"-L --max-redirs 3 --post301 --post302 --post303"},
+		},
 		{
 			name: "Single quotes escaping",
 			req: storage.Request{
@@ -0,0 +1,271 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Folder groups requests inside a collection. Folders can be nested; the
+// headers and auth of a folder are inherited by every request below it.
+type Folder struct {
+	ID       string    `yaml:"id"`
+	Name     string    `yaml:"name"`
+	Headers  Params    `yaml:"headers,omitempty"`
+	Auth     *Auth     `yaml:"auth,omitempty"`
+	Requests []Request `yaml:"requests,omitempty"`
+	Folders  []Folder  `yaml:"folders,omitempty"`
+}
+
+// Inherited holds the headers and auth a request picks up from its collection
+// and folders
+type Inherited struct {
+	Headers   Params
+	Auth      *Auth
+	From      map[string]string // lowercased header name -> collection or folder it comes from
+	AuthFrom  string
+	Transport Transport // the collection's connection settings
+}
+
+// Inherited returns what the requests at the root of the collection inherit
+func (c Collection) Inherited() Inherited {
+	return Inherited{Transport: c.Transport}.layer(c.Name, c.Headers, c.Auth)
+}
+
+// Inherit layers a folder on top of what was inherited so far: its headers
+// replace outer headers with the same name, and its auth replaces outer auth
+func (i Inherited) Inherit(f Folder) Inherited {
+	return i.layer(f.Name, f.Headers, f.Auth)
+}
+
+func (i Inherited) layer(source string, headers Params, auth *Auth) Inherited {
+	var merged Params
+	for _, h := range i.Headers {
+		if !headers.Contains(h.Key) {
+			merged = append(merged, h)
+		}
+	}
+	from := make(map[string]string, len(i.From)+len(headers))
+	for k, v := range i.From {
+		from[k] = v
+	}
+	for _, h := range headers {
+		from[strings.ToLower(h.Key)] = source
+	}
+	i.Headers = append(merged, headers...)
+	i.From = from
+	if auth != nil {
+		i.Auth = auth
+		i.AuthFrom = source
+	}
+	return i
+}
+
+// Source returns the collection or folder an inherited header comes from
+func (i Inherited) Source(header string) string {
+	return i.From[strings.ToLower(header)]
+}
+
+// Apply returns a copy of req with the inherited headers added before its own
+// (unless the request lists the same header, even disabled) and its auth
+// chosen by the request's auth mode
+func (i Inherited) Apply(req Request) Request {
+	if len(i.Headers) > 0 {
+		var headers Params
+		for _, h := range i.Headers {
+			if !req.Headers.Contains(h.Key) {
+				headers = append(headers, h)
+			}
+		}
+		req.Headers = append(headers, req.Headers...)
+	}
+	switch req.EffectiveAuthMode() {
+	case AuthInherit:
+		req.Auth = nil
+		if i.Auth != nil {
+			auth := *i.Auth
+			req.Auth = &auth
+		}
+	case AuthNone:
+		req.Auth = nil
+	}
+	// The result carries its final auth
+	req.AuthMode = AuthOverride
+	return req
+}
+
+// RequestEntry is a request together with its place in the folder tree
+type RequestEntry struct {
+	Request   Request
+	FolderID  string   // innermost folder, empty at the collection root
+	Path      []string // folder names, outermost first
+	Inherited Inherited
+}
+
+// Entries returns every request of the collection, depth first: each folder in
+// order, then the requests next to them (the order of the sidebar)
+func (c Collection) Entries() []RequestEntry {
+	var entries []RequestEntry
+	walkRequests(c.Requests, c.Folders, "", nil, c.Inherited(), &entries)
+	return entries
+}
+
+func walkRequests(requests []Request, folders []Folder, folderID string, path []string, inh Inherited, out *[]RequestEntry) {
+	for _, f := range folders {
+		sub := append(append([]string(nil), path...), f.Name)
+		walkRequests(f.Requests, f.Folders, f.ID, sub, inh.Inherit(f), out)
+	}
+	for _, r := range requests {
+		*out = append(*out, RequestEntry{Request: r, FolderID: folderID, Path: path, Inherited: inh})
+	}
+}
+
+// Entry returns the request with the given ID and its place in the tree
+func (c Collection) Entry(id string) (RequestEntry, bool) {
+	for _, e := range c.Entries() {
+		if e.Request.ID == id {
+			return e, true
+		}
+	}
+	return RequestEntry{}, false
+}
+
+// RequestCount returns the number of requests, including those in folders
+func (c Collection) RequestCount() int {
+	return len(c.Entries())
+}
+
+// FindRequest returns a pointer to the request with the given ID, or nil
+func (c *Collection) FindRequest(id string) *Request {
+	return findRequest(c.Requests, c.Folders, id)
+}
+
+func findRequest(requests []Request, folders []Folder, id string) *Request {
+	for i := range requests {
+		if requests[i].ID == id {
+			return &requests[i]
+		}
+	}
+	for i := range folders {
+		if r := findRequest(folders[i].Requests, folders[i].Folders, id); r != nil {
+			return r
+		}
+	}
+	return nil
+}
+
+// FindFolder returns a pointer to the folder with the given ID, or nil
+func (c *Collection) FindFolder(id string) *Folder {
+	return findFolder(c.Folders, id)
+}
+
+func findFolder(folders []Folder, id string) *Folder {
+	for i := range folders {
+		if folders[i].ID == id {
+			return &folders[i]
+		}
+		if f := findFolder(folders[i].Folders, id); f != nil {
+			return f
+		}
+	}
+	return nil
+}
+
+// RemoveRequest removes the request with the given ID wherever it is
+func (c *Collection) RemoveRequest(id string) (Request, bool) {
+	return removeRequest(&c.Requests, c.Folders, id)
+}
+
+func removeRequest(requests *[]Request, folders []Folder, id string) (Request, bool) {
+	for i, r := range *requests {
+		if r.ID == id {
+			*requests = append((*requests)[:i:i], (*requests)[i+1:]...)
+			return r, true
+		}
+	}
+	for i := range folders {
+		if r, ok := removeRequest(&folders[i].Requests, folders[i].Folders, id); ok {
+			return r, true
+		}
+	}
+	return Request{}, false
+}
+
+// AddRequest appends a request to the folder with the given ID, or to the
+// collection root when folderID is empty
+func (c *Collection) AddRequest(folderID string, req Request) error {
+	if folderID == "" {
+		c.Requests = append(c.Requests, req)
+		return nil
+	}
+	f := c.FindFolder(folderID)
+	if f == nil {
+		return fmt.Errorf("folder not found")
+	}
+	f.Requests = append(f.Requests, req)
+	return nil
+}
+
+// MoveRequest moves a request into the folder with the given ID (empty for the root)
+func (c *Collection) MoveRequest(requestID, folderID string) error {
+	if folderID != "" && c.FindFolder(folderID) == nil {
+		return fmt.Errorf("folder not found")
+	}
+	req, ok := c.RemoveRequest(requestID)
+	if !ok {
+		return fmt.Errorf("request not found")
+	}
+	return c.AddRequest(folderID, req)
+}
+
+// EnsureFolder returns the ID of the folder at the given "/" separated path,
+// creating the missing folders. An empty path is the collection root.
+func (c *Collection) EnsureFolder(path string) string {
+	folders := &c.Folders
+	id := ""
+	for _, name := range strings.Split(path, "/") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		idx := -1
+		for i := range *folders {
+			if strings.EqualFold((*folders)[i].Name, name) {
+				idx = i
+				break
+			}
+		}
+		if idx == -1 {
+			*folders = append(*folders, Folder{ID: NewID(), Name: name})
+			idx = len(*folders) - 1
+		}
+		id = (*folders)[idx].ID
+		folders = &(*folders)[idx].Folders
+	}
+	return id
+}
+
+// FilterRequests returns a copy of the collection keeping only the requests
+// accepted by keep. Folders left without requests are dropped.
+func (c Collection) FilterRequests(keep func(RequestEntry) bool) Collection {
+	filtered := c
+	filtered.Requests, filtered.Folders = filterTree(c.Requests, c.Folders, "", nil, c.Inherited(), keep)
+	return filtered
+}
+
+func filterTree(requests []Request, folders []Folder, folderID string, path []string, inh Inherited, keep func(RequestEntry) bool) ([]Request, []Folder) {
+	var keptRequests []Request
+	for _, r := range requests {
+		if keep(RequestEntry{Request: r, FolderID: folderID, Path: path, Inherited: inh}) {
+			keptRequests = append(keptRequests, r)
+		}
+	}
+	var keptFolders []Folder
+	for _, f := range folders {
+		sub := append(append([]string(nil), path...), f.Name)
+		f.Requests, f.Folders = filterTree(f.Requests, f.Folders, f.ID, sub, inh.Inherit(f), keep)
+		if len(f.Requests) > 0 || len(f.Folders) > 0 {
+			keptFolders = append(keptFolders, f)
+		}
+	}
+	return keptRequests, keptFolders
+}
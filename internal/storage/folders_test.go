@@ -0,0 +1,161 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func testTree() Collection {
+	return Collection{
+		Name:     "Tree",
+		Requests: []Request{{ID: "health", Name: "Health"}},
+		Folders: []Folder{
+			{
+				ID:      "auth",
+				Name:    "Auth",
+				Headers: Params{{Key: "X-Team", Value: "auth", Enabled: true}, {Key: "X-Env", Value: "dev", Enabled: true}},
+				Auth:    &Auth{Username: "outer"},
+				Requests: []Request{
+					{ID: "login", Name: "Login"},
+				},
+				Folders: []Folder{
+					{
+						ID:       "tokens",
+						Name:     "Tokens",
+						Headers:  Params{{Key: "x-env", Value: "prod", Enabled: true}},
+						Requests: []Request{{ID: "refresh", Name: "Refresh", Headers: Params{{Key: "X-Team", Value: "mine", Enabled: true}}}},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestCollection_Entries(t *testing.T) {
+	entries := testTree().Entries()
+
+	var names []string
+	for _, e := range entries {
+		names = append(names, strings.Join(append(e.Path, e.Request.Name), "/"))
+	}
+	if got := strings.Join(names, ","); got != "Auth/Tokens/Refresh,Auth/Login,Health" {
+		t.Errorf("Unexpected order: %s", got)
+	}
+
+	// Inner folders override outer headers; the request's own headers win
+	refresh := entries[0].Inherited.Apply(entries[0].Request)
+	if refresh.Headers.Get("X-Env") != "prod" || refresh.Headers.Get("X-Team") != "mine" || len(refresh.Headers) != 2 {
+		t.Errorf("Unexpected inherited headers: %+v", refresh.Headers)
+	}
+	if refresh.Auth == nil || refresh.Auth.Username != "outer" {
+		t.Errorf("Expected auth from the outer folder, got %+v", refresh.Auth)
+	}
+
+	// Root requests inherit nothing
+	health := entries[2].Inherited.Apply(entries[2].Request)
+	if len(health.Headers) != 0 || health.Auth != nil {
+		t.Errorf("Root request should inherit nothing, got %+v", health)
+	}
+}
+
+func TestInherited_AuthModes(t *testing.T) {
+	col := Collection{
+		Name:    "API",
+		Headers: Params{{Key: "Accept", Value: "application/json", Enabled: true}},
+		Auth:    &Auth{Username: "collection"},
+		Folders: []Folder{{Name: "Admin", Auth: &Auth{Username: "admin"}}},
+	}
+	root := col.Inherited()
+	admin := root.Inherit(col.Folders[0])
+	own := &Auth{Username: "own"}
+
+	tests := []struct {
+		name      string
+		inherited Inherited
+		req       Request
+		wantUser  string // empty for no auth
+	}{
+		{"implied inherit", root, Request{}, "collection"},
+		{"nearest folder wins", admin, Request{}, "admin"},
+		{"implied override", admin, Request{Auth: own}, "own"},
+		{"explicit inherit ignores own auth", admin, Request{Auth: own, AuthMode: AuthInherit}, "admin"},
+		{"none", admin, Request{Auth: own, AuthMode: AuthNone}, ""},
+		{"override without auth", admin, Request{AuthMode: AuthOverride}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.inherited.Apply(tt.req)
+			user := ""
+			if got.Auth != nil {
+				user = got.Auth.Username
+			}
+			if user != tt.wantUser {
+				t.Errorf("Expected auth %q, got %q", tt.wantUser, user)
+			}
+			if got.Headers.Get("Accept") != "application/json" {
+				t.Errorf("Expected the collection header, got %+v", got.Headers)
+			}
+		})
+	}
+
+	if src := admin.Source("accept"); src != "API" {
+		t.Errorf("Expected Accept to come from the collection, got %q", src)
+	}
+	if admin.AuthFrom != "Admin" {
+		t.Errorf("Expected auth to come from Admin, got %q", admin.AuthFrom)
+	}
+}
+
+func TestCollection_MoveRequest(t *testing.T) {
+	col := testTree()
+
+	target := col.EnsureFolder("Auth/Tokens/Admin")
+	if target == "" || col.FindFolder("tokens").Folders[0].Name != "Admin" {
+		t.Fatalf("Expected Auth/Tokens/Admin to be created under the existing folders")
+	}
+	if err := col.MoveRequest("health", target); err != nil {
+		t.Fatalf("MoveRequest failed: %v", err)
+	}
+	if len(col.Requests) != 0 {
+		t.Errorf("Expected the root to be empty, got %+v", col.Requests)
+	}
+	entry, ok := col.Entry("health")
+	if !ok || strings.Join(entry.Path, "/") != "Auth/Tokens/Admin" {
+		t.Errorf("Expected Health in Auth/Tokens/Admin, got %+v", entry.Path)
+	}
+
+	if err := col.MoveRequest("health", "missing"); err == nil {
+		t.Error("Expected an error for an unknown folder")
+	}
+	if err := col.MoveRequest("missing", ""); err == nil {
+		t.Error("Expected an error for an unknown request")
+	}
+
+	if _, ok := col.RemoveRequest("refresh"); !ok || col.FindRequest("refresh") != nil {
+		t.Error("Expected Refresh to be removed")
+	}
+}
+
+func TestCollection_FoldersYAML(t *testing.T) {
+	col := testTree()
+	assignIDs(&col, make(map[string]bool), NewID)
+
+	data, err := yaml.Marshal(col)
+	if err != nil {
+		t.Fatalf("Failed to marshal: %v", err)
+	}
+	var loaded Collection
+	if err := yaml.Unmarshal(data, &loaded); err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+	if loaded.RequestCount() != 3 || loaded.FindRequest("refresh") == nil || loaded.FindFolder("tokens") == nil {
+		t.Errorf("Folder tree lost in round trip:\n%s", data)
+	}
+
+	filtered := loaded.FilterRequests(func(e RequestEntry) bool { return e.Request.Name == "Login" })
+	if filtered.RequestCount() != 1 || len(filtered.Folders) != 1 || len(filtered.Folders[0].Folders) != 0 {
+		t.Errorf("Expected only Auth/Login to remain, got %+v", filtered)
+	}
+}
@@ -0,0 +1,136 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// templateFunc is a dynamic value available as {{$name args}}, evaluated at send time
+type templateFunc struct {
+	usage string
+	eval  func(args string) (string, error)
+}
+
+var templateFuncs = map[string]templateFunc{
+	"$uuid": {"Random UUID v4", func(string) (string, error) {
+		return newUUID()
+	}},
+	"$timestamp": {"Unix time in seconds", func(string) (string, error) {
+		return strconv.FormatInt(time.Now().Unix(), 10), nil
+	}},
+	"$isoTimestamp": {"UTC time, ISO 8601", func(string) (string, error) {
+		return time.Now().UTC().Format(time.RFC3339), nil
+	}},
+	"$randomInt": {"$randomInt min max", func(args string) (string, error) {
+		return randomInt(strings.Fields(args))
+	}},
+	"$randomEmail": {"Random @example.com address", func(string) (string, error) {
+		name, err := randomString(10)
+		if err != nil {
+			return "", err
+		}
+		return name + "@example.com", nil
+	}},
+	"$env": {"$env NAME (OS environment)", func(args string) (string, error) {
+		name := strings.TrimSpace(args)
+		if name == "" {
+			return "", fmt.Errorf("$env needs a variable name")
+		}
+		return os.Getenv(name), nil
+	}},
+	"$base64": {"$base64 text", func(args string) (string, error) {
+		return base64.StdEncoding.EncodeToString([]byte(args)), nil
+	}},
+	"$urlencode": {"$urlencode text", func(args string) (string, error) {
+		return url.QueryEscape(args), nil
+	}},
+	"$hmacSha256": {"$hmacSha256 key message (hex)", func(args string) (string, error) {
+		key, msg, ok := strings.Cut(args, " ")
+		if !ok || key == "" {
+			return "", fmt.Errorf("$hmacSha256 needs a key and a message")
+		}
+		mac := hmac.New(sha256.New, []byte(key))
+		mac.Write([]byte(msg))
+		return hex.EncodeToString(mac.Sum(nil)), nil
+	}},
+}
+
+// TemplateFunctions returns the built-in dynamic functions and a usage hint for each
+func TemplateFunctions() map[string]string {
+	funcs := make(map[string]string, len(templateFuncs))
+	for name, fn := range templateFuncs {
+		funcs[name] = fn.usage
+	}
+	return funcs
+}
+
+// EvalFunction evaluates a "$name args" expression (the inside of a {{ }} tag).
+// ok is false if the expression does not name a built-in function.
+func EvalFunction(expr string) (value string, ok bool, err error) {
+	expr = strings.TrimSpace(expr)
+	name, args, _ := strings.Cut(expr, " ")
+	fn, ok := templateFuncs[name]
+	if !ok {
+		return "", false, nil
+	}
+	value, err = fn.eval(strings.TrimSpace(args))
+	return value, true, err
+}
+
+func newUUID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40 // version 4
+	b[8] = (b[8] & 0x3f) | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
+
+func randomInt(args []string) (string, error) {
+	lo, hi := int64(0), int64(1000)
+	if len(args) == 2 {
+		var err1, err2 error
+		lo, err1 = strconv.ParseInt(args[0], 10, 64)
+		hi, err2 = strconv.ParseInt(args[1], 10, 64)
+		if err1 != nil || err2 != nil {
+			return "", fmt.Errorf("$randomInt needs integer bounds")
+		}
+	} else if len(args) != 0 {
+		return "", fmt.Errorf("$randomInt takes min and max")
+	}
+	// The span of the widest ranges overflows an int64
+	span := new(big.Int).Sub(big.NewInt(hi), big.NewInt(lo))
+	span.Add(span, big.NewInt(1))
+	if span.Sign() <= 0 {
+		return "", fmt.Errorf("$randomInt: max is below min")
+	}
+	n, err := rand.Int(rand.Reader, span)
+	if err != nil {
+		return "", err
+	}
+	return n.Add(n, big.NewInt(lo)).String(), nil
+}
+
+func randomString(n int) (string, error) {
+	const letters = "abcdefghijklmnopqrstuvwxyz0123456789"
+	b := make([]byte, n)
+	for i := range b {
+		idx, err := rand.Int(rand.Reader, big.NewInt(int64(len(letters))))
+		if err != nil {
+			return "", err
+		}
+		b[i] = letters[idx.Int64()]
+	}
+	return string(b), nil
+}
@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestEvalFunction(t *testing.T) {
+	t.Setenv("TAPI_TEST_VAR", "from-os")
+
+	tests := []struct {
+		expr    string
+		pattern string
+	}{
+		{"$uuid", `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`},
+		{"$timestamp", `^\d{10,}$`},
+		{"$isoTimestamp", `^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}Z$`},
+		{"$randomEmail", `^[a-z0-9]{10}@example\.com$`},
+		{"$env TAPI_TEST_VAR", `^from-os$`},
+		{"$base64 hello", `^aGVsbG8=$`},
+		{"$urlencode a/b c", `^a%2Fb\+c$`},
+		// echo -n "msg" | openssl dgst -sha256 -hmac "key"
+		{"$hmacSha256 key msg", `^2d93cbc1be167bcb1637a4a23cbff01a7878f0c50ee833954ea5221bb1b8c628$`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			val, ok, err := EvalFunction(tt.expr)
+			if !ok || err != nil {
+				t.Fatalf("Expected %s to evaluate, got ok=%v err=%v", tt.expr, ok, err)
+			}
+			if !regexp.MustCompile(tt.pattern).MatchString(val) {
+				t.Errorf("Expected %s to match %s, got %q", tt.expr, tt.pattern, val)
+			}
+		})
+	}
+}
+
+func TestEvalFunction_RandomInt(t *testing.T) {
+	for i := 0; i < 50; i++ {
+		val, _, err := EvalFunction("$randomInt 5 7")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		n, _ := strconv.Atoi(val)
+		if n < 5 || n > 7 {
+			t.Fatalf("Expected value in [5, 7], got %d", n)
+		}
+	}
+
+	if _, _, err := EvalFunction("$randomInt 9 1"); err == nil {
+		t.Error("Expected error when max is below min")
+	}
+
+	// Ranges wider than an int64 can hold
+	for _, fn := range []string{"$randomInt 0 9223372036854775807", "$randomInt -9223372036854775808 9223372036854775807"} {
+		val, _, err := EvalFunction(fn)
+		if err != nil {
+			t.Fatalf("Unexpected error for %s: %v", fn, err)
+		}
+		if _, err := strconv.ParseInt(val, 10, 64); err != nil {
+			t.Errorf("Expected an int64 from %s, got %q", fn, val)
+		}
+	}
+}
+
+func TestEvalFunction_Unknown(t *testing.T) {
+	if _, ok, _ := EvalFunction("$nope"); ok {
+		t.Error("Expected unknown function to be reported")
+	}
+	if _, ok, _ := EvalFunction("host"); ok {
+		t.Error("Expected plain variable not to be a function")
+	}
+}
+
+func TestTemplateFunctions(t *testing.T) {
+	funcs := TemplateFunctions()
+	for name := range funcs {
+		if !strings.HasPrefix(name, "$") {
+			t.Errorf("Expected function names to start with $, got %q", name)
+		}
+	}
+	if _, ok := funcs["$uuid"]; !ok {
+		t.Error("Expected $uuid to be listed")
+	}
+}
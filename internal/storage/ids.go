@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"fmt"
+)
+
+// NewID returns a new random identifier for a request or collection
+func NewID() string {
+	id, err := newUUID()
+	if err != nil {
+		// crypto/rand does not fail on supported platforms
+		panic(err)
+	}
+	return id
+}
+
+// assignIDs gives an ID from newID to the collection and every folder and
+// request that lacks one, or whose ID was already seen (e.g. a request copied
+// by hand in the YAML file)
+func assignIDs(c *Collection, seen map[string]bool, newID func() string) {
+	assignID(&c.ID, seen, newID)
+	assignTreeIDs(c.Requests, c.Folders, seen, newID)
+}
+
+func assignTreeIDs(requests []Request, folders []Folder, seen map[string]bool, newID func() string) {
+	for i := range requests {
+		assignID(&requests[i].ID, seen, newID)
+	}
+	for i := range folders {
+		assignID(&folders[i].ID, seen, newID)
+		assignTreeIDs(folders[i].Requests, folders[i].Folders, seen, newID)
+	}
+}
+
+func assignID(id *string, seen map[string]bool, newID func() string) {
+	for *id == "" || seen[*id] {
+		*id = newID()
+	}
+	seen[*id] = true
+}
+
+// fileIDs returns a newID for the entries of a collection file that have no
+// ID yet. The IDs follow from the file name and the order they are handed
+// out in, so they stay the same from one load to the next until a save
+// writes them to the file.
+func fileIDs(filename string) func() string {
+	n := 0
+	return func() string {
+		n++
+		sum := sha256.Sum256([]byte(fmt.Sprintf("%s#%d", filename, n)))
+		b := sum[:16]
+		b[6] = (b[6] & 0x0f) | 0x50 // version 5, name-based
+		b[8] = (b[8] & 0x3f) | 0x80 // RFC 4122 variant
+		return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+	}
+}
+
+// CollectionIndex returns the position of the collection with the given ID, or -1
+func CollectionIndex(collections []Collection, id string) int {
+	for i, c := range collections {
+		if c.ID == id {
+			return i
+		}
+	}
+	return -1
+}
@@ -17,9 +17,8 @@ func importCurl(input string) (storage.Request, error) {
 	tokens := tokenizeCurl(input)
 
 	req := storage.Request{
-		Name:    "Imported Request",
-		Method:  "GET",
-		Headers: make(map[string]string),
+		Name:   "Imported Request",
+		Method: "GET",
 	}
 
 	for i := 0; i < len(tokens); i++ {
@@ -37,7 +36,7 @@ func importCurl(input string) (storage.Request, error) {
 				i++
 				parts := strings.SplitN(tokens[i], ":", 2)
 				if len(parts) == 2 {
-					req.Headers[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+					req.Headers.Add(strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]))
 				}
 			}
 
@@ -2,6 +2,8 @@ package importer
 
 import (
 	"testing"
+
+	"github.com/styltsou/tapi/internal/storage"
 )
 
 // ========================================
@@ -60,7 +62,7 @@ func TestImportPostman(t *testing.T) {
 		if r.Name != "Get Users" || r.Method != "GET" || r.URL != "https://api.example.com/users" {
 			t.Errorf("request 0 mismatch: %+v", r)
 		}
-		if r.Headers["Accept"] != "application/json" {
+		if r.Headers.Get("Accept") != "application/json" {
 			t.Errorf("missing Accept header")
 		}
 
@@ -95,11 +97,53 @@ func TestImportPostman(t *testing.T) {
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
-		if len(col.Requests) != 2 {
-			t.Fatalf("got %d requests, want 2 (flattened)", len(col.Requests))
+		if len(col.Requests) != 1 || col.Requests[0].Name != "Health" {
+			t.Fatalf("expected only 'Health' at the root, got %+v", col.Requests)
 		}
-		if col.Requests[0].Name != "Login" {
-			t.Errorf("expected flattened request 'Login', got %q", col.Requests[0].Name)
+		if len(col.Folders) != 1 || col.Folders[0].Name != "Auth Folder" {
+			t.Fatalf("expected folder 'Auth Folder', got %+v", col.Folders)
+		}
+		if len(col.Folders[0].Requests) != 1 || col.Folders[0].Requests[0].Name != "Login" {
+			t.Errorf("expected 'Login' inside the folder, got %+v", col.Folders[0].Requests)
+		}
+	})
+
+	t.Run("folder auth", func(t *testing.T) {
+		data := []byte(`{
+			"info": {"name": "Auth"},
+			"auth": {"type": "apikey", "apikey": [{"key": "key", "value": "X-Key"}, {"key": "value", "value": "{{key}}"}, {"key": "in", "value": "query"}]},
+			"item": [
+				{
+					"name": "Admin",
+					"auth": {"type": "bearer", "bearer": [{"key": "token", "value": "{{admin_token}}", "type": "string"}]},
+					"item": [
+						{"name": "Users", "request": {"method": "GET", "url": "/users"}},
+						{"name": "Public", "request": {"method": "GET", "url": "/public", "auth": {"type": "noauth"}}},
+						{"name": "Legacy", "request": {"method": "GET", "url": "/legacy", "auth": {"type": "basic", "basic": [{"key": "username", "value": "admin"}, {"key": "password", "value": "secret"}]}}}
+					]
+				}
+			]
+		}`)
+
+		col, err := importPostman(data)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if col.Auth == nil || col.Auth.Type != storage.AuthAPIKey || col.Auth.Key != "X-Key" || col.Auth.Value != "{{key}}" || col.Auth.In != storage.InQuery {
+			t.Errorf("collection auth = %+v", col.Auth)
+		}
+		folder := col.Folders[0]
+		if folder.Auth == nil || folder.Auth.Type != storage.AuthBearer || folder.Auth.Token != "{{admin_token}}" {
+			t.Fatalf("folder auth = %+v", folder.Auth)
+		}
+		if r := folder.Requests[0]; r.Auth != nil || r.EffectiveAuthMode() != storage.AuthInherit {
+			t.Errorf("expected Users to inherit, got mode %q auth %+v", r.EffectiveAuthMode(), r.Auth)
+		}
+		if r := folder.Requests[1]; r.EffectiveAuthMode() != storage.AuthNone {
+			t.Errorf("expected Public to send no auth, got mode %q", r.EffectiveAuthMode())
+		}
+		if r := folder.Requests[2]; r.Auth == nil || r.Auth.Type != storage.AuthBasic || r.Auth.Username != "admin" || r.Auth.Password != "secret" {
+			t.Errorf("Legacy auth = %+v", r.Auth)
 		}
 	})
 
@@ -168,7 +212,7 @@ func TestImportInsomnia(t *testing.T) {
 		if len(col.Requests) != 2 {
 			t.Fatalf("got %d requests, want 2", len(col.Requests))
 		}
-		if col.Requests[0].Method != "GET" || col.Requests[0].Headers["Accept"] != "application/json" {
+		if col.Requests[0].Method != "GET" || col.Requests[0].Headers.Get("Accept") != "application/json" {
 			t.Errorf("request 0 mismatch: %+v", col.Requests[0])
 		}
 		if col.Requests[1].Body != `{"title": "test"}` {
@@ -176,6 +220,83 @@ func TestImportInsomnia(t *testing.T) {
 		}
 	})
 
+	t.Run("request groups", func(t *testing.T) {
+		data := []byte(`{
+			"_type": "export",
+			"__export_format": 4,
+			"resources": [
+				{"_type": "workspace", "_id": "wrk_1", "name": "Groups"},
+				{"_type": "request", "_id": "req_1", "parentId": "fld_2", "name": "Refresh", "method": "POST", "url": "/refresh"},
+				{"_type": "request_group", "_id": "fld_1", "parentId": "wrk_1", "name": "Auth"},
+				{"_type": "request_group", "_id": "fld_2", "parentId": "fld_1", "name": "Tokens"},
+				{"_type": "request", "_id": "req_2", "parentId": "fld_1", "name": "Login", "method": "POST", "url": "/login"},
+				{"_type": "request", "_id": "req_3", "parentId": "wrk_1", "name": "Health", "method": "GET", "url": "/health"}
+			]
+		}`)
+
+		col, err := importInsomnia(data)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(col.Requests) != 1 || col.Requests[0].Name != "Health" {
+			t.Fatalf("expected only 'Health' at the root, got %+v", col.Requests)
+		}
+		if len(col.Folders) != 1 || col.Folders[0].Name != "Auth" {
+			t.Fatalf("expected folder 'Auth', got %+v", col.Folders)
+		}
+		auth := col.Folders[0]
+		if len(auth.Requests) != 1 || auth.Requests[0].Name != "Login" {
+			t.Errorf("expected 'Login' in Auth, got %+v", auth.Requests)
+		}
+		if len(auth.Folders) != 1 || auth.Folders[0].Name != "Tokens" || len(auth.Folders[0].Requests) != 1 || auth.Folders[0].Requests[0].Name != "Refresh" {
+			t.Errorf("expected Auth/Tokens/Refresh, got %+v", auth.Folders)
+		}
+	})
+
+	t.Run("group auth and headers", func(t *testing.T) {
+		data := []byte(`{
+			"_type": "export",
+			"__export_format": 4,
+			"resources": [
+				{"_type": "workspace", "_id": "wrk_1", "name": "Groups"},
+				{
+					"_type": "request_group", "_id": "fld_1", "parentId": "wrk_1", "name": "Admin",
+					"headers": [{"name": "X-Tenant", "value": "acme"}, {"name": "X-Debug", "value": "1", "disabled": true}],
+					"authentication": {"type": "oauth2", "grantType": "client_credentials", "accessTokenUrl": "https://auth.example.com/token", "clientId": "app", "clientSecret": "{{secret}}", "scope": "admin"}
+				},
+				{"_type": "request_group", "_id": "fld_2", "parentId": "wrk_1", "name": "Plain", "authentication": {}},
+				{"_type": "request", "_id": "req_1", "parentId": "fld_1", "name": "Users", "method": "GET", "url": "/users", "authentication": {}},
+				{"_type": "request", "_id": "req_2", "parentId": "fld_1", "name": "Public", "method": "GET", "url": "/public", "authentication": {"type": "none"}},
+				{"_type": "request", "_id": "req_3", "parentId": "fld_2", "name": "Health", "method": "GET", "url": "/health"}
+			]
+		}`)
+
+		col, err := importInsomnia(data)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(col.Folders) != 2 {
+			t.Fatalf("expected 2 folders, got %+v", col.Folders)
+		}
+		admin := col.Folders[0]
+		if admin.Headers.Get("X-Tenant") != "acme" || len(admin.Headers) != 2 || admin.Headers[1].Enabled {
+			t.Errorf("folder headers = %+v", admin.Headers)
+		}
+		want := storage.Auth{Type: storage.AuthOAuth2, Grant: storage.GrantClientCredentials, TokenURL: "https://auth.example.com/token", ClientID: "app", ClientSecret: "{{secret}}", Scope: "admin"}
+		if admin.Auth == nil || *admin.Auth != want {
+			t.Errorf("folder auth = %+v, want %+v", admin.Auth, want)
+		}
+		if r := admin.Requests[0]; r.EffectiveAuthMode() != storage.AuthInherit {
+			t.Errorf("expected Users to inherit, got mode %q", r.EffectiveAuthMode())
+		}
+		if r := admin.Requests[1]; r.EffectiveAuthMode() != storage.AuthNone {
+			t.Errorf("expected Public to send no auth, got mode %q", r.EffectiveAuthMode())
+		}
+		if col.Folders[1].Auth != nil {
+			t.Errorf("expected no auth on Plain, got %+v", col.Folders[1].Auth)
+		}
+	})
+
 	t.Run("no requests", func(t *testing.T) {
 		data := []byte(`{"_type": "export", "__export_format": 4, "resources": [{"_type": "workspace", "_id": "w1", "name": "Empty"}]}`)
 		_, err := importInsomnia(data)
@@ -212,8 +333,8 @@ func TestImportCurl(t *testing.T) {
 		if req.Method != "POST" {
 			t.Errorf("method = %q, want POST", req.Method)
 		}
-		if req.Headers["Content-Type"] != "application/json" {
-			t.Errorf("Content-Type header = %q", req.Headers["Content-Type"])
+		if req.Headers.Get("Content-Type") != "application/json" {
+			t.Errorf("Content-Type header = %q", req.Headers.Get("Content-Type"))
 		}
 		if req.Body != `{"name": "John"}` {
 			t.Errorf("body = %q", req.Body)
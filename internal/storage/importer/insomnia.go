@@ -24,15 +24,48 @@ type insomniaResource struct {
 
 type insomniaRequest struct {
 	insomniaResource
-	Method  string            `json:"method"`
-	URL     string            `json:"url"`
-	Headers []insomniaHeader  `json:"headers"`
-	Body    *insomniaBody     `json:"body"`
+	Method         string           `json:"method"`
+	URL            string           `json:"url"`
+	Headers        []insomniaHeader `json:"headers"`
+	Body           *insomniaBody    `json:"body"`
+	Authentication *insomniaAuth    `json:"authentication"`
+}
+
+// insomniaGroup is a request group, a folder whose auth and headers apply to
+// the requests below it
+type insomniaGroup struct {
+	insomniaResource
+	Headers        []insomniaHeader `json:"headers"`
+	Authentication *insomniaAuth    `json:"authentication"`
+}
+
+// insomniaAuth holds the settings of every type; an empty object inherits
+type insomniaAuth struct {
+	Type             string `json:"type"`
+	Disabled         bool   `json:"disabled"`
+	Username         string `json:"username"`
+	Password         string `json:"password"`
+	Token            string `json:"token"`
+	Key              string `json:"key"`
+	Value            string `json:"value"`
+	AddTo            string `json:"addTo"`
+	GrantType        string `json:"grantType"`
+	AccessTokenURL   string `json:"accessTokenUrl"`
+	AuthorizationURL string `json:"authorizationUrl"`
+	ClientID         string `json:"clientId"`
+	ClientSecret     string `json:"clientSecret"`
+	Scope            string `json:"scope"`
+	AccessKeyID      string `json:"accessKeyId"`
+	SecretAccessKey  string `json:"secretAccessKey"`
+	SessionToken     string `json:"sessionToken"`
+	Region           string `json:"region"`
+	Service          string `json:"service"`
 }
 
 type insomniaHeader struct {
-	Name  string `json:"name"`
-	Value string `json:"value"`
+	Name     string `json:"name"`
+	Value    string `json:"value"`
+	Disabled bool   `json:"disabled"`
 }
 
 type insomniaBody struct {
@@ -49,7 +82,8 @@ func importInsomnia(data []byte) (storage.Collection, error) {
 
 	// Find workspace name
 	collectionName := "Imported Collection"
-	var requests []storage.Request
+	var requests []insomniaItem
+	var groups []insomniaGroup
 
 	for _, raw := range export.Resources {
 		// Peek at _type to decide how to unmarshal
@@ -64,6 +98,13 @@ func importInsomnia(data []byte) (storage.Collection, error) {
 				collectionName = base.Name
 			}
 
+		case "request_group":
+			var group insomniaGroup
+			if err := json.Unmarshal(raw, &group); err != nil {
+				continue
+			}
+			groups = append(groups, group)
+
 		case "request":
 			var req insomniaRequest
 			if err := json.Unmarshal(raw, &req); err != nil {
@@ -76,10 +117,13 @@ func importInsomnia(data []byte) (storage.Collection, error) {
 				URL:    req.URL,
 			}
 
-			if len(req.Headers) > 0 {
-				r.Headers = make(map[string]string, len(req.Headers))
-				for _, h := range req.Headers {
-					r.Headers[h.Name] = h.Value
+			r.Headers = convertInsomniaHeaders(req.Headers)
+
+			// Without auth of its own a request inherits its folder's
+			if a := req.Authentication; a != nil && !a.Disabled {
+				r.Auth = convertInsomniaAuth(a)
+				if a.Type == "none" {
+					r.AuthMode = storage.AuthNone
 				}
 			}
 
@@ -87,7 +131,7 @@ func importInsomnia(data []byte) (storage.Collection, error) {
 				r.Body = req.Body.Text
 			}
 
-			requests = append(requests, r)
+			requests = append(requests, insomniaItem{parentID: req.ParentID, request: r})
 		}
 	}
 
@@ -95,8 +139,102 @@ func importInsomnia(data []byte) (storage.Collection, error) {
 		return storage.Collection{}, fmt.Errorf("no requests found in Insomnia export")
 	}
 
-	return storage.Collection{
-		Name:     collectionName,
-		Requests: requests,
-	}, nil
+	// Items whose parent is not a request group (usually the workspace) go to the root
+	isGroup := make(map[string]bool, len(groups))
+	for _, g := range groups {
+		isGroup[g.ID] = true
+	}
+	root := func(parentID string) bool { return !isGroup[parentID] }
+
+	col := storage.Collection{Name: collectionName}
+	col.Requests, col.Folders = buildInsomniaTree(requests, groups, root, make(map[string]bool))
+	return col, nil
+}
+
+// insomniaItem is a converted request waiting to be placed under its parent
+type insomniaItem struct {
+	parentID string
+	request  storage.Request
+}
+
+// buildInsomniaTree returns the requests and request groups accepted by isParent,
+// with their children nested below them
+func buildInsomniaTree(requests []insomniaItem, groups []insomniaGroup, isParent func(string) bool, visited map[string]bool) ([]storage.Request, []storage.Folder) {
+	var reqs []storage.Request
+	var folders []storage.Folder
+
+	for _, g := range groups {
+		// visited guards against parentId cycles in a malformed export
+		if !isParent(g.ParentID) || visited[g.ID] {
+			continue
+		}
+		visited[g.ID] = true
+		folder := storage.Folder{Name: g.Name, Headers: convertInsomniaHeaders(g.Headers)}
+		if g.Authentication != nil && !g.Authentication.Disabled {
+			folder.Auth = convertInsomniaAuth(g.Authentication)
+		}
+		id := g.ID
+		folder.Requests, folder.Folders = buildInsomniaTree(requests, groups, func(p string) bool { return p == id }, visited)
+		folders = append(folders, folder)
+	}
+	for _, item := range requests {
+		if isParent(item.parentID) {
+			reqs = append(reqs, item.request)
+		}
+	}
+	return reqs, folders
+}
+
+// convertInsomniaHeaders converts a header list, nil when empty
+func convertInsomniaHeaders(headers []insomniaHeader) storage.Params {
+	if len(headers) == 0 {
+		return nil
+	}
+	params := make(storage.Params, 0, len(headers))
+	for _, h := range headers {
+		params = append(params, storage.Param{Key: h.Name, Value: h.Value, Enabled: !h.Disabled})
+	}
+	return params
+}
+
+// convertInsomniaAuth converts an authentication setting. It returns nil for
+// no auth and for the types we don't support; none is told apart by the caller.
+func convertInsomniaAuth(a *insomniaAuth) *storage.Auth {
+	switch a.Type {
+	case "basic":
+		return &storage.Auth{Type: storage.AuthBasic, Username: a.Username, Password: a.Password}
+	case "digest":
+		return &storage.Auth{Type: storage.AuthDigest, Username: a.Username, Password: a.Password}
+	case "bearer":
+		return &storage.Auth{Type: storage.AuthBearer, Token: a.Token}
+	case "apikey":
+		auth := &storage.Auth{Type: storage.AuthAPIKey, Key: a.Key, Value: a.Value}
+		if a.AddTo == "queryParams" {
+			auth.In = storage.InQuery
+		}
+		return auth
+	case "oauth2":
+		// Insomnia names its grants as we do
+		return &storage.Auth{
+			Type:         storage.AuthOAuth2,
+			Grant:        a.GrantType,
+			TokenURL:     a.AccessTokenURL,
+			AuthURL:      a.AuthorizationURL,
+			ClientID:     a.ClientID,
+			ClientSecret: a.ClientSecret,
+			Scope:        a.Scope,
+			Username:     a.Username,
+			Password:     a.Password,
+		}
+	case "iam":
+		return &storage.Auth{
+			Type:         storage.AuthAWS,
+			AccessKey:    a.AccessKeyID,
+			SecretKey:    a.SecretAccessKey,
+			SessionToken: a.SessionToken,
+			Region:       a.Region,
+			Service:      a.Service,
+		}
+	}
+	return nil
 }
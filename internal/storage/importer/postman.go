@@ -12,6 +12,7 @@ import (
 type postmanCollection struct {
 	Info postmanInfo   `json:"info"`
 	Item []postmanItem `json:"item"`
+	Auth *postmanAuth  `json:"auth"`
 }
 
 type postmanInfo struct {
@@ -22,8 +23,9 @@ type postmanInfo struct {
 type postmanItem struct {
 	Name    string          `json:"name"`
 	Request *postmanRequest `json:"request"`
-	// Folders contain nested items
+	// Folders contain nested items, and the auth they share
 	Item []postmanItem `json:"item"`
+	Auth *postmanAuth  `json:"auth"`
 }
 
 type postmanRequest struct {
@@ -31,11 +33,47 @@ type postmanRequest struct {
 	URL    json.RawMessage `json:"url"`
 	Header []postmanKV     `json:"header"`
 	Body   *postmanBody    `json:"body"`
+	Auth   *postmanAuth    `json:"auth"`
+}
+
+// postmanAuth keeps the settings of each type under the type name, as a
+// list of key/value pairs: {"type": "bearer", "bearer": [{"key": "token", ...}]}
+type postmanAuth struct {
+	Type     string
+	Settings map[string]string
+}
+
+func (a *postmanAuth) UnmarshalJSON(data []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(raw["type"], &a.Type); err != nil {
+		return fmt.Errorf("auth without a type: %w", err)
+	}
+	var settings []struct {
+		Key   string `json:"key"`
+		Value any    `json:"value"`
+	}
+	// A type without settings has no list
+	if list, ok := raw[a.Type]; ok {
+		if err := json.Unmarshal(list, &settings); err != nil {
+			return fmt.Errorf("invalid %s auth: %w", a.Type, err)
+		}
+	}
+	a.Settings = make(map[string]string, len(settings))
+	for _, kv := range settings {
+		if kv.Value != nil {
+			a.Settings[kv.Key] = fmt.Sprint(kv.Value)
+		}
+	}
+	return nil
 }
 
 type postmanKV struct {
-	Key   string `json:"key"`
-	Value string `json:"value"`
+	Key      string `json:"key"`
+	Value    string `json:"value"`
+	Disabled bool   `json:"disabled"`
 }
 
 type postmanBody struct {
@@ -79,22 +117,92 @@ func importPostman(data []byte) (storage.Collection, error) {
 		return storage.Collection{}, fmt.Errorf("Postman collection has no name")
 	}
 
-	requests := flattenPostmanItems(pc.Item)
+	requests, folders := convertPostmanItems(pc.Item)
 
 	return storage.Collection{
 		Name:     pc.Info.Name,
+		Auth:     convertPostmanAuth(pc.Auth),
 		Requests: requests,
+		Folders:  folders,
 	}, nil
 }
 
-// flattenPostmanItems recursively extracts requests from potentially nested folders.
-func flattenPostmanItems(items []postmanItem) []storage.Request {
+// convertPostmanHeaders converts a header list, nil when empty
+func convertPostmanHeaders(header []postmanKV) storage.Params {
+	if len(header) == 0 {
+		return nil
+	}
+	headers := make(storage.Params, 0, len(header))
+	for _, h := range header {
+		headers = append(headers, storage.Param{Key: h.Key, Value: h.Value, Enabled: !h.Disabled})
+	}
+	return headers
+}
+
+// postmanGrants maps Postman oauth2 grant types to ours
+var postmanGrants = map[string]string{
+	"client_credentials":   storage.GrantClientCredentials,
+	"password_credentials": storage.GrantPassword,
+	"authorization_code":   storage.GrantAuthorizationCode,
+}
+
+// convertPostmanAuth converts an auth setting. It returns nil for no auth and
+// for the types we don't support; noauth is told apart by the caller.
+func convertPostmanAuth(a *postmanAuth) *storage.Auth {
+	if a == nil {
+		return nil
+	}
+	s := a.Settings
+	switch a.Type {
+	case "basic":
+		return &storage.Auth{Type: storage.AuthBasic, Username: s["username"], Password: s["password"]}
+	case "digest":
+		return &storage.Auth{Type: storage.AuthDigest, Username: s["username"], Password: s["password"]}
+	case "bearer":
+		return &storage.Auth{Type: storage.AuthBearer, Token: s["token"]}
+	case "apikey":
+		auth := &storage.Auth{Type: storage.AuthAPIKey, Key: s["key"], Value: s["value"]}
+		if s["in"] == "query" {
+			auth.In = storage.InQuery
+		}
+		return auth
+	case "oauth2":
+		return &storage.Auth{
+			Type:         storage.AuthOAuth2,
+			Grant:        postmanGrants[s["grant_type"]],
+			TokenURL:     s["accessTokenUrl"],
+			AuthURL:      s["authUrl"],
+			ClientID:     s["clientId"],
+			ClientSecret: s["clientSecret"],
+			Scope:        s["scope"],
+			Username:     s["username"],
+			Password:     s["password"],
+		}
+	case "awsv4":
+		return &storage.Auth{
+			Type:         storage.AuthAWS,
+			AccessKey:    s["accessKey"],
+			SecretKey:    s["secretKey"],
+			SessionToken: s["sessionToken"],
+			Region:       s["region"],
+			Service:      s["service"],
+		}
+	}
+	return nil
+}
+
+// convertPostmanItems converts items into requests and folders, keeping the
+// folder hierarchy.
+func convertPostmanItems(items []postmanItem) ([]storage.Request, []storage.Folder) {
 	var requests []storage.Request
+	var folders []storage.Folder
 
 	for _, item := range items {
-		// If it's a folder (has sub-items but no request), recurse
-		if item.Request == nil && len(item.Item) > 0 {
-			requests = append(requests, flattenPostmanItems(item.Item)...)
+		// A folder has sub-items but no request
+		if item.Request == nil && item.Item != nil {
+			folder := storage.Folder{Name: item.Name, Auth: convertPostmanAuth(item.Auth)}
+			folder.Requests, folder.Folders = convertPostmanItems(item.Item)
+			folders = append(folders, folder)
 			continue
 		}
 
@@ -103,16 +211,17 @@ func flattenPostmanItems(items []postmanItem) []storage.Request {
 		}
 
 		req := storage.Request{
-			Name:   item.Name,
-			Method: item.Request.Method,
-			URL:    parsePostmanURL(item.Request.URL),
+			Name:    item.Name,
+			Method:  item.Request.Method,
+			URL:     parsePostmanURL(item.Request.URL),
+			Headers: convertPostmanHeaders(item.Request.Header),
 		}
 
-		// Headers
-		if len(item.Request.Header) > 0 {
-			req.Headers = make(map[string]string, len(item.Request.Header))
-			for _, h := range item.Request.Header {
-				req.Headers[h.Key] = h.Value
+		// Without auth of its own a request inherits its folder's
+		if a := item.Request.Auth; a != nil {
+			req.Auth = convertPostmanAuth(a)
+			if a.Type == "noauth" {
+				req.AuthMode = storage.AuthNone
 			}
 		}
 
@@ -124,5 +233,5 @@ func flattenPostmanItems(items []postmanItem) []storage.Request {
 		requests = append(requests, req)
 	}
 
-	return requests
+	return requests, folders
 }
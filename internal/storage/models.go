@@ -1,27 +1,151 @@
 // Package storage handles everything regarding the persistence layer
 package storage
 
-type BasicAuth struct {
-	Username string `yaml:"username"`
-	Password string `yaml:"password"`
-}
+import (
+	"strings"
+	"time"
+)
 
 type Request struct {
-	Name    string            `yaml:"name"`
-	Method  string            `yaml:"method"`
-	URL     string            `yaml:"url"`
-	Headers map[string]string `yaml:"headers,omitempty"`
-	Body    string            `yaml:"body,omitempty"`
-	Auth    *BasicAuth        `yaml:"auth,omitempty"`
+	ID        string `yaml:"id"` // stable across renames, assigned on first load or save
+	Name      string `yaml:"name"`
+	Method    string `yaml:"method"`
+	URL       string `yaml:"url"`
+	Query     Params `yaml:"query,omitempty"` // appended to the URL when sent
+	Headers   Params `yaml:"headers,omitempty"`
+	Body      string `yaml:"body,omitempty"`
+	Auth      *Auth  `yaml:"auth,omitempty"`
+	AuthMode  string `yaml:"auth_mode,omitempty"`  // inherit, none or override, see EffectiveAuthMode
+	Timeout   int    `yaml:"timeout,omitempty"`    // seconds, overrides the config timeout
+	NoCookies bool   `yaml:"no_cookies,omitempty"` // don't send the cookie jar's cookies (received ones are still kept)
+
+	// Redirects are followed up to MaxRedirects (DefaultMaxRedirects when 0)
+	NoFollow     bool `yaml:"no_follow,omitempty"`     // return the 3xx response instead of following it
+	MaxRedirects int  `yaml:"max_redirects,omitempty"` // hops before giving up
+	KeepMethod   bool `yaml:"keep_method,omitempty"`   // resend the method and body on 301/302/303 instead of switching to GET
+
+	Variables  map[string]string `yaml:"variables,omitempty"` // request-scoped overrides
+	Assertions []Assertion       `yaml:"assertions,omitempty"`
+	Extract    []Extraction      `yaml:"extract,omitempty"`
+}
+
+// Extraction sources
+const (
+	ExtractJSON   = "json"   // property is a JSON path
+	ExtractHeader = "header" // property is a header name
+	ExtractRegex  = "regex"  // property is a regex on the body; first capture group wins
+	ExtractCookie = "cookie" // property is a cookie name from Set-Cookie
+)
+
+// Extraction copies a value from the response into a runtime variable,
+// so later requests can use it as {{var}}
+type Extraction struct {
+	Var      string `yaml:"var"`
+	From     string `yaml:"from"`
+	Property string `yaml:"property"`
+	Persist  bool   `yaml:"persist,omitempty"` // also save into the active environment file
+}
+
+// Assertion targets
+const (
+	AssertStatus   = "status"
+	AssertHeader   = "header"
+	AssertJSON     = "json"
+	AssertBody     = "body"
+	AssertDuration = "duration" // milliseconds
+	AssertSize     = "size"     // bytes
+)
+
+// Assertion operators
+const (
+	OpEquals   = "equals"
+	OpInRange  = "in_range" // value "200-299"
+	OpExists   = "exists"
+	OpMatches  = "matches" // regular expression
+	OpContains = "contains"
+	OpType     = "type" // string, number, boolean, object, array, null
+	OpBelow    = "below"
+)
+
+// Assertion is an expected outcome checked against the response after every execution
+type Assertion struct {
+	Target   string `yaml:"target"`
+	Property string `yaml:"property,omitempty"` // header name or JSON path
+	Op       string `yaml:"op"`
+	Value    string `yaml:"value,omitempty"`
+}
+
+// String returns a short description, e.g. "header Content-Type equals application/json"
+func (a Assertion) String() string {
+	parts := []string{a.Target}
+	if a.Property != "" {
+		parts = append(parts, a.Property)
+	}
+	parts = append(parts, a.Op)
+	if a.Value != "" {
+		parts = append(parts, a.Value)
+	}
+	return strings.Join(parts, " ")
+}
+
+// DefaultMaxRedirects is the number of redirects followed when a request
+// does not set its own limit
+const DefaultMaxRedirects = 10
+
+// EffectiveMaxRedirects returns the number of redirects the request follows
+func (r Request) EffectiveMaxRedirects() int {
+	if r.NoFollow {
+		return 0
+	}
+	if r.MaxRedirects > 0 {
+		return r.MaxRedirects
+	}
+	return DefaultMaxRedirects
+}
+
+// TimeoutDuration returns the request timeout override, or 0 if none is set
+func (r Request) TimeoutDuration() time.Duration {
+	if r.Timeout <= 0 {
+		return 0
+	}
+	return time.Duration(r.Timeout) * time.Second
+}
+
+// WithDefaultHeaders returns a copy of the request with the given headers added
+// before its own. A header the request already lists, even disabled, is not added.
+func (r Request) WithDefaultHeaders(defaults map[string]string) Request {
+	if len(defaults) == 0 {
+		return r
+	}
+	var headers Params
+	for _, h := range ParamsFromMap(defaults) {
+		if !r.Headers.Contains(h.Key) {
+			headers = append(headers, h)
+		}
+	}
+	r.Headers = append(headers, r.Headers...)
+	return r
 }
 
 type Collection struct {
-	Name     string    `yaml:"name"`
-	BaseURL  string    `yaml:"base_url"`
-	Requests []Request `yaml:"requests"`
+	ID        string            `yaml:"id"`
+	Name      string            `yaml:"name"`
+	BaseURL   string            `yaml:"base_url"`
+	Headers   Params            `yaml:"headers,omitempty"` // inherited by every request
+	Auth      *Auth             `yaml:"auth,omitempty"`    // inherited by every request
+	Variables map[string]string `yaml:"variables,omitempty"`
+	Transport Transport         `yaml:"transport,omitempty"` // proxy and TLS settings, see MergeTransports
+	Requests  []Request         `yaml:"requests"`
+	Folders   []Folder          `yaml:"folders,omitempty"`
 }
 
 type Environment struct {
-	Name      string            `yaml:"name"`
-	Variables map[string]string `yaml:"variables"`
+	Name       string            `yaml:"name"`
+	Variables  map[string]string `yaml:"variables"`
+	Secrets    []string          `yaml:"secrets,omitempty"`    // names of variables whose values are masked
+	Encryption *Encryption       `yaml:"encryption,omitempty"` // set once secrets are stored encrypted
+	Transport  Transport         `yaml:"transport,omitempty"`  // proxy and TLS settings, see MergeTransports
+
+	locked  map[string]string // encrypted values that could not be decrypted this session
+	lockErr error             // why they could not, see LockError
 }
@@ -0,0 +1,165 @@
+package storage
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Param is a single header or query parameter. Disabled entries are kept in the
+// request file but not sent.
+type Param struct {
+	Key     string
+	Value   string
+	Enabled bool
+}
+
+// Params is an ordered list of headers or query parameters. The same key may
+// appear several times and is sent once per entry, in order.
+type Params []Param
+
+// paramYAML is the on-disk form of a Param; enabled is omitted when true
+type paramYAML struct {
+	Key     string `yaml:"key"`
+	Value   string `yaml:"value"`
+	Enabled *bool  `yaml:"enabled,omitempty"`
+}
+
+// ParamsFromMap converts a map into enabled params, sorted by key
+func ParamsFromMap(m map[string]string) Params {
+	if len(m) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	params := make(Params, 0, len(keys))
+	for _, k := range keys {
+		params = append(params, Param{Key: k, Value: m[k], Enabled: true})
+	}
+	return params
+}
+
+// UnmarshalYAML accepts the list form as well as the older "name: value" map,
+// which is converted to enabled entries sorted by key
+func (p *Params) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.MappingNode {
+		var m map[string]string
+		if err := value.Decode(&m); err != nil {
+			return err
+		}
+		*p = ParamsFromMap(m)
+		return nil
+	}
+
+	var entries []paramYAML
+	if err := value.Decode(&entries); err != nil {
+		return err
+	}
+	params := make(Params, 0, len(entries))
+	for _, e := range entries {
+		params = append(params, Param{Key: e.Key, Value: e.Value, Enabled: e.Enabled == nil || *e.Enabled})
+	}
+	*p = params
+	return nil
+}
+
+// MarshalYAML writes the list form
+func (p Params) MarshalYAML() (interface{}, error) {
+	entries := make([]paramYAML, 0, len(p))
+	for _, param := range p {
+		e := paramYAML{Key: param.Key, Value: param.Value}
+		if !param.Enabled {
+			disabled := false
+			e.Enabled = &disabled
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// Get returns the value of the first enabled entry with the given key.
+// Keys are compared case-insensitively, like HTTP header names.
+func (p Params) Get(key string) string {
+	for _, param := range p {
+		if param.Enabled && strings.EqualFold(param.Key, key) {
+			return param.Value
+		}
+	}
+	return ""
+}
+
+// Values returns the values of all enabled entries with the given key, in order
+func (p Params) Values(key string) []string {
+	var values []string
+	for _, param := range p {
+		if param.Enabled && strings.EqualFold(param.Key, key) {
+			values = append(values, param.Value)
+		}
+	}
+	return values
+}
+
+// Contains reports whether an entry with the given key exists, enabled or not
+func (p Params) Contains(key string) bool {
+	for _, param := range p {
+		if strings.EqualFold(param.Key, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// Add appends an enabled entry
+func (p *Params) Add(key, value string) {
+	*p = append(*p, Param{Key: key, Value: value, Enabled: true})
+}
+
+// Enabled returns the entries that are sent
+func (p Params) Enabled() Params {
+	var enabled Params
+	for _, param := range p {
+		if param.Enabled {
+			enabled = append(enabled, param)
+		}
+	}
+	return enabled
+}
+
+// Clone returns a copy that can be modified independently
+func (p Params) Clone() Params {
+	if p == nil {
+		return nil
+	}
+	return append(Params(nil), p...)
+}
+
+// Encode builds a query string from the enabled entries, in order
+func (p Params) Encode() string {
+	var parts []string
+	for _, param := range p.Enabled() {
+		if param.Key == "" {
+			continue
+		}
+		parts = append(parts, url.QueryEscape(param.Key)+"="+url.QueryEscape(param.Value))
+	}
+	return strings.Join(parts, "&")
+}
+
+// FullURL returns the request URL with the enabled query params appended
+func (r Request) FullURL() string {
+	query := r.Query.Encode()
+	if query == "" {
+		return r.URL
+	}
+	sep := "?"
+	if strings.Contains(r.URL, "?") {
+		sep = "&"
+	}
+	return r.URL + sep + query
+}
@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestParams_YAML(t *testing.T) {
+	// The older map form is still accepted and sorted by key
+	legacy := `
+name: Legacy
+method: GET
+url: /users
+headers:
+  X-Trace: "1"
+  Accept: application/json
+`
+	var req Request
+	if err := yaml.Unmarshal([]byte(legacy), &req); err != nil {
+		t.Fatalf("Failed to parse legacy headers: %v", err)
+	}
+	want := Params{
+		{Key: "Accept", Value: "application/json", Enabled: true},
+		{Key: "X-Trace", Value: "1", Enabled: true},
+	}
+	if !reflect.DeepEqual(req.Headers, want) {
+		t.Errorf("Expected %+v, got %+v", want, req.Headers)
+	}
+
+	// The list form keeps order, duplicates and disabled entries
+	req.Headers = Params{
+		{Key: "Accept", Value: "application/json", Enabled: true},
+		{Key: "Accept", Value: "text/plain", Enabled: false},
+	}
+	req.Query = Params{{Key: "page", Value: "1", Enabled: true}}
+	data, err := yaml.Marshal(req)
+	if err != nil {
+		t.Fatalf("Failed to marshal: %v", err)
+	}
+	if strings.Count(string(data), "enabled: false") != 1 {
+		t.Errorf("Expected enabled to be written for disabled entries only, got:\n%s", data)
+	}
+
+	var loaded Request
+	if err := yaml.Unmarshal(data, &loaded); err != nil {
+		t.Fatalf("Failed to parse list form: %v", err)
+	}
+	if !reflect.DeepEqual(loaded.Headers, req.Headers) || !reflect.DeepEqual(loaded.Query, req.Query) {
+		t.Errorf("Round trip mismatch: %+v %+v", loaded.Headers, loaded.Query)
+	}
+}
+
+func TestParams_Lookup(t *testing.T) {
+	p := Params{
+		{Key: "Accept", Value: "application/json", Enabled: false},
+		{Key: "accept", Value: "text/plain", Enabled: true},
+		{Key: "Accept", Value: "text/html", Enabled: true},
+	}
+
+	if got := p.Get("ACCEPT"); got != "text/plain" {
+		t.Errorf("Expected first enabled value text/plain, got %q", got)
+	}
+	if got := strings.Join(p.Values("Accept"), ","); got != "text/plain,text/html" {
+		t.Errorf("Expected enabled values in order, got %q", got)
+	}
+	if !p.Contains("accept") || p.Contains("X-Other") {
+		t.Errorf("Unexpected Contains result")
+	}
+}
+
+func TestRequest_FullURL(t *testing.T) {
+	tests := []struct {
+		url      string
+		query    Params
+		expected string
+	}{
+		{"/users", nil, "/users"},
+		{"/users", Params{{Key: "q", Value: "a b", Enabled: true}, {Key: "x", Value: "1", Enabled: false}}, "/users?q=a+b"},
+		{"/users?page=1", Params{{Key: "q", Value: "c", Enabled: true}}, "/users?page=1&q=c"},
+	}
+
+	for _, tt := range tests {
+		req := Request{URL: tt.url, Query: tt.query}
+		if got := req.FullURL(); got != tt.expected {
+			t.Errorf("FullURL(%q) = %q, want %q", tt.url, got, tt.expected)
+		}
+	}
+}
+
+func TestRequest_WithDefaultHeaders(t *testing.T) {
+	req := Request{Headers: Params{
+		{Key: "user-agent", Value: "custom", Enabled: false},
+		{Key: "X-Req", Value: "1", Enabled: true},
+	}}
+
+	got := req.WithDefaultHeaders(map[string]string{"User-Agent": "tapi", "Accept": "*/*"})
+	want := Params{
+		{Key: "Accept", Value: "*/*", Enabled: true},
+		{Key: "user-agent", Value: "custom", Enabled: false},
+		{Key: "X-Req", Value: "1", Enabled: true},
+	}
+	if !reflect.DeepEqual(got.Headers, want) {
+		t.Errorf("Expected %+v, got %+v", want, got.Headers)
+	}
+}
@@ -57,6 +57,7 @@ func LoadCollections() ([]Collection, []error, error) {
 
 	var collections []Collection
 	var loadErrors []error
+	seenIDs := make(map[string]bool)
 
 	// Iterate through files
 	for _, file := range files {
@@ -82,6 +83,10 @@ func LoadCollections() ([]Collection, []error, error) {
 		nameWithoutExt := strings.TrimSuffix(filename, filepath.Ext(filename))
 		collection.Name = formatName(nameWithoutExt)
 
+		// Files written before IDs existed get them in memory only; the IDs are
+		// the same on every load and are written by the next save
+		assignIDs(&collection, seenIDs, fileIDs(filename))
+
 		collections = append(collections, collection)
 		logger.Logger.Info("Loaded collection", "name", collection.Name, "file", file)
 	}
@@ -107,8 +112,9 @@ func formatName(filename string) string {
 	return strings.Join(words, " ")
 }
 
-// SaveCollection saves a collection to disk atomically
-func SaveCollection(c Collection) error {
+// SaveCollection saves a collection to disk atomically. IDs are given to the
+// collection and to the folders and requests in it that have none.
+func SaveCollection(c *Collection) error {
 	// Get collections directory path
 	collectionsPath, err := GetStoragePath("collections")
 	if err != nil {
@@ -123,6 +129,18 @@ func SaveCollection(c Collection) error {
 	// Determine filename from collection name
 	filename := slug.Make(c.Name) + ".yaml"
 	finalPath := filepath.Join(collectionsPath, filename)
+
+	assignIDs(c, make(map[string]bool), NewID)
+	if err := writeCollectionFile(finalPath, *c); err != nil {
+		return err
+	}
+
+	logger.Logger.Info("Saved collection", "name", c.Name, "file", finalPath)
+	return nil
+}
+
+// writeCollectionFile writes the collection YAML through a temporary file
+func writeCollectionFile(finalPath string, c Collection) error {
 	tempPath := finalPath + ".tmp"
 
 	// Marshal collection to YAML
@@ -145,8 +163,6 @@ func SaveCollection(c Collection) error {
 		os.Remove(tempPath)
 		return err
 	}
-
-	logger.Logger.Info("Saved collection", "name", c.Name, "file", finalPath)
 	return nil
 }
 
@@ -3,13 +3,14 @@ package storage
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/styltsou/tapi/internal/logger"
 )
 
 func TestMain(m *testing.M) {
-	_ = logger.Init()
+	logger.InitDiscard()
 	os.Exit(m.Run())
 }
 
@@ -59,7 +60,7 @@ func TestPersistence(t *testing.T) {
 	}
 
 	// Test Save
-	if err := SaveCollection(col); err != nil {
+	if err := SaveCollection(&col); err != nil {
 		t.Fatalf("SaveCollection failed: %v", err)
 	}
 
@@ -110,7 +111,7 @@ func TestPersistence_BasicAuth(t *testing.T) {
 				Name:   "With Auth",
 				Method: "GET",
 				URL:    "/protected",
-				Auth: &BasicAuth{
+				Auth: &Auth{
 					Username: "admin",
 					Password: "s3cret!",
 				},
@@ -123,7 +124,7 @@ func TestPersistence_BasicAuth(t *testing.T) {
 		},
 	}
 
-	if err := SaveCollection(col); err != nil {
+	if err := SaveCollection(&col); err != nil {
 		t.Fatalf("SaveCollection failed: %v", err)
 	}
 
@@ -166,3 +167,78 @@ func TestPersistence_BasicAuth(t *testing.T) {
 		t.Errorf("Expected Auth to be nil on second request, got %+v", noAuth.Auth)
 	}
 }
+
+func TestPersistence_IDs(t *testing.T) {
+	tmpDir := t.TempDir()
+	oldHome := os.Getenv("HOME")
+	os.Setenv("HOME", tmpDir)
+	defer os.Setenv("HOME", oldHome)
+
+	colDir := filepath.Join(tmpDir, ".tapi", "collections")
+	if err := os.MkdirAll(colDir, 0755); err != nil {
+		t.Fatalf("Failed to create collections dir: %v", err)
+	}
+
+	// Files written before IDs existed, with the same request name in both,
+	// and a request copied by hand (duplicate ID)
+	files := map[string]string{
+		"users.yaml":  "name: Users\nrequests:\n  - name: Login\n    method: POST\n    url: /login\n",
+		"orders.yaml": "name: Orders\nrequests:\n  - id: abc\n    name: Login\n    method: POST\n    url: /login\n  - id: abc\n    name: Login copy\n    method: POST\n    url: /login\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(colDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	first, _, err := LoadCollections()
+	if err != nil {
+		t.Fatalf("LoadCollections failed: %v", err)
+	}
+	seen := make(map[string]bool)
+	for _, c := range first {
+		for _, id := range append([]string{c.ID}, requestIDs(c)...) {
+			if id == "" || seen[id] {
+				t.Errorf("Expected unique non-empty IDs, got %q in %s", id, c.Name)
+			}
+			seen[id] = true
+		}
+	}
+
+	// Loading doesn't touch the files, yet the IDs are the same on the next load
+	for name, content := range files {
+		if data, _ := os.ReadFile(filepath.Join(colDir, name)); string(data) != content {
+			t.Errorf("Expected %s to be left as is, got:\n%s", name, data)
+		}
+	}
+	second, _, err := LoadCollections()
+	if err != nil {
+		t.Fatalf("LoadCollections failed: %v", err)
+	}
+	for i := range first {
+		if first[i].ID != second[i].ID || strings.Join(requestIDs(first[i]), ",") != strings.Join(requestIDs(second[i]), ",") {
+			t.Errorf("IDs changed between loads for %s", first[i].Name)
+		}
+	}
+
+	// A save writes them
+	if err := SaveCollection(&second[0]); err != nil {
+		t.Fatalf("SaveCollection failed: %v", err)
+	}
+	third, _, err := LoadCollections()
+	if err != nil {
+		t.Fatalf("LoadCollections failed: %v", err)
+	}
+	data, _ := os.ReadFile(filepath.Join(colDir, "orders.yaml"))
+	if third[0].ID != first[0].ID || !strings.Contains(string(data), "id: "+first[0].ID) {
+		t.Errorf("Expected the save to keep the ID %s, got %s:\n%s", first[0].ID, third[0].ID, data)
+	}
+}
+
+func requestIDs(c Collection) []string {
+	var ids []string
+	for _, r := range c.Requests {
+		ids = append(ids, r.ID)
+	}
+	return ids
+}
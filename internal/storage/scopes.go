@@ -0,0 +1,55 @@
+package storage
+
+// Variable scopes, from lowest to highest precedence
+const (
+	ScopeGlobal      = "global"
+	ScopeCollection  = "collection"
+	ScopeEnvironment = "environment"
+	ScopeRuntime     = "runtime"
+	ScopeRequest     = "request"
+)
+
+// Scopes holds the variable layers available to a request. When a name is
+// defined in several scopes, the more specific one wins:
+// global < collection < environment < runtime < request.
+type Scopes struct {
+	Global      map[string]string // ~/.tapi/globals.yaml
+	Collection  map[string]string // variables in the collection file
+	Environment map[string]string // the active environment
+	Runtime     map[string]string // values extracted from responses
+	Request     map[string]string // variables declared on the request
+
+	Secrets []string // names whose values are masked when displayed
+}
+
+type scopeLayer struct {
+	name string
+	vars map[string]string
+}
+
+// layers returns the scopes with their names, lowest precedence first
+func (s Scopes) layers() []scopeLayer {
+	return []scopeLayer{
+		{ScopeGlobal, s.Global},
+		{ScopeCollection, s.Collection},
+		{ScopeEnvironment, s.Environment},
+		{ScopeRuntime, s.Runtime},
+		{ScopeRequest, s.Request},
+	}
+}
+
+// Merged flattens the scopes into a single map used for substitution
+func (s Scopes) Merged() map[string]string {
+	return MergeVariables(s.Global, s.Collection, s.Environment, s.Runtime, s.Request)
+}
+
+// Sources returns, for every variable, the scope its value comes from
+func (s Scopes) Sources() map[string]string {
+	sources := make(map[string]string)
+	for _, layer := range s.layers() {
+		for k := range layer.vars {
+			sources[k] = layer.name
+		}
+	}
+	return sources
+}
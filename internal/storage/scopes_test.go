@@ -0,0 +1,38 @@
+package storage
+
+import "testing"
+
+func TestScopes_Precedence(t *testing.T) {
+	s := Scopes{
+		Global:      map[string]string{"host": "global", "a": "global"},
+		Collection:  map[string]string{"host": "collection", "b": "collection"},
+		Environment: map[string]string{"host": "env", "c": "env"},
+		Runtime:     map[string]string{"host": "runtime", "d": "runtime"},
+		Request:     map[string]string{"host": "request"},
+	}
+
+	merged := s.Merged()
+	expected := map[string]string{"host": "request", "a": "global", "b": "collection", "c": "env", "d": "runtime"}
+	for k, want := range expected {
+		if merged[k] != want {
+			t.Errorf("Expected %s=%q, got %q", k, want, merged[k])
+		}
+	}
+
+	sources := s.Sources()
+	expectedSources := map[string]string{
+		"host": ScopeRequest, "a": ScopeGlobal, "b": ScopeCollection, "c": ScopeEnvironment, "d": ScopeRuntime,
+	}
+	for k, want := range expectedSources {
+		if sources[k] != want {
+			t.Errorf("Expected %s from %q, got %q", k, want, sources[k])
+		}
+	}
+}
+
+func TestScopes_Empty(t *testing.T) {
+	var s Scopes
+	if len(s.Merged()) != 0 || len(s.Sources()) != 0 {
+		t.Error("Expected empty scopes to merge to nothing")
+	}
+}
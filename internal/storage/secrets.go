@@ -0,0 +1,319 @@
+package storage
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Mask is displayed in place of secret values
+const Mask = "••••••"
+
+// encPrefix marks an encrypted value in an environment file. The variable
+// name is authenticated with the value, so a value moved to another variable
+// doesn't decrypt; v1 values, written without it, are still read.
+const (
+	encPrefix   = "enc:v2:"
+	encPrefixV1 = "enc:v1:"
+)
+
+const defaultKDFIterations = 600_000
+
+var (
+	ErrLocked        = errors.New("secrets are encrypted, unlock them with the passphrase first")
+	ErrBadPassphrase = errors.New("wrong passphrase")
+)
+
+// Encryption describes how the secret values of an environment file are encrypted:
+// AES-256-GCM with a key derived from the session passphrase using PBKDF2-SHA256.
+type Encryption struct {
+	Salt       string `yaml:"salt"` // base64
+	Iterations int    `yaml:"iterations"`
+}
+
+// The passphrase is kept for the session only, derived keys are cached per salt
+var (
+	keyMu      sync.Mutex
+	passphrase string
+	keyCache   = map[string][]byte{}
+)
+
+// SetPassphrase sets the passphrase used to encrypt and decrypt secrets for this session.
+// An empty passphrase disables encryption of newly saved environments.
+func SetPassphrase(p string) {
+	keyMu.Lock()
+	defer keyMu.Unlock()
+	passphrase = p
+	keyCache = map[string][]byte{}
+}
+
+// HasPassphrase reports whether secrets can be encrypted and decrypted
+func HasPassphrase() bool {
+	keyMu.Lock()
+	defer keyMu.Unlock()
+	return passphrase != ""
+}
+
+func deriveKey(enc *Encryption) ([]byte, error) {
+	keyMu.Lock()
+	defer keyMu.Unlock()
+	if passphrase == "" {
+		return nil, ErrLocked
+	}
+	// A file can't weaken the key derivation below what it is written with
+	if enc.Iterations < defaultKDFIterations {
+		return nil, fmt.Errorf("encryption iterations %d are below the minimum of %d", enc.Iterations, defaultKDFIterations)
+	}
+	if key, ok := keyCache[enc.Salt]; ok {
+		return key, nil
+	}
+	salt, err := base64.StdEncoding.DecodeString(enc.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid salt: %w", err)
+	}
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, enc.Iterations, 32)
+	if err != nil {
+		return nil, err
+	}
+	keyCache[enc.Salt] = key
+	return key, nil
+}
+
+func newEncryption() (*Encryption, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	return &Encryption{Salt: base64.StdEncoding.EncodeToString(salt), Iterations: defaultKDFIterations}, nil
+}
+
+// IsEncrypted reports whether a stored value is an encrypted secret
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, encPrefix) || strings.HasPrefix(value, encPrefixV1)
+}
+
+// encryptValue encrypts the value of the variable name
+func encryptValue(key []byte, name, plaintext string) (string, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), []byte(name))
+	return encPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// decryptValue decrypts the value of the variable name
+func decryptValue(key []byte, name, value string) (string, error) {
+	additional := []byte(name)
+	encoded, found := strings.CutPrefix(value, encPrefix)
+	if !found {
+		encoded, additional = strings.TrimPrefix(value, encPrefixV1), nil
+	}
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("invalid encrypted value: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < gcm.NonceSize() {
+		return "", errors.New("invalid encrypted value")
+	}
+	plain, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], additional)
+	if err != nil {
+		return "", ErrBadPassphrase
+	}
+	return string(plain), nil
+}
+
+// IsSecret reports whether the variable is flagged as secret
+func (e Environment) IsSecret(name string) bool {
+	for _, s := range e.Secrets {
+		if s == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Locked reports whether some secrets could not be decrypted (no or wrong passphrase).
+// Locked variables are left out of Variables.
+func (e Environment) Locked() bool {
+	return len(e.locked) > 0
+}
+
+// LockError returns why the secrets are locked: ErrLocked without a
+// passphrase, ErrBadPassphrase when it doesn't open them
+func (e Environment) LockError() error {
+	return e.lockErr
+}
+
+// LockedNames returns the names of the variables that are still encrypted
+func (e Environment) LockedNames() []string {
+	names := make([]string, 0, len(e.locked))
+	for name := range e.locked {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SecretValues returns the secret variables with their values, for masking
+func (e Environment) SecretValues() map[string]string {
+	secrets := make(map[string]string)
+	for _, name := range e.Secrets {
+		if v, ok := e.Variables[name]; ok && v != "" {
+			secrets[name] = v
+		}
+	}
+	return secrets
+}
+
+// MaskSecrets returns a copy of vars with the values of secret variables replaced by Mask
+func MaskSecrets(vars map[string]string, secrets []string) map[string]string {
+	masked := MergeVariables(vars)
+	for _, name := range secrets {
+		if _, ok := masked[name]; ok {
+			masked[name] = Mask
+		}
+	}
+	return masked
+}
+
+// RedactSecretValues replaces every occurrence of a secret value in text with its
+// {{name}} placeholder, so the text can be shared without leaking the value
+func RedactSecretValues(text string, secrets map[string]string) string {
+	// Longest values first so a secret containing another is replaced whole
+	names := make([]string, 0, len(secrets))
+	for name := range secrets {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return len(secrets[names[i]]) > len(secrets[names[j]]) })
+
+	for _, name := range names {
+		if v := secrets[name]; v != "" {
+			text = strings.ReplaceAll(text, v, "{{"+name+"}}")
+		}
+	}
+	return text
+}
+
+// sealEnvironment returns the environment as it is written to disk: secret values are
+// encrypted when a passphrase is set, and locked values are written back unchanged.
+func sealEnvironment(env Environment) (Environment, error) {
+	sealed := env
+	sealed.Variables = MergeVariables(env.Variables)
+	for name, v := range env.locked {
+		if _, edited := sealed.Variables[name]; !edited {
+			sealed.Variables[name] = v
+		}
+	}
+
+	if !HasPassphrase() {
+		if env.Encryption != nil {
+			for _, name := range env.Secrets {
+				if v, ok := sealed.Variables[name]; ok && !IsEncrypted(v) {
+					return env, ErrLocked
+				}
+			}
+		}
+		return sealed, nil
+	}
+
+	if len(env.Secrets) == 0 {
+		return sealed, nil
+	}
+	if sealed.Encryption == nil {
+		enc, err := newEncryption()
+		if err != nil {
+			return env, err
+		}
+		sealed.Encryption = enc
+	}
+	key, err := deriveKey(sealed.Encryption)
+	if err != nil {
+		return env, err
+	}
+	for _, name := range env.Secrets {
+		v, ok := sealed.Variables[name]
+		if !ok || IsEncrypted(v) {
+			continue
+		}
+		// The session passphrase did not open this file; don't mix keys
+		if env.Locked() {
+			return env, ErrLocked
+		}
+		ct, err := encryptValue(key, name, v)
+		if err != nil {
+			return env, err
+		}
+		sealed.Variables[name] = ct
+	}
+	return sealed, nil
+}
+
+// openEnvironment decrypts the encrypted values of a loaded environment. Values that
+// cannot be decrypted are moved out of Variables and kept so they survive a save.
+func openEnvironment(env *Environment) error {
+	var encrypted []string
+	for name, v := range env.Variables {
+		if IsEncrypted(v) {
+			encrypted = append(encrypted, name)
+		}
+	}
+	if len(encrypted) == 0 {
+		return nil
+	}
+
+	lock := func(cause error) error {
+		env.locked = make(map[string]string, len(encrypted))
+		for _, name := range encrypted {
+			env.locked[name] = env.Variables[name]
+			delete(env.Variables, name)
+		}
+		env.lockErr = cause
+		return cause
+	}
+
+	if env.Encryption == nil {
+		return lock(errors.New("encrypted values without encryption settings"))
+	}
+	key, err := deriveKey(env.Encryption)
+	if err != nil {
+		return lock(err)
+	}
+
+	plain := make(map[string]string, len(encrypted))
+	for _, name := range encrypted {
+		v, err := decryptValue(key, name, env.Variables[name])
+		if err != nil {
+			return lock(err)
+		}
+		plain[name] = v
+	}
+	for name, v := range plain {
+		env.Variables[name] = v
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package storage
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSecrets_EncryptedRoundTrip(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	if err := os.MkdirAll(filepath.Join(home, ".tapi", "environments"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { SetPassphrase("") })
+
+	SetPassphrase("correct horse")
+	env := Environment{
+		Name:      "prod",
+		Variables: map[string]string{"host": "api.example.com", "token": "s3cr3t"},
+		Secrets:   []string{"token"},
+	}
+	if err := SaveEnvironment(env); err != nil {
+		t.Fatalf("SaveEnvironment failed: %v", err)
+	}
+
+	file := filepath.Join(home, ".tapi", "environments", "prod.yaml")
+	data, _ := os.ReadFile(file)
+	if strings.Contains(string(data), "s3cr3t") || !strings.Contains(string(data), encPrefix) {
+		t.Errorf("Expected secret to be encrypted on disk, got:\n%s", data)
+	}
+	if info, _ := os.Stat(file); info.Mode().Perm() != 0o600 {
+		t.Errorf("Expected mode 0600, got %v", info.Mode().Perm())
+	}
+
+	envs, _ := LoadEnvironments()
+	if len(envs) != 1 || envs[0].Variables["token"] != "s3cr3t" || envs[0].Locked() {
+		t.Fatalf("Expected decrypted secret, got %+v", envs)
+	}
+
+	// Without the passphrase the secret stays locked but survives a save
+	SetPassphrase("")
+	envs, _ = LoadEnvironments()
+	locked := envs[0]
+	if !locked.Locked() || locked.Variables["token"] != "" || locked.Variables["host"] != "api.example.com" {
+		t.Fatalf("Expected locked environment, got %+v", locked)
+	}
+	locked.Variables["host"] = "api2.example.com"
+	if err := SaveEnvironment(locked); err != nil {
+		t.Fatalf("Saving a locked environment failed: %v", err)
+	}
+	locked.Variables["token"] = "new"
+	if err := SaveEnvironment(locked); err != ErrLocked {
+		t.Errorf("Expected ErrLocked when adding a secret without passphrase, got %v", err)
+	}
+
+	SetPassphrase("wrong")
+	if envs, _ = LoadEnvironments(); !envs[0].Locked() {
+		t.Error("Expected wrong passphrase to leave the environment locked")
+	}
+
+	SetPassphrase("correct horse")
+	envs, _ = LoadEnvironments()
+	if envs[0].Variables["token"] != "s3cr3t" || envs[0].Variables["host"] != "api2.example.com" {
+		t.Errorf("Expected preserved secret and edited host, got %+v", envs[0].Variables)
+	}
+}
+
+func TestSecrets_BoundToVariable(t *testing.T) {
+	t.Cleanup(func() { SetPassphrase("") })
+	SetPassphrase("correct horse")
+	enc, err := newEncryption()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := deriveKey(enc)
+	if err != nil {
+		t.Fatalf("deriveKey failed: %v", err)
+	}
+
+	token, _ := encryptValue(key, "token", "s3cr3t")
+	password, _ := encryptValue(key, "password", "hunter2")
+	env := Environment{
+		// The values were swapped in the file
+		Variables:  map[string]string{"token": password, "password": token},
+		Encryption: enc,
+	}
+	if err := openEnvironment(&env); err != ErrBadPassphrase || !env.Locked() || env.LockError() != ErrBadPassphrase {
+		t.Errorf("Expected values moved to another variable not to decrypt, got %v (%+v)", err, env.Variables)
+	}
+
+	// Values written before the name was bound still open
+	legacy := Environment{Variables: map[string]string{"token": encryptV1(t, key, "s3cr3t")}, Encryption: enc}
+	if err := openEnvironment(&legacy); err != nil || legacy.Variables["token"] != "s3cr3t" {
+		t.Errorf("Expected the v1 value to decrypt, got %q (%v)", legacy.Variables["token"], err)
+	}
+}
+
+// encryptV1 encrypts a value as it was before v2, without the variable name
+func encryptV1(t *testing.T, key []byte, plaintext string) string {
+	block, _ := aes.NewCipher(key)
+	gcm, _ := cipher.NewGCM(block)
+	nonce := make([]byte, gcm.NonceSize())
+	rand.Read(nonce)
+	return encPrefixV1 + base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, []byte(plaintext), nil))
+}
+
+func TestSecrets_MinimumIterations(t *testing.T) {
+	t.Cleanup(func() { SetPassphrase("") })
+	SetPassphrase("correct horse")
+	enc, _ := newEncryption()
+	enc.Iterations = 1
+	if _, err := deriveKey(enc); err == nil || !strings.Contains(err.Error(), "below the minimum") {
+		t.Errorf("Expected 1 iteration to be rejected, got %v", err)
+	}
+	env := Environment{Variables: map[string]string{"token": encPrefix + "AAAA"}, Encryption: enc}
+	if err := openEnvironment(&env); err == nil || !env.Locked() {
+		t.Errorf("Expected the environment to stay locked, got %v", err)
+	}
+}
+
+func TestSecrets_PlaintextWithoutPassphrase(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	if err := os.MkdirAll(filepath.Join(home, ".tapi", "environments"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	env := Environment{Name: "dev", Variables: map[string]string{"token": "t"}, Secrets: []string{"token"}}
+	if err := SaveEnvironment(env); err != nil {
+		t.Fatalf("SaveEnvironment failed: %v", err)
+	}
+	envs, _ := LoadEnvironments()
+	if envs[0].Variables["token"] != "t" || !envs[0].IsSecret("token") || envs[0].Encryption != nil {
+		t.Errorf("Expected plaintext secret flag round trip, got %+v", envs[0])
+	}
+}
+
+func TestMaskAndRedactSecrets(t *testing.T) {
+	vars := map[string]string{"token": "abc123", "host": "h"}
+	masked := MaskSecrets(vars, []string{"token"})
+	if masked["token"] != Mask || masked["host"] != "h" || vars["token"] != "abc123" {
+		t.Errorf("Unexpected masking: %v (original %v)", masked, vars)
+	}
+
+	got := RedactSecretValues("curl -H 'Authorization: Bearer abc123'", map[string]string{"token": "abc123"})
+	if got != "curl -H 'Authorization: Bearer {{token}}'" {
+		t.Errorf("Unexpected redaction: %q", got)
+	}
+}
@@ -1,16 +1,23 @@
 package storage
 
 import (
+	"fmt"
 	"regexp"
 	"strings"
 )
 
-var varRegex = regexp.MustCompile(`\{\{(.*?)\}\}`)
+// varRegex matches innermost tags, so nested tags like {{$base64 {{user}}}} resolve inside out
+var varRegex = regexp.MustCompile(`\{\{([^{}]*?)\}\}`)
 
 // Substitute replaces variables in the input string with values from the environment.
+// Tags starting with $ call a built-in function (see TemplateFunctions); a
+// call that fails is left as is, and CheckResolved reports its error.
 // It supports recursive substitution up to a certain depth.
 func Substitute(input string, env map[string]string) string {
-	if len(env) == 0 || !strings.Contains(input, "{{") {
+	if !strings.Contains(input, "{{") {
+		return input
+	}
+	if len(env) == 0 && !strings.Contains(input, "{{$") && !strings.Contains(input, "{{ $") {
 		return input
 	}
 
@@ -29,9 +36,151 @@ func Substitute(input string, env map[string]string) string {
 			if val, ok := env[key]; ok {
 				return val
 			}
+			if strings.HasPrefix(key, "$") {
+				if val, ok, err := EvalFunction(key); ok && err == nil {
+					return val
+				}
+			}
 			return match // Keep original tag if not found
 		})
 	}
 
 	return result
 }
+
+// MergeVariables combines variable maps into a new map. Later maps win on conflicts.
+func MergeVariables(layers ...map[string]string) map[string]string {
+	merged := make(map[string]string)
+	for _, layer := range layers {
+		for k, v := range layer {
+			merged[k] = v
+		}
+	}
+	return merged
+}
+
+// SubstituteRequest returns a copy of req with variables substituted in the URL,
+// query params, body, headers and auth. The original request is left untouched.
+func SubstituteRequest(req Request, env map[string]string) Request {
+	req.URL = Substitute(req.URL, env)
+	req.Body = Substitute(req.Body, env)
+
+	req.Query = substituteParams(req.Query, env)
+	req.Headers = substituteParams(req.Headers, env)
+
+	if req.Auth != nil {
+		auth := *req.Auth
+		for _, f := range auth.fields() {
+			*f = Substitute(*f, env)
+		}
+		req.Auth = &auth
+	}
+
+	if req.Assertions != nil {
+		assertions := make([]Assertion, len(req.Assertions))
+		for i, a := range req.Assertions {
+			a.Value = Substitute(a.Value, env)
+			assertions[i] = a
+		}
+		req.Assertions = assertions
+	}
+
+	return req
+}
+
+func substituteParams(params Params, env map[string]string) Params {
+	if params == nil {
+		return nil
+	}
+	substituted := make(Params, len(params))
+	for i, p := range params {
+		p.Key = Substitute(p.Key, env)
+		p.Value = Substitute(p.Value, env)
+		substituted[i] = p
+	}
+	return substituted
+}
+
+// UnresolvedError lists the variables still referenced after substitution
+type UnresolvedError struct {
+	Missing []string // no value available
+	Cyclic  []string // defined, but still unresolved after the recursion limit
+	Failed  []error  // built-in function calls that failed, e.g. {{$randomInt 5 1}}
+}
+
+func (e *UnresolvedError) Error() string {
+	var parts []string
+	if len(e.Missing) > 0 {
+		parts = append(parts, "unresolved variables: "+strings.Join(e.Missing, ", "))
+	}
+	if len(e.Cyclic) > 0 {
+		parts = append(parts, "cyclic variables: "+strings.Join(e.Cyclic, ", "))
+	}
+	for _, err := range e.Failed {
+		parts = append(parts, err.Error())
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Unwrap returns the errors of the failed function calls
+func (e *UnresolvedError) Unwrap() []error {
+	return e.Failed
+}
+
+// FindUnresolved returns the names of {{ }} tags left in an already substituted
+// text, in order of appearance and without duplicates
+func FindUnresolved(text string) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, m := range varRegex.FindAllStringSubmatch(text, -1) {
+		name := strings.TrimSpace(m[1])
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// CheckResolved reports variables that an already substituted request still
+// references in its URL, query params, headers, body or auth. It returns nil or an *UnresolvedError.
+func CheckResolved(req Request, env map[string]string) error {
+	texts := []string{req.URL, req.Body}
+	// Disabled entries are not sent, so they may reference anything
+	for _, p := range append(req.Query.Enabled(), req.Headers.Enabled()...) {
+		texts = append(texts, p.Key, p.Value)
+	}
+	if req.Auth != nil {
+		texts = append(texts, req.Auth.Values()...)
+	}
+
+	uerr := &UnresolvedError{}
+	seen := make(map[string]bool)
+	for _, text := range texts {
+		for _, name := range FindUnresolved(text) {
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			if _, ok := env[name]; ok {
+				uerr.Cyclic = append(uerr.Cyclic, name)
+			} else if _, ok, err := EvalFunction(name); ok && err != nil {
+				// Substitute leaves a failed call in place, evaluating it again gives its error
+				uerr.Failed = append(uerr.Failed, fmt.Errorf("{{%s}}: %w", name, err))
+			} else {
+				uerr.Missing = append(uerr.Missing, name)
+			}
+		}
+	}
+
+	if len(uerr.Missing) == 0 && len(uerr.Cyclic) == 0 && len(uerr.Failed) == 0 {
+		return nil
+	}
+	return uerr
+}
+
+// ResolveRequest substitutes variables in req and checks that none are left over
+func ResolveRequest(req Request, env map[string]string) (Request, error) {
+	resolved := SubstituteRequest(req, env)
+	return resolved, CheckResolved(resolved, env)
+}
@@ -1,6 +1,8 @@
 package storage
 
 import (
+	"errors"
+	"strings"
 	"testing"
 )
 
@@ -53,6 +55,30 @@ func TestSubstitute(t *testing.T) {
 			env:      nil,
 			expected: "http://{{host}}",
 		},
+		{
+			name:     "Function with nested variable",
+			input:    "Basic {{$base64 {{user}}:{{pass}}}}",
+			env:      map[string]string{"user": "ada", "pass": "pw"},
+			expected: "Basic YWRhOnB3",
+		},
+		{
+			name:     "Function without env",
+			input:    "q={{$urlencode a b&c}}",
+			env:      nil,
+			expected: "q=a+b%26c",
+		},
+		{
+			name:     "Unknown function",
+			input:    "{{$nope}}",
+			env:      map[string]string{"host": "localhost"},
+			expected: "{{$nope}}",
+		},
+		{
+			name:     "JSON body braces",
+			input:    `{"user": {"id": "{{id}}"}}`,
+			env:      map[string]string{"id": "7"},
+			expected: `{"user": {"id": "7"}}`,
+		},
 	}
 
 	for _, tt := range tests {
@@ -64,3 +90,94 @@ func TestSubstitute(t *testing.T) {
 		})
 	}
 }
+
+func TestSubstituteRequest(t *testing.T) {
+	req := Request{
+		URL:     "{{host}}/users",
+		Body:    `{"id": "{{id}}"}`,
+		Headers: Params{{Key: "Authorization", Value: "Bearer {{token}}", Enabled: true}},
+		Auth:    &Auth{Username: "{{user}}", Password: "pw"},
+	}
+	env := map[string]string{"host": "http://localhost", "id": "7", "token": "abc", "user": "admin"}
+
+	got := SubstituteRequest(req, env)
+
+	if got.URL != "http://localhost/users" || got.Body != `{"id": "7"}` {
+		t.Errorf("Unexpected URL/body: %q %q", got.URL, got.Body)
+	}
+	if got.Headers.Get("Authorization") != "Bearer abc" || got.Auth.Username != "admin" {
+		t.Errorf("Unexpected headers/auth: %+v %+v", got.Headers, got.Auth)
+	}
+	// The original request must not be modified
+	if req.Headers.Get("Authorization") != "Bearer {{token}}" || req.Auth.Username != "{{user}}" {
+		t.Errorf("Original request was mutated: %+v %+v", req.Headers, req.Auth)
+	}
+}
+
+func TestResolveRequest(t *testing.T) {
+	req := Request{
+		URL:     "{{host}}/users/{{id}}",
+		Body:    `{"a": "{{loop}}", "b": "{{missing}}"}`,
+		Headers: Params{{Key: "Authorization", Value: "Bearer {{token}}", Enabled: true}},
+	}
+	env := map[string]string{"host": "http://localhost", "loop": "{{loop2}}", "loop2": "{{loop}}"}
+
+	_, err := ResolveRequest(req, env)
+	var uerr *UnresolvedError
+	if !errors.As(err, &uerr) {
+		t.Fatalf("Expected *UnresolvedError, got %v", err)
+	}
+	if strings.Join(uerr.Missing, ",") != "id,missing,token" {
+		t.Errorf("Unexpected missing variables: %v", uerr.Missing)
+	}
+	if len(uerr.Cyclic) != 1 {
+		t.Errorf("Expected one cyclic variable, got %v", uerr.Cyclic)
+	}
+	if !strings.Contains(err.Error(), "unresolved variables: id, missing, token") {
+		t.Errorf("Unexpected message: %q", err.Error())
+	}
+
+	env["id"], env["token"], env["missing"], env["loop"] = "1", "t", "m", "x"
+	if _, err := ResolveRequest(req, env); err != nil {
+		t.Errorf("Expected fully resolved request, got %v", err)
+	}
+}
+
+func TestResolveRequest_FunctionError(t *testing.T) {
+	req := Request{URL: "/items?n={{$randomInt 5 1}}&id={{$uuid}}", Body: "{{missing}}"}
+	_, err := ResolveRequest(req, nil)
+	var uerr *UnresolvedError
+	if !errors.As(err, &uerr) {
+		t.Fatalf("Expected *UnresolvedError, got %v", err)
+	}
+	if len(uerr.Failed) != 1 || !strings.Contains(uerr.Failed[0].Error(), "max is below min") {
+		t.Errorf("Expected the $randomInt error, got %v", uerr.Failed)
+	}
+	if strings.Join(uerr.Missing, ",") != "missing" {
+		t.Errorf("Expected only the variable to be missing, got %v", uerr.Missing)
+	}
+	want := "unresolved variables: missing; {{$randomInt 5 1}}: $randomInt: max is below min"
+	if err.Error() != want {
+		t.Errorf("Expected %q, got %q", want, err.Error())
+	}
+}
+
+func TestSubstituteRequest_AuthTypes(t *testing.T) {
+	env := map[string]string{"token": "abc", "keyName": "X-Api-Key", "key": "k1", "region": "eu-west-1"}
+	for _, auth := range []Auth{
+		{Type: AuthBearer, Token: "{{token}}"},
+		{Type: AuthAPIKey, Key: "{{keyName}}", Value: "{{key}}"},
+		{Type: AuthAWS, AccessKey: "{{keyName}}", SecretKey: "{{key}}", Region: "{{region}}"},
+	} {
+		got := SubstituteRequest(Request{Auth: &auth}, env)
+		values := strings.Join(got.Auth.Values(), "")
+		if values == "" || strings.Contains(values, "{{") {
+			t.Errorf("Expected %s auth to be substituted, got %+v", auth.Type, got.Auth)
+		}
+	}
+
+	err := CheckResolved(Request{Auth: &Auth{Type: AuthBearer, Token: "{{missing}}"}}, env)
+	if err == nil || !strings.Contains(err.Error(), "missing") {
+		t.Errorf("Expected the bearer token to be checked, got %v", err)
+	}
+}
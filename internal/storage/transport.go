@@ -0,0 +1,132 @@
+package storage
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// HTTP versions a transport can be limited to
+const (
+	HTTP1 = "1.1"
+	HTTP2 = "2" // prior knowledge (h2c) for http:// URLs
+)
+
+// UnixScheme starts the URLs of requests sent over a Unix domain socket. The
+// socket path ends at the first colon, the request path follows:
+// unix:///var/run/docker.sock:/containers/json
+const UnixScheme = "unix://"
+
+// Transport holds the connection settings: proxy, TLS and HTTP version. They
+// can be set in config.yaml, on a collection and on an environment; see
+// MergeTransports for how they combine.
+type Transport struct {
+	Proxy       string       `yaml:"proxy,omitempty"`                // http://, https:// or socks5:// URL; HTTP(S)_PROXY when empty
+	NoProxy     []string     `yaml:"no_proxy,omitempty"`             // hosts, domains and CIDRs reached directly
+	CABundle    string       `yaml:"ca_bundle,omitempty"`            // PEM file trusted on top of the system roots
+	Insecure    *bool        `yaml:"insecure_skip_verify,omitempty"` // don't verify server certificates
+	MinTLS      string       `yaml:"min_tls_version,omitempty"`      // 1.0, 1.1, 1.2 or 1.3
+	HTTPVersion string       `yaml:"http_version,omitempty"`         // 1.1 or 2; negotiated when empty
+	ClientCerts []ClientCert `yaml:"client_certs,omitempty"`         // mTLS, chosen by host
+	Resolve     []string     `yaml:"resolve,omitempty"`              // host:port:addr, connects to addr instead of looking up host
+}
+
+// ClientCert is a certificate presented to the hosts it is set for
+type ClientCert struct {
+	Host string `yaml:"host"` // host name, or *.example.com for its subdomains
+	Cert string `yaml:"cert"` // PEM file
+	Key  string `yaml:"key"`  // PEM file
+}
+
+// Matches reports whether the certificate is presented to host
+func (c ClientCert) Matches(host string) bool {
+	host = strings.ToLower(host)
+	pattern := strings.ToLower(c.Host)
+	if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+		return strings.HasSuffix(host, "."+suffix)
+	}
+	return host == pattern
+}
+
+// InsecureSkipVerify reports whether server certificates are not verified
+func (t Transport) InsecureSkipVerify() bool {
+	return t.Insecure != nil && *t.Insecure
+}
+
+// IsZero reports whether nothing is set, in which case the default transport
+// is used
+func (t Transport) IsZero() bool {
+	return t.Proxy == "" && len(t.NoProxy) == 0 && t.CABundle == "" && t.Insecure == nil &&
+		t.MinTLS == "" && t.HTTPVersion == "" && len(t.ClientCerts) == 0 && len(t.Resolve) == 0
+}
+
+// MergeTransports combines settings from the least to the most specific
+// (config, collection, environment). A setting replaces the ones before it;
+// client certificates and host overrides add up, the most specific being
+// tried first.
+func MergeTransports(layers ...Transport) Transport {
+	var merged Transport
+	for _, t := range layers {
+		if t.Proxy != "" {
+			merged.Proxy = t.Proxy
+		}
+		if len(t.NoProxy) > 0 {
+			merged.NoProxy = t.NoProxy
+		}
+		if t.CABundle != "" {
+			merged.CABundle = t.CABundle
+		}
+		if t.Insecure != nil {
+			merged.Insecure = t.Insecure
+		}
+		if t.MinTLS != "" {
+			merged.MinTLS = t.MinTLS
+		}
+		if t.HTTPVersion != "" {
+			merged.HTTPVersion = t.HTTPVersion
+		}
+		if len(t.ClientCerts) > 0 {
+			merged.ClientCerts = append(append([]ClientCert(nil), t.ClientCerts...), merged.ClientCerts...)
+		}
+		if len(t.Resolve) > 0 {
+			merged.Resolve = append(append([]string(nil), t.Resolve...), merged.Resolve...)
+		}
+	}
+	return merged
+}
+
+// ExpandHome replaces a leading ~ with the user's home directory, for the
+// file paths in settings
+func ExpandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, strings.TrimPrefix(path, "~"))
+		}
+	}
+	return path
+}
+
+// SplitUnixURL splits a unix:// URL into the socket path and the same request
+// as an http://localhost URL. ok is false for other URLs.
+func SplitUnixURL(raw string) (socket, httpURL string, ok bool) {
+	rest, ok := strings.CutPrefix(raw, UnixScheme)
+	if !ok {
+		return "", "", false
+	}
+	socket, requestURI, _ := strings.Cut(rest, ":")
+	if !strings.HasPrefix(requestURI, "/") {
+		requestURI = "/" + requestURI
+	}
+	return socket, "http://localhost" + requestURI, socket != ""
+}
+
+// JoinUnixURL is the reverse of SplitUnixURL: the unix:// URL sending the
+// request of httpURL over socket
+func JoinUnixURL(socket, httpURL string) (string, error) {
+	u, err := url.Parse(httpURL)
+	if err != nil {
+		return "", err
+	}
+	return UnixScheme + socket + ":" + u.RequestURI(), nil
+}
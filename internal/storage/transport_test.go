@@ -0,0 +1,73 @@
+package storage
+
+import "testing"
+
+func TestMergeTransports(t *testing.T) {
+	off, on := false, true
+	config := Transport{Proxy: "http://proxy:3128", MinTLS: "1.2", Insecure: &on,
+		ClientCerts: []ClientCert{{Host: "*.example.com", Cert: "wildcard.pem", Key: "wildcard.key"}}}
+	collection := Transport{NoProxy: []string{"localhost"}, HTTPVersion: HTTP1, Resolve: []string{"api.example.com:443:10.0.0.1"}}
+	env := Transport{Proxy: "socks5://127.0.0.1:1080", Insecure: &off, Resolve: []string{"api.example.com:443:127.0.0.1"},
+		ClientCerts: []ClientCert{{Host: "api.example.com", Cert: "api.pem", Key: "api.key"}}}
+
+	got := MergeTransports(config, collection, env)
+	if got.Proxy != env.Proxy {
+		t.Errorf("Expected the environment proxy, got %q", got.Proxy)
+	}
+	if len(got.NoProxy) != 1 || got.HTTPVersion != HTTP1 || got.MinTLS != "1.2" {
+		t.Errorf("Expected the settings nobody overrides to be kept, got %+v", got)
+	}
+	if got.InsecureSkipVerify() {
+		t.Error("Expected the environment to turn verification back on")
+	}
+	if len(got.ClientCerts) != 2 || got.ClientCerts[0].Host != "api.example.com" {
+		t.Errorf("Expected the environment certificate first, got %+v", got.ClientCerts)
+	}
+	if len(got.Resolve) != 2 || got.Resolve[0] != env.Resolve[0] {
+		t.Errorf("Expected the environment override first, got %v", got.Resolve)
+	}
+	if !MergeTransports().IsZero() {
+		t.Error("Expected no settings from no layers")
+	}
+}
+
+func TestClientCert_Matches(t *testing.T) {
+	tests := []struct {
+		pattern string
+		host    string
+		want    bool
+	}{
+		{"api.example.com", "api.example.com", true},
+		{"api.example.com", "API.example.com", true},
+		{"api.example.com", "www.example.com", false},
+		{"*.example.com", "api.example.com", true},
+		{"*.example.com", "example.com", false},
+		{"*.example.com", "api.example.org", false},
+	}
+	for _, tt := range tests {
+		if got := (ClientCert{Host: tt.pattern}).Matches(tt.host); got != tt.want {
+			t.Errorf("%q matching %q: expected %v, got %v", tt.pattern, tt.host, tt.want, got)
+		}
+	}
+}
+
+func TestSplitUnixURL(t *testing.T) {
+	tests := []struct {
+		raw, socket, httpURL string
+		ok                   bool
+	}{
+		{"unix:///var/run/docker.sock:/containers/json?all=1", "/var/run/docker.sock", "http://localhost/containers/json?all=1", true},
+		{"unix:///var/run/docker.sock", "/var/run/docker.sock", "http://localhost/", true},
+		{"unix:///tmp/app.sock:health", "/tmp/app.sock", "http://localhost/health", true},
+		{"http://localhost/", "", "", false},
+	}
+	for _, tt := range tests {
+		socket, httpURL, ok := SplitUnixURL(tt.raw)
+		if socket != tt.socket || httpURL != tt.httpURL || ok != tt.ok {
+			t.Errorf("SplitUnixURL(%q): expected %q %q %v, got %q %q %v", tt.raw, tt.socket, tt.httpURL, tt.ok, socket, httpURL, ok)
+		}
+	}
+	if got, _ := JoinUnixURL("/tmp/app.sock", "http://localhost/health?deep=1"); got != "unix:///tmp/app.sock:/health?deep=1" {
+		t.Errorf("Expected the socket and request path joined, got %q", got)
+	}
+}
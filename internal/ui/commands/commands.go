@@ -3,107 +3,146 @@ package commands
 import (
 	uimsg "github.com/styltsou/tapi/internal/ui/msg"
 
+	"context"
+	"errors"
 	"fmt"
 
 	"os"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/styltsou/tapi/internal/cookies"
 	"github.com/styltsou/tapi/internal/http"
+	"github.com/styltsou/tapi/internal/oauth"
 	"github.com/styltsou/tapi/internal/storage"
 )
 
-func DeleteRequestCmd(collectionName, requestName string) tea.Cmd {
+func DeleteRequestCmd(collectionID, requestID string) tea.Cmd {
 	return func() tea.Msg {
 		collections, _, _ := storage.LoadCollections()
-		for i, col := range collections {
-			if col.Name == collectionName {
-				// Filter out the request
-				newRequests := []storage.Request{}
-				for _, r := range col.Requests {
-					if r.Name != requestName {
-						newRequests = append(newRequests, r)
-					}
-				}
-				collections[i].Requests = newRequests
-				if err := storage.SaveCollection(collections[i]); err != nil {
-					return uimsg.ErrMsg{Err: err}
-				}
-				return uimsg.RequestDeletedMsg{
-					CollectionName: collectionName,
-					RequestName:    requestName,
-				}
-			}
+		i := storage.CollectionIndex(collections, collectionID)
+		if i == -1 {
+			return uimsg.ErrMsg{Err: fmt.Errorf("collection not found")}
+		}
+		if _, ok := collections[i].RemoveRequest(requestID); !ok {
+			return uimsg.ErrMsg{Err: fmt.Errorf("request not found")}
+		}
+		if err := storage.SaveCollection(&collections[i]); err != nil {
+			return uimsg.ErrMsg{Err: err}
+		}
+		return uimsg.RequestDeletedMsg{
+			CollectionID: collectionID,
+			RequestID:    requestID,
 		}
-		return uimsg.ErrMsg{Err: fmt.Errorf("collection %q not found", collectionName)}
 	}
 }
 
-func DeleteCollectionCmd(name string) tea.Cmd {
+func DeleteCollectionCmd(collectionID string) tea.Cmd {
 	return func() tea.Msg {
-		if err := storage.DeleteCollection(name); err != nil {
+		collections, _, _ := storage.LoadCollections()
+		i := storage.CollectionIndex(collections, collectionID)
+		if i == -1 {
+			return uimsg.ErrMsg{Err: fmt.Errorf("collection not found")}
+		}
+		if err := storage.DeleteCollection(collections[i].Name); err != nil {
 			return uimsg.ErrMsg{Err: err}
 		}
-		return uimsg.CollectionDeletedMsg{Name: name}
+		return uimsg.CollectionDeletedMsg{CollectionID: collectionID}
 	}
 }
 
-func RenameCollectionCmd(oldName, newName string) tea.Cmd {
+func RenameCollectionCmd(collectionID, newName string) tea.Cmd {
 	return func() tea.Msg {
 		collections, _, _ := storage.LoadCollections()
-		for _, col := range collections {
-			if col.Name == oldName {
-				// 1. Delete old
-				if err := storage.DeleteCollection(oldName); err != nil {
-					return uimsg.ErrMsg{Err: err}
-				}
-				// 2. Save as new
-				col.Name = newName
-				if err := storage.SaveCollection(col); err != nil {
-					return uimsg.ErrMsg{Err: err}
-				}
-				return uimsg.CollectionRenamedMsg{OldName: oldName, NewName: newName}
-			}
+		i := storage.CollectionIndex(collections, collectionID)
+		if i == -1 {
+			return uimsg.ErrMsg{Err: fmt.Errorf("collection not found")}
+		}
+		col := collections[i]
+		// 1. Delete old
+		if err := storage.DeleteCollection(col.Name); err != nil {
+			return uimsg.ErrMsg{Err: err}
 		}
-		return uimsg.ErrMsg{Err: fmt.Errorf("collection not found")}
+		// 2. Save as new
+		col.Name = newName
+		if err := storage.SaveCollection(&col); err != nil {
+			return uimsg.ErrMsg{Err: err}
+		}
+		return uimsg.CollectionRenamedMsg{CollectionID: collectionID, NewName: newName}
 	}
 }
 
-func DuplicateRequestCmd(collectionName, requestName string) tea.Cmd {
+func DuplicateRequestCmd(collectionID, requestID string) tea.Cmd {
 	return func() tea.Msg {
 		collections, _, _ := storage.LoadCollections()
-		for i, col := range collections {
-			if col.Name == collectionName {
-				for _, r := range col.Requests {
-					if r.Name == requestName {
-						dup := storage.Request{
-							Name:    r.Name + " (copy)",
-							Method:  r.Method,
-							URL:     r.URL,
-							Body:    r.Body,
-							Headers: make(map[string]string),
-						}
-						for k, v := range r.Headers {
-							dup.Headers[k] = v
-						}
-						if r.Auth != nil {
-							dup.Auth = &storage.BasicAuth{
-								Username: r.Auth.Username,
-								Password: r.Auth.Password,
-							}
-						}
-						collections[i].Requests = append(collections[i].Requests, dup)
-						if err := storage.SaveCollection(collections[i]); err != nil {
-							return uimsg.ErrMsg{Err: err}
-						}
-						return uimsg.RequestDuplicatedMsg{
-							CollectionName: collectionName,
-							RequestName:    r.Name + " (copy)",
-						}
-					}
-				}
-			}
+		i := storage.CollectionIndex(collections, collectionID)
+		if i == -1 {
+			return uimsg.ErrMsg{Err: fmt.Errorf("collection not found")}
+		}
+		entry, ok := collections[i].Entry(requestID)
+		if !ok {
+			return uimsg.ErrMsg{Err: fmt.Errorf("request not found")}
+		}
+		r := entry.Request
+		dup := r
+		dup.ID = storage.NewID()
+		dup.Name = r.Name + " (copy)"
+		dup.Query = r.Query.Clone()
+		dup.Headers = r.Headers.Clone()
+		if r.Auth != nil {
+			auth := *r.Auth
+			dup.Auth = &auth
+		}
+		dup.Variables = storage.MergeVariables(r.Variables)
+		dup.Assertions = append([]storage.Assertion(nil), r.Assertions...)
+		dup.Extract = append([]storage.Extraction(nil), r.Extract...)
+		// The copy goes next to the original
+		if err := collections[i].AddRequest(entry.FolderID, dup); err != nil {
+			return uimsg.ErrMsg{Err: err}
+		}
+		if err := storage.SaveCollection(&collections[i]); err != nil {
+			return uimsg.ErrMsg{Err: err}
+		}
+		return uimsg.RequestDuplicatedMsg{
+			CollectionID: collectionID,
+			Request:      dup,
 		}
-		return uimsg.ErrMsg{Err: fmt.Errorf("request not found")}
+	}
+}
+
+// MoveRequestCmd moves a request into the folder at the given path ("Auth/Tokens"),
+// creating the folders that don't exist yet. An empty path moves it to the root.
+func MoveRequestCmd(collectionID, requestID, folderPath string) tea.Cmd {
+	return func() tea.Msg {
+		collections, _, _ := storage.LoadCollections()
+		i := storage.CollectionIndex(collections, collectionID)
+		if i == -1 {
+			return uimsg.ErrMsg{Err: fmt.Errorf("collection not found")}
+		}
+		folderID := collections[i].EnsureFolder(folderPath)
+		if err := collections[i].MoveRequest(requestID, folderID); err != nil {
+			return uimsg.ErrMsg{Err: err}
+		}
+		if err := storage.SaveCollection(&collections[i]); err != nil {
+			return uimsg.ErrMsg{Err: err}
+		}
+		return uimsg.RequestMovedMsg{CollectionID: collectionID, RequestID: requestID, FolderPath: folderPath}
+	}
+}
+
+// CreateFolderCmd creates the folder at the given path, including missing parents
+func CreateFolderCmd(collectionID, folderPath string) tea.Cmd {
+	return func() tea.Msg {
+		collections, _, _ := storage.LoadCollections()
+		i := storage.CollectionIndex(collections, collectionID)
+		if i == -1 {
+			return uimsg.ErrMsg{Err: fmt.Errorf("collection not found")}
+		}
+		collections[i].EnsureFolder(folderPath)
+		if err := storage.SaveCollection(&collections[i]); err != nil {
+			return uimsg.ErrMsg{Err: err}
+		}
+		return uimsg.FolderCreatedMsg{CollectionID: collectionID, Path: folderPath}
 	}
 }
 
@@ -120,37 +159,133 @@ func LoadCollectionsCmd() tea.Cmd {
 	}
 }
 
-func ExecuteRequestCmd(httpClient *http.Client, req storage.Request, baseURL string) tea.Cmd {
+// ExecuteRequestCmd sends req. The outcome carries send, the ID the model
+// gave the request, so it reaches the tab that sent it.
+func ExecuteRequestCmd(ctx context.Context, httpClient *http.Client, send uint64, req storage.Request, baseURL string) tea.Cmd {
+	return func() tea.Msg {
+		response, err := httpClient.Execute(ctx, req, baseURL)
+		return requestOutcome(send, req, response, err)
+	}
+}
+
+// DownloadCmd downloads the body of req to dl.Path. Progress is reported
+// with DownloadProgressMsg until the download ends with the same messages as
+// ExecuteRequestCmd.
+func DownloadCmd(ctx context.Context, httpClient *http.Client, send uint64, req storage.Request, baseURL string, dl http.Download) tea.Cmd {
+	return func() tea.Msg {
+		updates := make(chan tea.Msg, 1)
+		dl.Progress = func(progress http.Progress) {
+			// Reports are dropped while the last one is waiting to be shown
+			select {
+			case updates <- uimsg.DownloadProgressMsg{Send: send, Progress: progress, Updates: updates}:
+			default:
+			}
+		}
+		go func() {
+			response, err := httpClient.Download(ctx, req, baseURL, dl)
+			updates <- requestOutcome(send, req, response, err)
+		}()
+		return <-updates
+	}
+}
+
+// requestOutcome is the message ending a request
+func requestOutcome(send uint64, req storage.Request, response *http.ProcessedResponse, err error) tea.Msg {
+	switch {
+	case errors.Is(err, http.ErrCancelled):
+		return uimsg.RequestCancelledMsg{Send: send, Request: req}
+	case err != nil:
+		return uimsg.RequestFailedMsg{Send: send, Err: err}
+	}
+	return uimsg.ResponseReadyMsg{Send: send, Response: response, Request: req}
+}
+
+// WaitForDownloadCmd waits for the next report or the outcome of a download
+func WaitForDownloadCmd(updates <-chan tea.Msg) tea.Cmd {
 	return func() tea.Msg {
-		response, err := httpClient.Execute(req, baseURL)
+		return <-updates
+	}
+}
+
+// tokenTimeout bounds a token request started from the token view; the
+// authorization code grant waits for the user to sign in in the browser
+const tokenTimeout = 2 * time.Minute
+
+// LoadTokenCmd looks up the cached token of an oauth2 auth
+func LoadTokenCmd(tokens *oauth.Manager, auth storage.Auth) tea.Cmd {
+	return func() tea.Msg {
+		token, ok, err := tokens.Cached(auth)
+		return uimsg.TokenLoadedMsg{Token: token, Cached: ok, Err: err}
+	}
+}
+
+// RefreshTokenCmd obtains a new access token, using the refresh token if there
+// is one, through the proxy and TLS settings of client
+func RefreshTokenCmd(client *http.Client, auth storage.Auth) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), tokenTimeout)
+		defer cancel()
+		token, err := client.AccessToken(ctx, auth, true)
 		if err != nil {
-			return uimsg.ErrMsg{Err: err}
+			return uimsg.TokenLoadedMsg{Err: err}
 		}
-		return uimsg.ResponseReadyMsg{Response: response, Request: req}
+		return uimsg.TokenLoadedMsg{Token: token, Cached: true}
+	}
+}
+
+// ClearTokenCmd removes the cached token of an oauth2 auth
+func ClearTokenCmd(tokens *oauth.Manager, auth storage.Auth) tea.Cmd {
+	return func() tea.Msg {
+		return uimsg.TokenLoadedMsg{Err: tokens.Clear(auth)}
+	}
+}
+
+// LoadCookiesCmd lists the cookies of the active environment
+func LoadCookiesCmd(jar *cookies.Jar) tea.Cmd {
+	return func() tea.Msg {
+		return uimsg.CookiesLoadedMsg{Cookies: jar.All()}
+	}
+}
+
+// SaveCookieCmd stores an edited cookie
+func SaveCookieCmd(jar *cookies.Jar, c cookies.Cookie) tea.Cmd {
+	return func() tea.Msg {
+		err := jar.Set(c)
+		return uimsg.CookiesLoadedMsg{Cookies: jar.All(), Err: err}
+	}
+}
+
+// DeleteCookieCmd removes a cookie
+func DeleteCookieCmd(jar *cookies.Jar, c cookies.Cookie) tea.Cmd {
+	return func() tea.Msg {
+		err := jar.Delete(c)
+		return uimsg.CookiesLoadedMsg{Cookies: jar.All(), Err: err}
 	}
 }
 
-func SaveRequestCmd(req storage.Request) tea.Cmd {
+// SaveRequestCmd overwrites the request with the same ID in the given collection
+func SaveRequestCmd(collectionID string, req storage.Request) tea.Cmd {
 	return func() tea.Msg {
 		collections, _, _ := storage.LoadCollections()
-		for i, col := range collections {
-			for j, r := range col.Requests {
-				if r.Name == req.Name {
-					collections[i].Requests[j] = req
-					if err := storage.SaveCollection(collections[i]); err != nil {
-						return uimsg.ErrMsg{Err: err}
-					}
-					return uimsg.StatusMsg{Message: "Request saved", IsError: false}
-				}
-			}
+		i := storage.CollectionIndex(collections, collectionID)
+		if i == -1 {
+			return uimsg.ErrMsg{Err: fmt.Errorf("collection not found for request %s", req.Name)}
 		}
-		return uimsg.ErrMsg{Err: fmt.Errorf("collection not found for request %s", req.Name)}
+		target := collections[i].FindRequest(req.ID)
+		if target == nil {
+			return uimsg.ErrMsg{Err: fmt.Errorf("request %s not found in %s", req.Name, collections[i].Name)}
+		}
+		*target = req
+		if err := storage.SaveCollection(&collections[i]); err != nil {
+			return uimsg.ErrMsg{Err: err}
+		}
+		return uimsg.RequestSavedMsg{CollectionID: collectionID, Request: req}
 	}
 }
 
 func SaveCollectionCmd(col storage.Collection) tea.Cmd {
 	return func() tea.Msg {
-		if err := storage.SaveCollection(col); err != nil {
+		if err := storage.SaveCollection(&col); err != nil {
 			return uimsg.ErrMsg{Err: err}
 		}
 		return uimsg.StatusMsg{Message: "Collection saved", IsError: false}
@@ -173,6 +308,16 @@ func LoadEnvsCmd() tea.Cmd {
 	}
 }
 
+func LoadGlobalsCmd() tea.Cmd {
+	return func() tea.Msg {
+		vars, err := storage.LoadGlobals()
+		if err != nil {
+			return uimsg.ErrMsg{Err: err}
+		}
+		return uimsg.GlobalsLoadedMsg{Variables: vars}
+	}
+}
+
 func SaveEnvCmd(env storage.Environment) tea.Cmd {
 	return func() tea.Msg {
 		if err := storage.SaveEnvironment(env); err != nil {
@@ -182,6 +327,17 @@ func SaveEnvCmd(env storage.Environment) tea.Cmd {
 	}
 }
 
+// PersistEnvCmd writes extracted variables back to the environment file
+// without leaving the current view
+func PersistEnvCmd(env storage.Environment) tea.Cmd {
+	return func() tea.Msg {
+		if err := storage.SaveEnvironment(env); err != nil {
+			return uimsg.ErrMsg{Err: err}
+		}
+		return uimsg.StatusMsg{Message: "Saved variables to " + env.Name}
+	}
+}
+
 func DeleteEnvCmd(name string) tea.Cmd {
 	return func() tea.Msg {
 		if err := storage.DeleteEnvironment(name); err != nil {
@@ -191,43 +347,21 @@ func DeleteEnvCmd(name string) tea.Cmd {
 	}
 }
 
-func CreateRequestCmd(collectionName string, req storage.Request) tea.Cmd {
+// CreateRequestCmd adds req at the root of the given collection
+func CreateRequestCmd(collectionID string, req storage.Request) tea.Cmd {
 	return func() tea.Msg {
 		collections, _, _ := storage.LoadCollections()
-		
-		// Find or create collection
-		var targetCol *storage.Collection
-		var targetIdx int
-		found := false
-
-		for i := range collections {
-			if collections[i].Name == collectionName {
-				targetCol = &collections[i]
-				targetIdx = i
-				found = true
-				break
-			}
+		i := storage.CollectionIndex(collections, collectionID)
+		if i == -1 {
+			return uimsg.ErrMsg{Err: fmt.Errorf("collection not found for request %s", req.Name)}
 		}
-
-		if !found {
-			// Create new collection
-			newCol := storage.Collection{Name: collectionName, Requests: []storage.Request{req}}
-			if err := storage.SaveCollection(newCol); err != nil {
-				return uimsg.ErrMsg{Err: err}
-			}
-		} else {
-			// Add to existing
-			targetCol.Requests = append(targetCol.Requests, req)
-			// Update in list
-			collections[targetIdx] = *targetCol
-			if err := storage.SaveCollection(*targetCol); err != nil {
-				return uimsg.ErrMsg{Err: err}
-			}
+		collections[i].Requests = append(collections[i].Requests, req)
+		if err := storage.SaveCollection(&collections[i]); err != nil {
+			return uimsg.ErrMsg{Err: err}
 		}
-
 		return uimsg.RequestCreatedMsg{
-			CollectionName: collectionName,
-			Request:        req,
+			CollectionID: collectionID,
+			Request:      req,
 		}
 	}
 }
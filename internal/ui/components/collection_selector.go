@@ -154,7 +154,7 @@ func (i collectionItem) Description() string {
 	if i.collection.BaseURL != "" {
 		return i.collection.BaseURL
 	}
-	return fmt.Sprintf("%d requests", len(i.collection.Requests))
+	return fmt.Sprintf("%d requests", i.collection.RequestCount())
 }
 func (i collectionItem) FilterValue() string { return i.collection.Name }
 
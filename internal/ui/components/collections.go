@@ -2,6 +2,8 @@
 package components
 
 import (
+	"strings"
+
 	uimsg "github.com/styltsou/tapi/internal/ui/msg"
 	"github.com/styltsou/tapi/internal/ui/styles"
 
@@ -11,12 +13,14 @@ import (
 	"github.com/styltsou/tapi/internal/storage"
 )
 
-// CollectionsModel handles the collections list view
+// CollectionsModel handles the collections list view. Folders are shown as a
+// tree that can be expanded and collapsed.
 type CollectionsModel struct {
 	Width       int
 	Height      int
 	collection  storage.Collection
 	list        list.Model
+	collapsed   map[string]bool // folder IDs
 }
 
 func NewCollectionsModel() CollectionsModel {
@@ -38,7 +42,8 @@ func NewCollectionsModel() CollectionsModel {
 	l.KeyMap.Quit.SetKeys() // Disable default quit keys (q, esc) to handle globally
 
 	return CollectionsModel{
-		list: l,
+		list:      l,
+		collapsed: make(map[string]bool),
 	}
 }
 
@@ -66,19 +71,41 @@ func (m *CollectionsModel) SetCollection(collection storage.Collection) {
 	
 	// Sets the title to the collection name
 	m.list.Title = collection.Name
+	m.refreshItems()
+}
 
+// refreshItems rebuilds the visible rows from the folder tree
+func (m *CollectionsModel) refreshItems() {
 	var items []list.Item
 	// Add "Create New Request" item
 	items = append(items, requestItem{isCreate: true})
+	items = m.appendTree(items, m.collection.Requests, m.collection.Folders, "", "", 0, m.collection.Inherited())
+	m.list.SetItems(items)
+}
 
-	for _, req := range collection.Requests {
+func (m *CollectionsModel) appendTree(items []list.Item, requests []storage.Request, folders []storage.Folder, folderID, path string, depth int, inh storage.Inherited) []list.Item {
+	for _, f := range folders {
+		folderPath := f.Name
+		if path != "" {
+			folderPath = path + "/" + f.Name
+		}
+		expanded := !m.collapsed[f.ID]
+		items = append(items, folderItem{folder: f, path: folderPath, depth: depth, expanded: expanded})
+		if expanded {
+			items = m.appendTree(items, f.Requests, f.Folders, f.ID, folderPath, depth+1, inh.Inherit(f))
+		}
+	}
+	for _, req := range requests {
 		items = append(items, requestItem{
-			collection: collection,
+			collection: m.collection,
 			request:    req,
+			folderID:   folderID,
+			path:       path,
+			depth:      depth,
+			inherited:  inh,
 		})
 	}
-
-	m.list.SetItems(items)
+	return items
 }
 
 func (m CollectionsModel) Update(msg tea.Msg) (CollectionsModel, tea.Cmd) {
@@ -88,6 +115,24 @@ func (m CollectionsModel) Update(msg tea.Msg) (CollectionsModel, tea.Cmd) {
 			break
 		}
 		
+		// Folder rows expand and collapse
+		if folder, ok := m.list.SelectedItem().(folderItem); ok {
+			switch msg.String() {
+			case "enter":
+				m.collapsed[folder.folder.ID] = folder.expanded
+				m.refreshItems()
+				return m, nil
+			case "l", "right":
+				m.collapsed[folder.folder.ID] = false
+				m.refreshItems()
+				return m, nil
+			case "h", "left":
+				m.collapsed[folder.folder.ID] = true
+				m.refreshItems()
+				return m, nil
+			}
+		}
+
 		switch msg.String() {
 		case "enter":
 			if selected, ok := m.list.SelectedItem().(requestItem); ok {
@@ -105,8 +150,10 @@ func (m CollectionsModel) Update(msg tea.Msg) (CollectionsModel, tea.Cmd) {
 
 				return m, func() tea.Msg {
 					return uimsg.RequestSelectedMsg{
-						Request: selected.request,
-						BaseURL: selected.collection.BaseURL,
+						CollectionID: selected.collection.ID,
+						Request:      selected.request,
+						BaseURL:      selected.collection.BaseURL,
+						Inherited:    selected.inherited,
 					}
 				}
 			}
@@ -117,42 +164,73 @@ func (m CollectionsModel) Update(msg tea.Msg) (CollectionsModel, tea.Cmd) {
 					return uimsg.ConfirmActionMsg{
 						Title: "Delete request: " + styles.ErrorColorStyle.Render(selected.request.Name),
 						OnConfirm: uimsg.DeleteRequestMsg{
-							CollectionName: selected.collection.Name,
-							RequestName:    selected.request.Name,
+							CollectionID: selected.collection.ID,
+							RequestID:    selected.request.ID,
 						},
 					}
 				}
 			}
 
 		case "D": // Delete Collection (with confirmation)
-			if selected, ok := m.list.SelectedItem().(requestItem); ok && !selected.isCreate {
+			if !m.createSelected() {
+				name, collectionID := m.collection.Name, m.collection.ID
 				return m, func() tea.Msg {
 					return uimsg.ConfirmActionMsg{
-						Title:     "Delete collection: " + styles.ErrorColorStyle.Render(selected.collection.Name),
-						OnConfirm: uimsg.DeleteCollectionMsg{Name: selected.collection.Name},
+						Title:     "Delete collection: " + styles.ErrorColorStyle.Render(name),
+						OnConfirm: uimsg.DeleteCollectionMsg{CollectionID: collectionID},
+					}
+				}
+			}
+
+		case "m": // Move Request to a folder
+			if selected, ok := m.list.SelectedItem().(requestItem); ok && !selected.isCreate {
+				collectionID, requestID := selected.collection.ID, selected.request.ID
+				return m, func() tea.Msg {
+					return uimsg.PromptForInputMsg{
+						Title:       "Move " + selected.request.Name + " to folder",
+						Placeholder: "Folder path, e.g. Auth/Tokens (empty for the root)",
+						OnCommit: func(val string) tea.Msg {
+							return uimsg.MoveRequestMsg{CollectionID: collectionID, RequestID: requestID, FolderPath: val}
+						},
 					}
 				}
 			}
 
+		case "f": // New Folder, inside the selected folder if any
+			parent := ""
+			if folder, ok := m.list.SelectedItem().(folderItem); ok {
+				parent = folder.path + "/"
+			}
+			collectionID := m.collection.ID
+			return m, func() tea.Msg {
+				return uimsg.PromptForInputMsg{
+					Title:       "New Folder",
+					Placeholder: "Folder name",
+					OnCommit: func(val string) tea.Msg {
+						return uimsg.CreateFolderMsg{CollectionID: collectionID, Path: parent + val}
+					},
+				}
+			}
+
 		case "y": // Duplicate Request
 			if selected, ok := m.list.SelectedItem().(requestItem); ok && !selected.isCreate {
 				return m, func() tea.Msg {
 					return uimsg.DuplicateRequestMsg{
-						CollectionName: selected.collection.Name,
-						RequestName:    selected.request.Name,
+						CollectionID: selected.collection.ID,
+						RequestID:    selected.request.ID,
 					}
 				}
 			}
 
 		case "r": // Rename Collection
-			if selected, ok := m.list.SelectedItem().(requestItem); ok && !selected.isCreate {
-				oldName := selected.collection.Name
+			if !m.createSelected() {
+				oldName, collectionID := m.collection.Name, m.collection.ID
 				return m, func() tea.Msg {
 					return uimsg.PromptForInputMsg{
 						Title:       "Rename Collection: " + oldName,
 						Placeholder: "Enter new collection name",
 						OnCommit: func(val string) tea.Msg {
-							return uimsg.RenameCollectionMsg{OldName: oldName, NewName: val}
+							return uimsg.RenameCollectionMsg{CollectionID: collectionID, NewName: val}
 						},
 					}
 				}
@@ -169,27 +247,62 @@ func (m CollectionsModel) View() string {
 	return m.list.View()
 }
 
+// createSelected reports whether the "Create New Request" row is selected
+func (m CollectionsModel) createSelected() bool {
+	selected, ok := m.list.SelectedItem().(requestItem)
+	return ok && selected.isCreate
+}
+
 // requestItem implements list.Item interface
 type requestItem struct {
 	collection storage.Collection
 	request    storage.Request
 	isCreate   bool
+
+	folderID  string // empty at the collection root
+	path      string // folder path, e.g. "Auth/Tokens"
+	depth     int
+	inherited storage.Inherited
 }
 
 func (i requestItem) FilterValue() string {
 	if i.isCreate {
 		return "New Request Create"
 	}
-	return i.collection.Name + " " + i.request.Name
+	return i.collection.Name + " " + i.path + " " + i.request.Name
 }
 
 func (i requestItem) Title() string {
 	if i.isCreate {
 		return "+ Create New Request"
 	}
-	return styles.MethodBadge(i.request.Method) + " " + i.request.Name
+	return strings.Repeat("  ", i.depth) + styles.MethodBadge(i.request.Method) + " " + i.request.Name
 }
 
 func (i requestItem) Description() string {
 	return ""
 }
+
+// folderItem is a folder row in the tree
+type folderItem struct {
+	folder   storage.Folder
+	path     string
+	depth    int
+	expanded bool
+}
+
+func (i folderItem) FilterValue() string {
+	return i.path
+}
+
+func (i folderItem) Title() string {
+	icon := "▸ "
+	if i.expanded {
+		icon = "▾ "
+	}
+	return strings.Repeat("  ", i.depth) + icon + i.folder.Name
+}
+
+func (i folderItem) Description() string {
+	return ""
+}
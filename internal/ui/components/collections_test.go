@@ -0,0 +1,52 @@
+package components
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/styltsou/tapi/internal/storage"
+	uimsg "github.com/styltsou/tapi/internal/ui/msg"
+)
+
+func TestCollectionsModel_FolderTree(t *testing.T) {
+	m := NewCollectionsModel()
+	m.SetSize(40, 20)
+	m.SetCollection(storage.Collection{
+		ID:       "col",
+		Name:     "API",
+		Requests: []storage.Request{{ID: "health", Name: "Health", Method: "GET"}},
+		Folders: []storage.Folder{{
+			ID:       "auth",
+			Name:     "Auth",
+			Headers:  storage.Params{{Key: "X-Team", Value: "auth", Enabled: true}},
+			Requests: []storage.Request{{ID: "login", Name: "Login", Method: "POST"}},
+		}},
+	})
+
+	// Create row, Auth, Auth/Login, Health
+	if n := len(m.list.Items()); n != 4 {
+		t.Fatalf("Expected 4 rows with the folder expanded, got %d", n)
+	}
+
+	// Collapse the folder
+	m.list.Select(1)
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if n := len(m.list.Items()); n != 3 {
+		t.Fatalf("Expected 3 rows with the folder collapsed, got %d", n)
+	}
+
+	// Expand again and open the request inside it
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("l")})
+	m.list.Select(2)
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd == nil {
+		t.Fatal("Expected a command when selecting a request")
+	}
+	msg, ok := cmd().(uimsg.RequestSelectedMsg)
+	if !ok {
+		t.Fatalf("Expected RequestSelectedMsg, got %T", cmd())
+	}
+	if msg.CollectionID != "col" || msg.Request.ID != "login" || msg.Inherited.Headers.Get("X-Team") != "auth" {
+		t.Errorf("Unexpected selection: %+v", msg)
+	}
+}
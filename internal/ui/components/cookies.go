@@ -0,0 +1,216 @@
+package components
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/styltsou/tapi/internal/cookies"
+	uimsg "github.com/styltsou/tapi/internal/ui/msg"
+	"github.com/styltsou/tapi/internal/ui/styles"
+)
+
+// cookieRows is the number of cookies listed at once
+const cookieRows = 12
+
+// CookiesModel lists the cookies of the active environment and lets the user
+// edit their value or delete them
+type CookiesModel struct {
+	Visible bool
+	Width   int
+	Height  int
+
+	env     string
+	cookies []cookies.Cookie
+	cursor  int
+	editing bool
+	input   textinput.Model
+	err     error
+}
+
+func NewCookiesModel() CookiesModel {
+	input := textinput.New()
+	input.Placeholder = "value"
+	input.Width = 40
+	return CookiesModel{input: input}
+}
+
+func (m *CookiesModel) SetSize(width, height int) {
+	m.Width = width
+	m.Height = height
+}
+
+// Show opens the view for an environment; the cookies arrive with CookiesLoadedMsg
+func (m *CookiesModel) Show(env string) {
+	m.Visible = true
+	m.env = env
+	m.cookies = nil
+	m.cursor = 0
+	m.editing = false
+	m.err = nil
+}
+
+// SetCookies updates the view after a lookup, edit or delete
+func (m *CookiesModel) SetCookies(msg uimsg.CookiesLoadedMsg) {
+	m.cookies = msg.Cookies
+	m.err = msg.Err
+	m.cursor = max(0, min(m.cursor, len(m.cookies)-1))
+}
+
+func (m CookiesModel) Update(msg tea.Msg) (CookiesModel, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+
+	if m.editing {
+		switch key.String() {
+		case "esc":
+			m.editing = false
+			m.input.Blur()
+			return m, nil
+		case "enter":
+			m.editing = false
+			m.input.Blur()
+			c := m.cookies[m.cursor]
+			c.Value = m.input.Value()
+			return m, func() tea.Msg { return uimsg.SaveCookieMsg{Cookie: c} }
+		}
+		var cmd tea.Cmd
+		m.input, cmd = m.input.Update(msg)
+		return m, cmd
+	}
+
+	switch key.String() {
+	case "esc", "q":
+		m.Visible = false
+	case "j", "down":
+		if m.cursor < len(m.cookies)-1 {
+			m.cursor++
+		}
+	case "k", "up":
+		if m.cursor > 0 {
+			m.cursor--
+		}
+	case "e", "enter":
+		if len(m.cookies) > 0 {
+			m.editing = true
+			m.input.SetValue(m.cookies[m.cursor].Value)
+			m.input.CursorEnd()
+			return m, m.input.Focus()
+		}
+	case "d":
+		if len(m.cookies) > 0 {
+			c := m.cookies[m.cursor]
+			return m, func() tea.Msg { return uimsg.DeleteCookieMsg{Cookie: c} }
+		}
+	}
+	return m, nil
+}
+
+func (m CookiesModel) View() string {
+	if !m.Visible {
+		return ""
+	}
+
+	bg := styles.DarkGray
+	dimStyle := lipgloss.NewStyle().Foreground(styles.Gray).Background(bg)
+	valueStyle := lipgloss.NewStyle().Background(bg)
+	titleStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("#FFFFFF")).
+		Background(lipgloss.Color("#7D56F4")).
+		Bold(true).
+		Padding(0, 2).
+		MarginBottom(1)
+
+	env := m.env
+	if env == "" {
+		env = "no environment"
+	}
+
+	var sb strings.Builder
+	sb.WriteString(titleStyle.Render("Cookies · " + env))
+	sb.WriteString("\n")
+
+	if m.err != nil {
+		sb.WriteString(lipgloss.NewStyle().Foreground(styles.ErrorColor).Background(bg).Render(m.err.Error()) + "\n\n")
+	}
+
+	if len(m.cookies) == 0 {
+		sb.WriteString(dimStyle.Render("No cookies yet.") + "\n")
+	}
+
+	// Keep the cursor in the visible window
+	start := max(0, m.cursor-cookieRows+1)
+	end := min(len(m.cookies), start+cookieRows)
+	for i := start; i < end; i++ {
+		c := m.cookies[i]
+		prefix := "  "
+		if i == m.cursor {
+			prefix = "> "
+		}
+		sb.WriteString(styles.SelectedStyle.Background(bg).Render(prefix) +
+			valueStyle.Bold(true).Render(c.Name) +
+			dimStyle.Render("  "+cookieScope(c)+"  "+cookieExpiry(c, time.Now())) + "\n")
+		if i == m.cursor {
+			if m.editing {
+				sb.WriteString(dimStyle.Render("    = ") + m.input.View() + "\n")
+			} else {
+				sb.WriteString(dimStyle.Render("    = ") + valueStyle.Render(abbreviateValue(c.Value, 48)) + "\n")
+			}
+		}
+	}
+
+	sb.WriteString("\n")
+	if m.editing {
+		sb.WriteString(dimStyle.Render("enter save · esc cancel"))
+	} else {
+		sb.WriteString(dimStyle.Render("j/k move · e edit value · d delete · esc close"))
+	}
+
+	width := min(72, m.Width-4)
+	content := styles.Solidify(sb.String(), width, lipgloss.NewStyle().Background(bg))
+	return styles.ModalStyle.Render(content)
+}
+
+// cookieScope shows where a cookie is sent: ".example.com/" for a domain and
+// its subdomains, "api.example.com/" for that host only
+func cookieScope(c cookies.Cookie) string {
+	domain := c.Domain
+	if !c.HostOnly {
+		domain = "." + domain
+	}
+	scope := domain + c.Path
+	if c.Secure {
+		scope += "  secure"
+	}
+	if c.HttpOnly {
+		scope += "  httponly"
+	}
+	return scope
+}
+
+// cookieExpiry describes when a cookie expires
+func cookieExpiry(c cookies.Cookie, now time.Time) string {
+	if c.Expires.IsZero() {
+		return "session"
+	}
+	left := c.Expires.Sub(now)
+	switch {
+	case left >= 48*time.Hour:
+		return fmt.Sprintf("expires %s (%dd)", c.Expires.Format("2006-01-02"), int(left.Hours()/24))
+	default:
+		return fmt.Sprintf("expires %s (%s)", c.Expires.Format("15:04"), left.Round(time.Minute))
+	}
+}
+
+// abbreviateValue cuts long values (JWTs, session blobs) to fit on one line
+func abbreviateValue(s string, n int) string {
+	if len([]rune(s)) <= n {
+		return s
+	}
+	return string([]rune(s)[:n-1]) + "…"
+}
@@ -0,0 +1,47 @@
+package components
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/styltsou/tapi/internal/cookies"
+	uimsg "github.com/styltsou/tapi/internal/ui/msg"
+)
+
+func TestCookiesModel_EditAndDelete(t *testing.T) {
+	m := NewCookiesModel()
+	m.SetSize(100, 40)
+	m.Show("staging")
+	m.SetCookies(uimsg.CookiesLoadedMsg{Cookies: []cookies.Cookie{
+		{Name: "csrf", Value: "x1", Domain: "example.com", Path: "/"},
+		{Name: "session", Value: "abc", Domain: "api.example.com", HostOnly: true, Path: "/", Expires: time.Now().Add(72 * time.Hour)},
+	}})
+
+	view := stripANSI(m.View())
+	for _, want := range []string{"Cookies · staging", ".example.com/", "api.example.com/", "session", "expires"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("Expected %q in the cookie view, got:\n%s", want, view)
+		}
+	}
+
+	// Edit the value of the second cookie
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("j")})
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("e")})
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("def")})
+	m, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	saved, ok := cmd().(uimsg.SaveCookieMsg)
+	if !ok {
+		t.Fatal("Expected SaveCookieMsg")
+	}
+	if saved.Cookie.Name != "session" || saved.Cookie.Value != "abcdef" || !saved.Cookie.HostOnly {
+		t.Errorf("Expected session=abcdef for the host only, got %+v", saved.Cookie)
+	}
+
+	_, cmd = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("d")})
+	deleted, ok := cmd().(uimsg.DeleteCookieMsg)
+	if !ok || deleted.Cookie.Name != "session" {
+		t.Errorf("Expected the session cookie to be deleted, got %+v", deleted)
+	}
+}
@@ -23,8 +23,9 @@ type EnvEditorModel struct {
 }
 
 type VarInput struct {
-	key   textinput.Model
-	value textinput.Model
+	key    textinput.Model
+	value  textinput.Model
+	secret bool // value is masked unless focused
 }
 
 func NewEnvEditorModel() EnvEditorModel {
@@ -46,7 +47,7 @@ func (m *EnvEditorModel) SetEnvironment(env storage.Environment) {
 		vi.SetValue(v)
 		vi.Width = 40
 
-		m.inputs = append(m.inputs, VarInput{key: ki, value: vi})
+		m.inputs = append(m.inputs, VarInput{key: ki, value: vi, secret: env.IsSecret(k)})
 	}
 	
 	// Add one empty row if none exist
@@ -87,14 +88,34 @@ func (m EnvEditorModel) Update(msg tea.Msg) (EnvEditorModel, tea.Cmd) {
 		case "ctrl+s":
 			// Save variables back to env
 			newVars := make(map[string]string)
+			var secrets []string
 			for _, input := range m.inputs {
 				if input.key.Value() != "" {
 					newVars[input.key.Value()] = input.value.Value()
+					if input.secret {
+						secrets = append(secrets, input.key.Value())
+					}
+				}
+			}
+			// Locked secrets are not editable but stay flagged
+			for _, name := range m.env.LockedNames() {
+				if _, edited := newVars[name]; !edited {
+					secrets = append(secrets, name)
 				}
 			}
 			m.env.Variables = newVars
+			m.env.Secrets = secrets
 			return m, func() tea.Msg { return SaveEnvMsg{Env: m.env} }
 
+		case "ctrl+t":
+			// Toggle the secret flag of the focused row
+			row := m.focused / 2
+			if row < len(m.inputs) {
+				m.inputs[row].secret = !m.inputs[row].secret
+				m.updateFocus()
+			}
+			return m, nil
+
 		case "ctrl+a":
 			m.AddRow()
 			m.focused = (len(m.inputs) - 1) * 2
@@ -180,6 +201,11 @@ func (m *EnvEditorModel) updateFocus() {
 	for i := range m.inputs {
 		m.inputs[i].key.Blur()
 		m.inputs[i].value.Blur()
+		m.inputs[i].value.EchoMode = textinput.EchoNormal
+		if m.inputs[i].secret {
+			m.inputs[i].value.EchoMode = textinput.EchoPassword
+			m.inputs[i].value.EchoCharacter = '•'
+		}
 	}
 
 	row := m.focused / 2
@@ -189,6 +215,8 @@ func (m *EnvEditorModel) updateFocus() {
 		if isKey {
 			m.inputs[row].key.Focus()
 		} else {
+			// Reveal a secret only while it is being edited
+			m.inputs[row].value.EchoMode = textinput.EchoNormal
 			m.inputs[row].value.Focus()
 		}
 	}
@@ -218,16 +246,26 @@ func (m EnvEditorModel) View() string {
 		prefixStyle := styles.ModalSelectedStyle.Copy().Background(bg)
 		dimStyle := styles.DimStyle.Copy().Background(bg)
 
+		sep := " : "
+		if input.secret {
+			sep = " * "
+		}
 		row := lipgloss.JoinHorizontal(lipgloss.Center,
 			prefixStyle.Render(prefix),
 			input.key.View(),
-			dimStyle.Render(" : "),
+			dimStyle.Render(sep),
 			input.value.View(),
 		)
 		sb.WriteString(lipgloss.NewStyle().Background(bg).Render(row) + "\n")
 	}
 
+	if locked := m.env.LockedNames(); len(locked) > 0 {
+		sb.WriteString("\n" + styles.DimStyle.Copy().Background(bg).Render(
+			"  Locked: "+strings.Join(locked, ", ")+" (:unlock to edit)") + "\n")
+	}
+
 	sb.WriteString("\n")
+	sb.WriteString(styles.DimStyle.Copy().Background(bg).Render("  Ctrl+T secret · Ctrl+A add · Ctrl+D delete · Ctrl+S save") + "\n")
 	width := 72 // Variable(24) + Value(44) + " : "(3) + prefix(1) etc... let's be generous
 	bgStyle := lipgloss.NewStyle().Background(styles.DarkGray)
 	
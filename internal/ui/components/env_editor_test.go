@@ -0,0 +1,44 @@
+package components
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/styltsou/tapi/internal/storage"
+)
+
+func TestEnvEditor_MasksSecrets(t *testing.T) {
+	m := NewEnvEditorModel()
+	m.SetEnvironment(storage.Environment{
+		Name:      "prod",
+		Variables: map[string]string{"token": "s3cr3t"},
+		Secrets:   []string{"token"},
+	})
+
+	// Key column is focused, so the secret value is masked
+	if view := m.View(); strings.Contains(view, "s3cr3t") {
+		t.Error("Expected secret value to be masked in the editor")
+	}
+
+	// Focusing the value reveals it for editing
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyTab})
+	if view := m.View(); !strings.Contains(view, "s3cr3t") {
+		t.Error("Expected secret value to be visible while editing it")
+	}
+}
+
+func TestEnvEditor_ToggleSecretAndSave(t *testing.T) {
+	m := NewEnvEditorModel()
+	m.SetEnvironment(storage.Environment{Name: "dev", Variables: map[string]string{"token": "t"}})
+
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlT})
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyCtrlS})
+	saved, ok := cmd().(SaveEnvMsg)
+	if !ok {
+		t.Fatal("Expected SaveEnvMsg")
+	}
+	if !saved.Env.IsSecret("token") {
+		t.Errorf("Expected token to be flagged secret, got %v", saved.Env.Secrets)
+	}
+}
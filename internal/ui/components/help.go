@@ -76,6 +76,10 @@ func getHelpCategories() []helpCategory {
 				{"m", "Menu"},
 				{"o", "Preview request"},
 				{"y", "Copy as cURL"},
+				{"a", "OAuth2 token"},
+				{"k", "Cookies"},
+				{"d", "Download response"},
+				{"x", "Cancel request"},
 				{"w", "Close tab"},
 				{"q", "Quit"},
 			},
@@ -105,6 +109,9 @@ func getHelpCategories() []helpCategory {
 				{"/", "Search body"},
 				{"n / N", "Next / Prev match"},
 				{"c", "Copy body"},
+				{"R", "Raw request"},
+				{"T", "Timing waterfall"},
+				{"S", "TLS details"},
 			},
 		},
 	}
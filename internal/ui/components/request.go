@@ -26,10 +26,54 @@ const (
 	SectionBody
 )
 
+// Auth rows, shown depending on the auth type
+const (
+	authFieldType = iota
+	authFieldUsername
+	authFieldPassword
+	authFieldToken
+	authFieldKey
+	authFieldValue
+	authFieldIn
+	authFieldGrant
+	authFieldTokenURL
+	authFieldAuthURL
+	authFieldClientID
+	authFieldClientSecret
+	authFieldScope
+	authFieldRefreshToken
+	authFieldAccessKey
+	authFieldSecretKey
+	authFieldSessionToken
+	authFieldRegion
+	authFieldService
+	authFieldAlgorithm
+	authFieldHeader
+)
+
+// authTextFields are the auth rows backed by a text input
+var authTextFields = []int{
+	authFieldUsername, authFieldPassword, authFieldToken, authFieldKey, authFieldValue,
+	authFieldTokenURL, authFieldAuthURL, authFieldClientID, authFieldClientSecret, authFieldScope, authFieldRefreshToken,
+	authFieldAccessKey, authFieldSecretKey, authFieldSessionToken, authFieldRegion, authFieldService, authFieldHeader,
+}
+
+// authTypeLabels are the names shown for storage.AuthTypes
+var authTypeLabels = map[string]string{
+	storage.AuthBasic:  "Basic",
+	storage.AuthBearer: "Bearer",
+	storage.AuthAPIKey: "API Key",
+	storage.AuthDigest: "Digest",
+	storage.AuthOAuth2: "OAuth2",
+	storage.AuthAWS:    "AWS Signature",
+	storage.AuthHMAC:   "HMAC",
+}
+
 // KVInput is a generic key-value pair of text inputs
 type KVInput struct {
-	key   textinput.Model
-	value textinput.Model
+	key      textinput.Model
+	value    textinput.Model
+	disabled bool // kept in the request but not sent (Ctrl+T)
 }
 
 // RequestModel handles the request builder view
@@ -55,14 +99,44 @@ type RequestModel struct {
 	// Auth Fields
 	authUsername textinput.Model
 	authPassword textinput.Model
-	authEnabled  bool
-	authFocusIdx int // 0=username, 1=password
+	authToken    textinput.Model
+	authKey      textinput.Model
+	authValue    textinput.Model
+	authType     string // one of storage.AuthTypes, cycled with ←/→ on the type row
+	authIn       string // storage.InHeader or InQuery, for API keys
+
+	// OAuth2 Fields
+	authGrant        string // one of storage.OAuth2Grants
+	authTokenURL     textinput.Model
+	authAuthURL      textinput.Model
+	authClientID     textinput.Model
+	authClientSecret textinput.Model
+	authScope        textinput.Model
+	authRefreshToken textinput.Model
+
+	// Signing Fields
+	authAccessKey    textinput.Model
+	authSecretKey    textinput.Model
+	authSessionToken textinput.Model
+	authRegion       textinput.Model
+	authService      textinput.Model
+	authHeader       textinput.Model
+	authAlgorithm    string // one of storage.HMACAlgorithms
+
+	authMode     string // storage.AuthInherit, AuthNone or AuthOverride (Ctrl+B cycles)
+	noCookies    bool   // don't send the cookie jar's cookies (Ctrl+O toggles)
+	authFocusIdx int    // index into authFields()
+
+	// Headers and auth from the collection and folders, shown greyed out
+	inherited storage.Inherited
 
 	// State
 	focusedSection RequestSection
 	focusedIndex   int // index within a section's list (e.g., which header)
 	isKeyFocused   bool // true if key is focused, false if value is focused in KV sections
 	vars           map[string]string
+	varSources     map[string]string // scope each variable resolves from
+	scopes         storage.Scopes
 	Preview        bool
 	
 	// Autocomplete
@@ -90,6 +164,33 @@ func NewRequestModel() RequestModel {
 	authPass.EchoMode = textinput.EchoPassword
 	authPass.EchoCharacter = '•'
 
+	authToken := textinput.New()
+	authToken.Placeholder = "Token"
+	authToken.Width = 40
+	authToken.EchoMode = textinput.EchoPassword
+	authToken.EchoCharacter = '•'
+
+	authKey := textinput.New()
+	authKey.Placeholder = "X-Api-Key"
+	authKey.Width = 30
+
+	authValue := textinput.New()
+	authValue.Placeholder = "Key"
+	authValue.Width = 40
+	authValue.EchoMode = textinput.EchoPassword
+	authValue.EchoCharacter = '•'
+
+	newAuthInput := func(placeholder string, width int, secret bool) textinput.Model {
+		input := textinput.New()
+		input.Placeholder = placeholder
+		input.Width = width
+		if secret {
+			input.EchoMode = textinput.EchoPassword
+			input.EchoCharacter = '•'
+		}
+		return input
+	}
+
 	return RequestModel{
 		methods:          []string{"GET", "POST", "PUT", "DELETE", "PATCH", "HEAD", "OPTIONS"},
 		methodIndex:      0,
@@ -97,6 +198,26 @@ func NewRequestModel() RequestModel {
 		bodyInput:        bodyInput,
 		authUsername:     authUser,
 		authPassword:     authPass,
+		authToken:        authToken,
+		authKey:          authKey,
+		authValue:        authValue,
+		authType:         storage.AuthBasic,
+		authIn:           storage.InHeader,
+		authGrant:        storage.GrantClientCredentials,
+		authTokenURL:     newAuthInput("https://auth.example.com/oauth/token", 40, false),
+		authAuthURL:      newAuthInput("https://auth.example.com/authorize", 40, false),
+		authClientID:     newAuthInput("Client ID", 30, false),
+		authClientSecret: newAuthInput("Client secret", 30, true),
+		authScope:        newAuthInput("read write", 30, false),
+		authRefreshToken: newAuthInput("Refresh token", 40, true),
+		authAccessKey:    newAuthInput("Access key ID", 30, false),
+		authSecretKey:    newAuthInput("Secret key", 40, true),
+		authSessionToken: newAuthInput("Session token (optional)", 40, true),
+		authRegion:       newAuthInput("us-east-1", 20, false),
+		authService:      newAuthInput("execute-api", 20, false),
+		authHeader:       newAuthInput("X-Signature", 30, false),
+		authAlgorithm:    storage.HMACSHA256,
+		authMode:         storage.AuthInherit,
 		focusedSection:   SectionURL,
 		focusedIndex:     1, // Start on URL
 		pathParamsInputs: []KVInput{},
@@ -128,8 +249,8 @@ func (m *RequestModel) SetSize(width, height int) {
 	
 	// Header(2) + Method/URL(2) + Params(min 3) + Headers(min 3) = ~10
 	estimatedOverhead := 12
-	if m.authEnabled {
-		estimatedOverhead += 4
+	if m.authMode == storage.AuthOverride {
+		estimatedOverhead += len(m.authFields()) + 1
 	}
 	
 	availableForBody := max(3, height - estimatedOverhead)
@@ -139,6 +260,7 @@ func (m *RequestModel) SetSize(width, height int) {
 func (m *RequestModel) LoadRequest(req storage.Request, baseURL string) {
 	m.request = req
 	m.BaseURL = baseURL
+	m.SetScopes(m.scopes)
 
 	for i, mthd := range m.methods {
 		if mthd == req.Method {
@@ -147,17 +269,17 @@ func (m *RequestModel) LoadRequest(req storage.Request, baseURL string) {
 		}
 	}
 
-	// Parse URL for query params and path params
-	m.pathInput.SetValue(req.URL)
+	// The URL shows the enabled query params; parsing it back keeps the
+	// disabled rows in place
+	m.queryInputs = kvInputsFromParams(req.Query)
+	m.pathInput.SetValue(appendQuery(req.URL, encodeQueryInputs(m.queryInputs)))
 	m.parseURLParams()
 
 	m.bodyInput.SetValue(req.Body)
+	m.noCookies = req.NoCookies
 
 	// Load headers
-	m.headerInputs = []KVInput{}
-	for key, value := range req.Headers {
-		m.addRow(&m.headerInputs, key, value)
-	}
+	m.headerInputs = kvInputsFromParams(req.Headers)
 
 	// Add empty rows if needed
 	if len(m.headerInputs) == 0 {
@@ -165,15 +287,32 @@ func (m *RequestModel) LoadRequest(req storage.Request, baseURL string) {
 	}
 
 	// Load auth
+	m.authMode = req.EffectiveAuthMode()
+	auth := storage.Auth{}
 	if req.Auth != nil {
-		m.authUsername.SetValue(req.Auth.Username)
-		m.authPassword.SetValue(req.Auth.Password)
-		m.authEnabled = true
-	} else {
-		m.authUsername.SetValue("")
-		m.authPassword.SetValue("")
-		m.authEnabled = false
+		auth = *req.Auth
 	}
+	m.authType = auth.EffectiveType()
+	m.authIn = auth.EffectiveIn()
+	m.authUsername.SetValue(auth.Username)
+	m.authPassword.SetValue(auth.Password)
+	m.authToken.SetValue(auth.Token)
+	m.authKey.SetValue(auth.Key)
+	m.authValue.SetValue(auth.Value)
+	m.authGrant = auth.EffectiveGrant()
+	m.authTokenURL.SetValue(auth.TokenURL)
+	m.authAuthURL.SetValue(auth.AuthURL)
+	m.authClientID.SetValue(auth.ClientID)
+	m.authClientSecret.SetValue(auth.ClientSecret)
+	m.authScope.SetValue(auth.Scope)
+	m.authRefreshToken.SetValue(auth.RefreshToken)
+	m.authAccessKey.SetValue(auth.AccessKey)
+	m.authSecretKey.SetValue(auth.SecretKey)
+	m.authSessionToken.SetValue(auth.SessionToken)
+	m.authRegion.SetValue(auth.Region)
+	m.authService.SetValue(auth.Service)
+	m.authHeader.SetValue(auth.Header)
+	m.authAlgorithm = auth.EffectiveAlgorithm()
 
 	m.focusedSection = SectionURL
 	m.focusedIndex = 1 // Focus URL
@@ -191,7 +330,16 @@ func (m *RequestModel) Clear() {
 	m.bodyInput.SetValue("")
 	m.authUsername.SetValue("")
 	m.authPassword.SetValue("")
-	m.authEnabled = false
+	for _, field := range authTextFields {
+		m.authInput(field).SetValue("")
+	}
+	m.authGrant = storage.GrantClientCredentials
+	m.authAlgorithm = storage.HMACSHA256
+	m.authType = storage.AuthBasic
+	m.authIn = storage.InHeader
+	m.authMode = storage.AuthInherit
+	m.noCookies = false
+	m.inherited = storage.Inherited{}
 	m.pathParamsInputs = []KVInput{}
 	m.headerInputs = []KVInput{newEmptyKVInput()}
 	m.queryInputs = []KVInput{newEmptyKVInput()}
@@ -200,11 +348,91 @@ func (m *RequestModel) Clear() {
 	m.updateFocus()
 }
 
+// SetInherited sets the headers and auth the request inherits from its
+// collection and folders
+func (m *RequestModel) SetInherited(inherited storage.Inherited) {
+	m.inherited = inherited
+}
+
+// GetRequestID returns the ID of the currently loaded request
+func (m RequestModel) GetRequestID() string {
+	return m.request.ID
+}
+
 // GetRequestName returns the name of the currently loaded request
 func (m RequestModel) GetRequestName() string {
 	return m.request.Name
 }
 
+// kvInputsFromParams creates one row per entry, keeping the disabled ones
+func kvInputsFromParams(params storage.Params) []KVInput {
+	rows := []KVInput{}
+	for _, p := range params {
+		row := newEmptyKVInput()
+		row.key.SetValue(p.Key)
+		row.value.SetValue(p.Value)
+		row.disabled = !p.Enabled
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// paramsFromKVInputs converts rows back into params, skipping rows without a key
+func paramsFromKVInputs(rows []KVInput) storage.Params {
+	var params storage.Params
+	for _, row := range rows {
+		if row.key.Value() != "" {
+			params = append(params, storage.Param{Key: row.key.Value(), Value: row.value.Value(), Enabled: !row.disabled})
+		}
+	}
+	return params
+}
+
+// queryEscaper escapes only what would break parsing the query back from the
+// URL input, so {{variables}} stay readable
+var queryEscaper = strings.NewReplacer("%", "%25", "&", "%26", "#", "%23", "+", "%2B", "=", "%3D")
+
+// encodeQueryInputs builds the query string shown in the URL input from the enabled rows
+func encodeQueryInputs(rows []KVInput) string {
+	var parts []string
+	for _, row := range rows {
+		if row.disabled || row.key.Value() == "" {
+			continue
+		}
+		parts = append(parts, queryEscaper.Replace(row.key.Value())+"="+queryEscaper.Replace(row.value.Value()))
+	}
+	return strings.Join(parts, "&")
+}
+
+func appendQuery(rawURL, query string) string {
+	if query == "" {
+		return rawURL
+	}
+	if strings.Contains(rawURL, "?") {
+		return rawURL + "&" + query
+	}
+	return rawURL + "?" + query
+}
+
+// parseQuery splits a query string into key/value pairs, in order
+func parseQuery(query string) [][2]string {
+	var pairs [][2]string
+	for _, part := range strings.Split(query, "&") {
+		if part == "" {
+			continue
+		}
+		k, v, _ := strings.Cut(part, "=")
+		if uk, err := url.QueryUnescape(k); err == nil {
+			k = uk
+		}
+		if uv, err := url.QueryUnescape(v); err == nil {
+			v = uv
+		}
+		pairs = append(pairs, [2]string{k, v})
+	}
+	return pairs
+}
+
 func (m *RequestModel) addRow(list *[]KVInput, k, v string) {
 	ki := textinput.New()
 	ki.SetValue(k)
@@ -257,50 +485,25 @@ func (m *RequestModel) parseURLParams() {
 	m.pathParamsInputs = newPathParams
 
 	// 2. Parse Query Params
-	// This is trickier because we want bidirectional sync.
-	// If the user typed in the URL, we update the list.
-	// If the user typed in the list, we update the URL (handled elsewhere).
-	// Here we assume URL is source of truth.
-	
-	// Check if URL has query
-	if idx := strings.Index(rawURL, "?"); idx != -1 {
-		// queryStr := rawURL[idx+1:] // Unused
-		// Simple split for now, robust parsing requires net/url
-		// But robust parsing might mess up partial capabilities while typing.
-		// Let's use net/url for robustness but handle errors gracefully.
-		
-		// To parse query properly, we need a valid URL structure.
-		// dummy scheme if missing
-		parseURL := rawURL
-		if !strings.HasPrefix(parseURL, "http") {
-			parseURL = "http://example.com/" + strings.TrimLeft(parseURL, "/")
+	// The URL is the source of truth for the enabled params. Disabled rows are not
+	// part of the URL, so they are carried over at their previous position.
+	var newQueryParams []KVInput
+	if _, query, ok := strings.Cut(rawURL, "?"); ok {
+		for _, pair := range parseQuery(query) {
+			row := newEmptyKVInput()
+			row.key.SetValue(pair[0])
+			row.value.SetValue(pair[1])
+			newQueryParams = append(newQueryParams, row)
 		}
-		
-		u, err := url.Parse(parseURL)
-		if err == nil {
-			q := u.Query()
-			newQueryParams := []KVInput{}
-			for k, vs := range q {
-				for _, v := range vs {
-					ki := textinput.New()
-					ki.SetValue(k)
-					ki.Placeholder = "Key"
-					ki.Width = 20
-					
-					vi := textinput.New()
-					vi.SetValue(v)
-					vi.Placeholder = "Value"
-					vi.Width = 40
-					
-					newQueryParams = append(newQueryParams, KVInput{key: ki, value: vi})
-				}
-			}
-			m.queryInputs = newQueryParams
+	}
+	for i, old := range m.queryInputs {
+		if old.disabled {
+			pos := min(i, len(newQueryParams))
+			newQueryParams = append(newQueryParams[:pos], append([]KVInput{old}, newQueryParams[pos:]...)...)
 		}
-	} else {
-		m.queryInputs = []KVInput{}
 	}
-	
+	m.queryInputs = newQueryParams
+
 	if len(m.queryInputs) == 0 {
 		m.addRow(&m.queryInputs, "", "")
 	}
@@ -319,22 +522,7 @@ func (m *RequestModel) syncURLFromParams() {
 	// we keep them as :key in the URL.
 	// But we DO reconstruct the query string.
 	
-	if len(m.queryInputs) > 0 {
-		vals := url.Values{}
-		hasQuery := false
-		for _, qi := range m.queryInputs {
-			k := qi.key.Value()
-			v := qi.value.Value()
-			if k != "" {
-				vals.Add(k, v)
-				hasQuery = true
-			}
-		}
-		
-		if hasQuery {
-			pathOnly += "?" + vals.Encode()
-		}
-	}
+	pathOnly = appendQuery(pathOnly, encodeQueryInputs(m.queryInputs))
 	
 	// Update the URL input without triggering a parse loop
 	// We need a flag or just be careful. 
@@ -388,11 +576,34 @@ func (m RequestModel) Update(msg tea.Msg) (RequestModel, tea.Cmd) {
 			m.updateFocus()
 			return m, nil
 
-		case "ctrl+b": // Toggle Basic Auth
-			m.authEnabled = !m.authEnabled
-			if m.authEnabled {
+		case "ctrl+t": // Toggle row without deleting it
+			if m.focusedSection == SectionHeaders && m.focusedIndex < len(m.headerInputs) {
+				m.headerInputs[m.focusedIndex].disabled = !m.headerInputs[m.focusedIndex].disabled
+			} else if m.focusedSection == SectionQueryParams && m.focusedIndex < len(m.queryInputs) {
+				m.queryInputs[m.focusedIndex].disabled = !m.queryInputs[m.focusedIndex].disabled
+				// Sync back to URL
+				m.syncURLFromParams()
+			}
+			return m, nil
+
+		case "ctrl+o": // Send the cookie jar's cookies or not
+			m.noCookies = !m.noCookies
+			return m, nil
+
+		case "ctrl+b": // Cycle auth: inherit -> basic -> none
+			switch m.authMode {
+			case storage.AuthInherit:
+				m.authMode = storage.AuthOverride
 				m.focusedSection = SectionAuth
 				m.authFocusIdx = 0
+			case storage.AuthOverride:
+				m.authMode = storage.AuthNone
+				if m.focusedSection == SectionAuth {
+					m.focusedSection = SectionBody
+					m.focusedIndex = 0
+				}
+			default:
+				m.authMode = storage.AuthInherit
 			}
 			m.updateFocus()
 			return m, nil
@@ -405,7 +616,23 @@ func (m RequestModel) Update(msg tea.Msg) (RequestModel, tea.Cmd) {
 			m.prevField()
 			return m, nil
 
-		case "up", "down", "left", "right":
+		case "left", "right":
+			if m.focusedSection == SectionAuth {
+				if field := m.authFields()[m.authFocusIdx]; m.authInput(field) == nil {
+					m.cycleAuthOption(field, msg.String() == "right")
+					return m, nil
+				}
+			}
+			if m.focusedSection == SectionURL && m.focusedIndex == 0 {
+				if msg.String() == "left" {
+					m.methodIndex = (m.methodIndex - 1 + len(m.methods)) % len(m.methods)
+				} else {
+					m.methodIndex = (m.methodIndex + 1) % len(m.methods)
+				}
+				return m, nil
+			}
+
+		case "up", "down":
 			if m.focusedSection == SectionURL && m.focusedIndex == 0 {
 				if msg.String() == "up" || msg.String() == "left" {
 					m.methodIndex = (m.methodIndex - 1 + len(m.methods)) % len(m.methods)
@@ -528,12 +755,14 @@ func (m RequestModel) Update(msg tea.Msg) (RequestModel, tea.Cmd) {
 			cmds = append(cmds, cmd)
 		}
 	case SectionAuth:
-		if m.authFocusIdx == 0 {
-			m.authUsername, cmd = m.authUsername.Update(msg)
-		} else {
-			m.authPassword, cmd = m.authPassword.Update(msg)
+		if input := m.authInput(m.authFields()[m.authFocusIdx]); input != nil {
+			oldVal := input.Value()
+			*input, cmd = input.Update(msg)
+			if input.Value() != oldVal {
+				m.checkForTrigger(input.Value())
+			}
+			cmds = append(cmds, cmd)
 		}
-		cmds = append(cmds, cmd)
 	case SectionBody:
 		oldVal := m.bodyInput.Value()
 		m.bodyInput, cmd = m.bodyInput.Update(msg)
@@ -549,7 +778,11 @@ func (m RequestModel) Update(msg tea.Msg) (RequestModel, tea.Cmd) {
 func (m *RequestModel) checkForTrigger(text string) {
 	// Simple trigger: if ends with {{
 	if strings.HasSuffix(text, "{{") {
-		m.suggestions.Show(m.vars, "", func(selected string) tea.Msg {
+		options := storage.TemplateFunctions()
+		for k, v := range storage.MaskSecrets(m.vars, m.scopes.Secrets) {
+			options[k] = v + " · " + m.varSources[k]
+		}
+		m.suggestions.Show(options, "", func(selected string) tea.Msg {
 			// Callback to insert
 			return uimsg.SuggestionSelectedMsg{VarName: selected}
 		}, nil)
@@ -609,7 +842,7 @@ func (m *RequestModel) nextField() {
 			m.focusedIndex++
 			m.isKeyFocused = true
 			if m.focusedIndex >= len(m.headerInputs) {
-				if m.authEnabled {
+				if m.authMode == storage.AuthOverride {
 					m.focusedSection = SectionAuth
 					m.authFocusIdx = 0
 				} else {
@@ -620,7 +853,7 @@ func (m *RequestModel) nextField() {
 		}
 	case SectionAuth:
 		m.authFocusIdx++
-		if m.authFocusIdx > 1 {
+		if m.authFocusIdx >= len(m.authFields()) {
 			m.focusedSection = SectionBody
 			m.focusedIndex = 0
 			m.authFocusIdx = 0
@@ -684,9 +917,9 @@ func (m *RequestModel) prevField() {
 			m.authFocusIdx = 0
 		}
 	case SectionBody:
-		if m.authEnabled {
+		if m.authMode == storage.AuthOverride {
 			m.focusedSection = SectionAuth
-			m.authFocusIdx = 1
+			m.authFocusIdx = len(m.authFields()) - 1
 		} else {
 			m.focusedSection = SectionHeaders
 			m.focusedIndex = max(0, len(m.headerInputs)-1)
@@ -699,8 +932,9 @@ func (m *RequestModel) prevField() {
 func (m *RequestModel) updateFocus() {
 	m.pathInput.Blur()
 	m.bodyInput.Blur()
-	m.authUsername.Blur()
-	m.authPassword.Blur()
+	for _, field := range authTextFields {
+		m.authInput(field).Blur()
+	}
 	
 	for i := range m.pathParamsInputs {
 		m.pathParamsInputs[i].value.Blur()
@@ -740,26 +974,222 @@ func (m *RequestModel) updateFocus() {
 			}
 		}
 	case SectionAuth:
-		if m.authFocusIdx == 0 {
-			m.authUsername.Focus()
-		} else {
-			m.authPassword.Focus()
+		if input := m.authInput(m.authFields()[m.authFocusIdx]); input != nil {
+			input.Focus()
 		}
 	case SectionBody:
 		m.bodyInput.Focus()
 	}
 }
 
+// authFields returns the auth rows of the selected type
+func (m RequestModel) authFields() []int {
+	switch m.authType {
+	case storage.AuthBearer:
+		return []int{authFieldType, authFieldToken}
+	case storage.AuthAPIKey:
+		return []int{authFieldType, authFieldKey, authFieldValue, authFieldIn}
+	case storage.AuthOAuth2:
+		fields := []int{authFieldType, authFieldGrant}
+		if m.authGrant == storage.GrantAuthorizationCode {
+			fields = append(fields, authFieldAuthURL)
+		}
+		fields = append(fields, authFieldTokenURL, authFieldClientID, authFieldClientSecret, authFieldScope)
+		switch m.authGrant {
+		case storage.GrantPassword:
+			fields = append(fields, authFieldUsername, authFieldPassword)
+		case storage.GrantRefreshToken:
+			fields = append(fields, authFieldRefreshToken)
+		}
+		return fields
+	case storage.AuthAWS:
+		return []int{authFieldType, authFieldAccessKey, authFieldSecretKey, authFieldSessionToken, authFieldRegion, authFieldService}
+	case storage.AuthHMAC:
+		return []int{authFieldType, authFieldSecretKey, authFieldAlgorithm, authFieldHeader}
+	default: // basic, digest
+		return []int{authFieldType, authFieldUsername, authFieldPassword}
+	}
+}
+
+// authInput returns the text input of an auth row, or nil for the rows
+// cycled with ←/→
+func (m *RequestModel) authInput(field int) *textinput.Model {
+	switch field {
+	case authFieldUsername:
+		return &m.authUsername
+	case authFieldPassword:
+		return &m.authPassword
+	case authFieldToken:
+		return &m.authToken
+	case authFieldKey:
+		return &m.authKey
+	case authFieldValue:
+		return &m.authValue
+	case authFieldTokenURL:
+		return &m.authTokenURL
+	case authFieldAuthURL:
+		return &m.authAuthURL
+	case authFieldClientID:
+		return &m.authClientID
+	case authFieldClientSecret:
+		return &m.authClientSecret
+	case authFieldScope:
+		return &m.authScope
+	case authFieldRefreshToken:
+		return &m.authRefreshToken
+	case authFieldAccessKey:
+		return &m.authAccessKey
+	case authFieldSecretKey:
+		return &m.authSecretKey
+	case authFieldSessionToken:
+		return &m.authSessionToken
+	case authFieldRegion:
+		return &m.authRegion
+	case authFieldService:
+		return &m.authService
+	case authFieldHeader:
+		return &m.authHeader
+	}
+	return nil
+}
+
+// cycleAuthOption moves the auth type, the OAuth2 grant, the HMAC algorithm
+// or the API key location to the next (or previous) option
+func (m *RequestModel) cycleAuthOption(field int, forward bool) {
+	switch field {
+	case authFieldIn:
+		if m.authIn == storage.InQuery {
+			m.authIn = storage.InHeader
+		} else {
+			m.authIn = storage.InQuery
+		}
+	case authFieldGrant:
+		m.authGrant = cycleOption(storage.OAuth2Grants, m.authGrant, forward)
+	case authFieldAlgorithm:
+		m.authAlgorithm = cycleOption(storage.HMACAlgorithms, m.authAlgorithm, forward)
+	default:
+		m.authType = cycleOption(storage.AuthTypes, m.authType, forward)
+	}
+}
+
+// cycleOption returns the option after (or before) current, wrapping around
+func cycleOption(options []string, current string, forward bool) string {
+	idx := 0
+	for i, o := range options {
+		if o == current {
+			idx = i
+		}
+	}
+	if forward {
+		idx = (idx + 1) % len(options)
+	} else {
+		idx = (idx - 1 + len(options)) % len(options)
+	}
+	return options[idx]
+}
+
+// buildAuth returns the auth entered for the selected type, or nil if its
+// main field is empty
+func (m RequestModel) buildAuth() *storage.Auth {
+	auth := storage.Auth{Type: m.authType}
+	switch m.authType {
+	case storage.AuthBearer:
+		auth.Token = m.authToken.Value()
+		if auth.Token == "" {
+			return nil
+		}
+	case storage.AuthAPIKey:
+		auth.Key = m.authKey.Value()
+		auth.Value = m.authValue.Value()
+		if m.authIn == storage.InQuery {
+			auth.In = storage.InQuery
+		}
+		if auth.Key == "" {
+			return nil
+		}
+	case storage.AuthOAuth2:
+		auth.TokenURL = m.authTokenURL.Value()
+		auth.ClientID = m.authClientID.Value()
+		auth.ClientSecret = m.authClientSecret.Value()
+		auth.Scope = m.authScope.Value()
+		switch m.authGrant {
+		case storage.GrantPassword:
+			auth.Grant = m.authGrant
+			auth.Username = m.authUsername.Value()
+			auth.Password = m.authPassword.Value()
+		case storage.GrantRefreshToken:
+			auth.Grant = m.authGrant
+			auth.RefreshToken = m.authRefreshToken.Value()
+		case storage.GrantAuthorizationCode:
+			auth.Grant = m.authGrant
+			auth.AuthURL = m.authAuthURL.Value()
+		}
+		if m.request.Auth != nil {
+			auth.RedirectPort = m.request.Auth.RedirectPort // not editable here
+		}
+		if auth.TokenURL == "" {
+			return nil
+		}
+	case storage.AuthAWS:
+		auth.AccessKey = m.authAccessKey.Value()
+		auth.SecretKey = m.authSecretKey.Value()
+		auth.SessionToken = m.authSessionToken.Value()
+		auth.Region = m.authRegion.Value()
+		auth.Service = m.authService.Value()
+		if auth.AccessKey == "" {
+			return nil
+		}
+	case storage.AuthHMAC:
+		auth.SecretKey = m.authSecretKey.Value()
+		auth.Header = m.authHeader.Value()
+		if m.authAlgorithm != storage.HMACSHA256 {
+			auth.Algorithm = m.authAlgorithm
+		}
+		if m.request.Auth != nil {
+			// not editable here
+			auth.TimestampHeader = m.request.Auth.TimestampHeader
+			auth.Encoding = m.request.Auth.Encoding
+		}
+		if auth.SecretKey == "" {
+			return nil
+		}
+	default:
+		auth.Username = m.authUsername.Value()
+		auth.Password = m.authPassword.Value()
+		if auth.Username == "" {
+			return nil
+		}
+	}
+	// Basic is the default type and is left out of the file
+	if auth.Type == storage.AuthBasic {
+		auth.Type = ""
+	}
+	return &auth
+}
+
 // BuildRequest constructs the request and possibly a targeted URL (with substitutions)
 func (m *RequestModel) BuildRequest() (storage.Request, string) {
 	req := storage.Request{
-		Name:    m.request.Name,
-		Method:  m.methods[m.methodIndex],
-		Headers: make(map[string]string),
-		Body:    m.bodyInput.Value(),
+		ID:        m.request.ID,
+		Name:      m.request.Name,
+		Method:    m.methods[m.methodIndex],
+		Query:     paramsFromKVInputs(m.queryInputs),
+		Headers:   paramsFromKVInputs(m.headerInputs),
+		Body:      m.bodyInput.Value(),
+		Timeout:   m.request.Timeout,
+		NoCookies: m.noCookies,
+
+		NoFollow:     m.request.NoFollow,
+		MaxRedirects: m.request.MaxRedirects,
+		KeepMethod:   m.request.KeepMethod,
+
+		Variables:  m.request.Variables,
+		Assertions: m.request.Assertions,
+		Extract:    m.request.Extract,
 	}
 
-	rawURL := m.pathInput.Value()
+	// Query params are stored in Query, the URL keeps the path only
+	rawURL, _, _ := strings.Cut(m.pathInput.Value(), "?")
 	
 	// Apply path params substitutions
 	targetedURL := rawURL
@@ -772,20 +1202,14 @@ func (m *RequestModel) BuildRequest() (storage.Request, string) {
 	}
 	
 	req.URL = rawURL // Saved request keeps the :params
-	
-	// Headers
-	for _, hi := range m.headerInputs {
-		if hi.key.Value() != "" {
-			req.Headers[hi.key.Value()] = hi.value.Value()
-		}
-	}
 
-	// Basic Auth
-	if m.authEnabled && m.authUsername.Value() != "" {
-		req.Auth = &storage.BasicAuth{
-			Username: m.authUsername.Value(),
-			Password: m.authPassword.Value(),
-		}
+	// Auth is only kept when the request uses its own. The mode is saved
+	// when it differs from the one implied by the auth.
+	if m.authMode == storage.AuthOverride {
+		req.Auth = m.buildAuth()
+	}
+	if m.authMode != req.EffectiveAuthMode() {
+		req.AuthMode = m.authMode
 	}
 
 	return req, targetedURL
@@ -842,6 +1266,10 @@ func (m RequestModel) View() string {
 	sb.WriteString(styles.HeaderStyle.Render("HEADERS"))
 	sb.WriteString("\n")
 	sb.WriteString(m.renderKVSection(m.headerInputs, SectionHeaders))
+	sb.WriteString(m.renderInheritedHeaders())
+	if m.noCookies {
+		sb.WriteString(styles.DimStyle.Render("  Cookies are not sent (Ctrl+O to send)") + "\n")
+	}
 	sb.WriteString("\n")
 
 	// Query Params
@@ -851,33 +1279,23 @@ func (m RequestModel) View() string {
 	sb.WriteString("\n")
 
 	// Auth
-	authLabel := "AUTH"
-	if m.authEnabled {
-		authLabel = "AUTH (Basic ✓)"
+	authLabel := "AUTH (inherit)"
+	switch m.authMode {
+	case storage.AuthOverride:
+		authLabel = "AUTH (" + authTypeLabels[m.authType] + " ✓)"
+	case storage.AuthNone:
+		authLabel = "AUTH (none)"
 	}
 	sb.WriteString(styles.HeaderStyle.Render(authLabel))
 	sb.WriteString("\n")
-	if m.authEnabled {
-		userPrefix := "  "
-		passPrefix := "  "
-		if m.focusedSection == SectionAuth && m.authFocusIdx == 0 {
-			userPrefix = "> "
-		}
-		if m.focusedSection == SectionAuth && m.authFocusIdx == 1 {
-			passPrefix = "> "
-		}
-		sb.WriteString(lipgloss.JoinHorizontal(lipgloss.Center,
-			styles.SelectedStyle.Render(userPrefix),
-			styles.DimStyle.Render("User: "),
-			m.authUsername.View(),
-		) + "\n")
-		sb.WriteString(lipgloss.JoinHorizontal(lipgloss.Center,
-			styles.SelectedStyle.Render(passPrefix),
-			styles.DimStyle.Render("Pass: "),
-			m.authPassword.View(),
-		) + "\n")
+	if m.authMode == storage.AuthOverride {
+		sb.WriteString(m.renderAuthFields())
+	} else if m.authMode == storage.AuthNone {
+		sb.WriteString(styles.DimStyle.Render("  No auth is sent (Ctrl+B to inherit)") + "\n")
+	} else if m.inherited.Auth != nil {
+		sb.WriteString(styles.DimStyle.Render("  "+authSummary(*m.inherited.Auth)+"  (from "+m.inherited.AuthFrom+")") + "\n")
 	} else {
-		sb.WriteString(styles.DimStyle.Render("  Disabled (Ctrl+B to enable)") + "\n")
+		sb.WriteString(styles.DimStyle.Render("  Nothing to inherit (Ctrl+B to set)") + "\n")
 	}
 	sb.WriteString("\n")
 
@@ -891,6 +1309,13 @@ func (m RequestModel) View() string {
 	}
 	sb.WriteString(bodyView)
 
+	if m.Preview {
+		sb.WriteString("\n\n")
+		sb.WriteString(styles.HeaderStyle.Render("VARIABLES"))
+		sb.WriteString("\n")
+		sb.WriteString(m.renderVarSources())
+	}
+
 	// Render suggestions overlay if visible
 	if m.suggestions.Visible {
 		return lipgloss.JoinHorizontal(lipgloss.Top, sb.String(), m.suggestions.View())
@@ -920,8 +1345,15 @@ func (m RequestModel) renderKVSection(inputs []KVInput, section RequestSection)
 			prefix = "> "
 		}
 
+		// Disabled rows are kept but not sent
+		state := ""
+		if input.disabled {
+			state = styles.DimStyle.Render("[off] ")
+		}
+
 		row := lipgloss.JoinHorizontal(lipgloss.Center,
 			styles.SelectedStyle.Render(prefix),
+			state,
 			keyView,
 			styles.DimStyle.Render(" : "),
 			valView,
@@ -931,61 +1363,238 @@ func (m RequestModel) renderKVSection(inputs []KVInput, section RequestSection)
 	return sb.String()
 }
 
+// renderAuthFields renders the rows of the selected auth type
+func (m RequestModel) renderAuthFields() string {
+	labels := map[int]string{
+		authFieldType:     "Type: ",
+		authFieldUsername: "User: ",
+		authFieldPassword: "Pass: ",
+		authFieldToken:    "Token: ",
+		authFieldKey:      "Name: ",
+		authFieldValue:    "Key: ",
+		authFieldIn:       "In: ",
+
+		authFieldGrant:        "Grant: ",
+		authFieldTokenURL:     "Token URL: ",
+		authFieldAuthURL:      "Auth URL: ",
+		authFieldClientID:     "Client ID: ",
+		authFieldClientSecret: "Secret: ",
+		authFieldScope:        "Scope: ",
+		authFieldRefreshToken: "Refresh: ",
+
+		authFieldAccessKey:    "Access key: ",
+		authFieldSecretKey:    "Secret key: ",
+		authFieldSessionToken: "Session: ",
+		authFieldRegion:       "Region: ",
+		authFieldService:      "Service: ",
+		authFieldAlgorithm:    "Algorithm: ",
+		authFieldHeader:       "Header: ",
+	}
+
+	var sb strings.Builder
+	for i, field := range m.authFields() {
+		prefix := "  "
+		if m.focusedSection == SectionAuth && m.authFocusIdx == i {
+			prefix = "> "
+		}
+		var value string
+		switch field {
+		case authFieldType:
+			value = "‹ " + authTypeLabels[m.authType] + " ›"
+		case authFieldIn:
+			value = "‹ " + m.authIn + " ›"
+		case authFieldGrant:
+			value = "‹ " + m.authGrant + " ›"
+		case authFieldAlgorithm:
+			value = "‹ " + m.authAlgorithm + " ›"
+		default:
+			value = m.authInput(field).View()
+		}
+		sb.WriteString(lipgloss.JoinHorizontal(lipgloss.Center,
+			styles.SelectedStyle.Render(prefix),
+			styles.DimStyle.Render(labels[field]),
+			value,
+		) + "\n")
+	}
+	return sb.String()
+}
+
+// authSummary describes an inherited auth without showing its secret
+func authSummary(auth storage.Auth) string {
+	switch auth.EffectiveType() {
+	case storage.AuthBearer:
+		return "Bearer  Token: " + storage.Mask
+	case storage.AuthAPIKey:
+		return "API Key  " + auth.Key + ": " + storage.Mask + " (" + auth.EffectiveIn() + ")"
+	case storage.AuthOAuth2:
+		return "OAuth2  " + auth.EffectiveGrant() + "  " + auth.TokenURL
+	case storage.AuthAWS:
+		return "AWS Signature  " + auth.AccessKey + "  " + auth.Region + "/" + auth.Service
+	case storage.AuthHMAC:
+		return "HMAC  " + auth.EffectiveAlgorithm() + "  " + auth.EffectiveHeader()
+	default:
+		return authTypeLabels[auth.EffectiveType()] + "  User: " + auth.Username + "  Pass: " + storage.Mask
+	}
+}
+
+// renderInheritedHeaders lists the inherited headers the request does not
+// override, greyed out since they are edited on the collection or folder
+func (m RequestModel) renderInheritedHeaders() string {
+	var sb strings.Builder
+	for _, h := range m.inherited.Headers {
+		overridden := false
+		for _, input := range m.headerInputs {
+			if strings.EqualFold(strings.TrimSpace(input.key.Value()), h.Key) {
+				overridden = true
+				break
+			}
+		}
+		if overridden || !h.Enabled {
+			continue
+		}
+		sb.WriteString(styles.DimStyle.Render("  "+h.Key+" : "+h.Value+"  (from "+m.inherited.Source(h.Key)+")") + "\n")
+	}
+	return sb.String()
+}
+
+// SetScopes sets the variables used for autocomplete and preview. The request
+// scope is taken from the loaded request.
+func (m *RequestModel) SetScopes(scopes storage.Scopes) {
+	scopes.Request = m.request.Variables
+	m.scopes = scopes
+	m.vars = scopes.Merged()
+	m.varSources = scopes.Sources()
+}
+
+// SetVariables sets a flat set of variables, treated as the environment scope
 func (m *RequestModel) SetVariables(vars map[string]string) {
-	m.vars = vars
+	m.SetScopes(storage.Scopes{Environment: vars})
 }
+// highlightVars renders {{ }} tags with their resolved value in green, or the
+// tag itself in red when it is missing or cyclic. Function tags such as
+// {{$uuid}} show a sample of their output.
 func (m RequestModel) highlightVars(text string) string {
-	// Re-implement basic regex replacement with checks
-	// varRegex is technically private in storage, need to redefine or expose?
-	// It's defined in internal/storage/templating.go as var varRegex, but not exported.
-	// We can redefine it here.
-	
-	// We iterate over MATCHES.
-	// For every match {{key}}, we check if it exists in m.vars.
-	// If yes -> Substitute and color GREEN/BOLD.
-	// If no -> Leave as {{key}} and color RED/BOLD/WARNING.
-	
-	// Since we need to replace parts of string, we can use ReplaceAllStringFunc equivalent manually.
-	
-	// Simple approach: Split by {{ and }}
-	
-	// Let's use loop.
-	result := text
-	start := 0
+	var sb strings.Builder
+	rest := text
 	for {
-		startIdx := strings.Index(result[start:], "{{")
+		startIdx := strings.Index(rest, "{{")
 		if startIdx == -1 {
 			break
 		}
-		actualStart := start + startIdx
-		
-		endIdx := strings.Index(result[actualStart:], "}}")
+		endIdx := matchingTagEnd(rest, startIdx)
 		if endIdx == -1 {
 			break
 		}
-		actualEnd := actualStart + endIdx + 2
-		
-		key := result[actualStart+2 : actualEnd-2]
-		key = strings.TrimSpace(key)
-		
+
+		tag := rest[startIdx:endIdx]
+		key := strings.TrimSpace(tag[2 : len(tag)-2])
+
 		var replacement string
-		if val, ok := m.vars[key]; ok {
-			// Found
+		if val := storage.Substitute(tag, m.vars); len(storage.FindUnresolved(val)) == 0 {
+			if m.usesSecret(tag) {
+				val = storage.Mask
+			}
 			replacement = lipgloss.NewStyle().Foreground(styles.SecondaryColor).Bold(true).Render(val)
 		} else {
-			// Not Found
 			replacement = lipgloss.NewStyle().Foreground(styles.ErrorColor).Bold(true).Render("{{" + key + "}}")
 		}
-		
-		// Replace
-		result = result[:actualStart] + replacement + result[actualEnd:]
-		
-		// Update start to skip replaced part
-		start = actualStart + len(replacement)
+
+		sb.WriteString(rest[:startIdx])
+		sb.WriteString(replacement)
+		rest = rest[endIdx:]
 	}
-	
-	return result
+	sb.WriteString(rest)
+
+	return sb.String()
+}
+
+// renderVarSources lists the variables used by the request with their resolved
+// value and the scope it comes from
+func (m RequestModel) renderVarSources() string {
+	texts := []string{m.pathInput.Value(), m.bodyInput.Value()}
+	if auth := m.buildAuth(); m.authMode == storage.AuthOverride && auth != nil {
+		texts = append(texts, auth.Values()...)
+	}
+	for _, h := range m.inherited.Headers {
+		texts = append(texts, h.Value)
+	}
+	if auth := m.inherited.Auth; m.authMode == storage.AuthInherit && auth != nil {
+		texts = append(texts, auth.Values()...)
+	}
+	for _, group := range [][]KVInput{m.pathParamsInputs, m.headerInputs, m.queryInputs} {
+		for _, input := range group {
+			texts = append(texts, input.value.Value())
+		}
+	}
+
+	var names []string
+	seen := make(map[string]bool)
+	for _, text := range texts {
+		for _, name := range storage.FindUnresolved(text) {
+			if !seen[name] && !strings.HasPrefix(name, "$") {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	if len(names) == 0 {
+		return styles.DimStyle.Render("  (none)") + "\n"
+	}
+
+	var sb strings.Builder
+	for _, name := range names {
+		if _, ok := m.vars[name]; !ok {
+			sb.WriteString("  " + lipgloss.NewStyle().Foreground(styles.ErrorColor).Bold(true).Render(name) +
+				styles.DimStyle.Render(" : missing") + "\n")
+			continue
+		}
+		sb.WriteString("  " + name + styles.DimStyle.Render(" : ") +
+			m.highlightVars("{{"+name+"}}") +
+			styles.DimStyle.Render("  ("+m.varSources[name]+")") + "\n")
+	}
+	return sb.String()
 }
+
+// usesSecret reports whether a tag references a secret variable, directly or
+// through the variables it expands to
+func (m RequestModel) usesSecret(tag string) bool {
+	if len(m.scopes.Secrets) == 0 {
+		return false
+	}
+	// Secrets expand to a tag that never resolves, so functions wrapping them
+	// are left unevaluated and the marker survives
+	const marker = "{{\x00}}"
+	vars := storage.MergeVariables(m.vars)
+	for _, name := range m.scopes.Secrets {
+		if _, ok := vars[name]; ok {
+			vars[name] = marker
+		}
+	}
+	return strings.Contains(storage.Substitute(tag, vars), marker)
+}
+
+// matchingTagEnd returns the index just past the "}}" closing the tag opened at
+// start, accounting for nested tags, or -1 if the tag is not closed
+func matchingTagEnd(s string, start int) int {
+	depth := 0
+	for i := start; i+1 < len(s); {
+		switch s[i : i+2] {
+		case "{{":
+			depth++
+			i += 2
+		case "}}":
+			depth--
+			i += 2
+			if depth == 0 {
+				return i
+			}
+		default:
+			i++
+		}
+	}
+	return -1
+}
+
 func max(a, b int) int {
 	if a > b {
 		return a
@@ -1009,8 +1618,9 @@ func (m *RequestModel) SetCursorMode(mode cursor.Mode) tea.Cmd {
 
 	m.pathInput.Cursor.SetMode(mode)
 	m.bodyInput.Cursor.SetMode(mode)
-	m.authUsername.Cursor.SetMode(mode)
-	m.authPassword.Cursor.SetMode(mode)
+	for _, field := range authTextFields {
+		m.authInput(field).Cursor.SetMode(mode)
+	}
 
 	for i := range m.pathParamsInputs {
 		m.pathParamsInputs[i].value.Cursor.SetMode(mode)
@@ -1,10 +1,11 @@
 package components
 
 import (
-
+	"reflect"
 	"strings"
 	"testing"
 
+	tea "github.com/charmbracelet/bubbletea"
 	"github.com/styltsou/tapi/internal/storage"
 )
 
@@ -34,24 +35,12 @@ func TestRequestModel_ParseURLParams(t *testing.T) {
 		t.Errorf("Expected 2 query params, got %d", len(m.queryInputs))
 	}
 	
-	// Query params might be unordered if utilizing map internally, but here likely ordered by URL appearance or not guaranteed?
-	// The implementation uses url.ParseQuery which returns a map, so order is NOT guaranteed.
-	// But our implementation iterates over the map.
-	
-	// Let's just check existence.
-	foundQ := false
-	foundPage := false
-	for _, qi := range m.queryInputs {
-		if qi.key.Value() == "q" && qi.value.Value() == "tapi" {
-			foundQ = true
-		}
-		if qi.key.Value() == "page" && qi.value.Value() == "1" {
-			foundPage = true
-		}
-	}
-	
-	if !foundQ || !foundPage {
-		t.Errorf("Query params parsing failed. FoundQ: %v, FoundPage: %v", foundQ, foundPage)
+	// Rows keep the order of the URL
+	if m.queryInputs[0].key.Value() != "q" || m.queryInputs[0].value.Value() != "tapi" ||
+		m.queryInputs[1].key.Value() != "page" || m.queryInputs[1].value.Value() != "1" {
+		t.Errorf("Query params parsing failed, got %s=%s, %s=%s",
+			m.queryInputs[0].key.Value(), m.queryInputs[0].value.Value(),
+			m.queryInputs[1].key.Value(), m.queryInputs[1].value.Value())
 	}
 }
 
@@ -85,6 +74,32 @@ func TestRequestModel_HighlightVars(t *testing.T) {
 	}
 }
 
+func TestRequestModel_HighlightVars_Functions(t *testing.T) {
+	m := NewRequestModel()
+	m.SetVariables(map[string]string{"user": "ada", "pass": "pw"})
+
+	output := m.highlightVars("Basic {{$base64 {{user}}:{{pass}}}}")
+	if !strings.Contains(output, "YWRhOnB3") {
+		t.Errorf("Highlighter should show evaluated function output, got %q", output)
+	}
+
+	output = m.highlightVars("{{$base64 {{missing}}}}")
+	if !strings.Contains(output, "{{$base64 {{missing}}}}") {
+		t.Errorf("Highlighter should keep unresolvable function tag literal, got %q", output)
+	}
+
+	m.SetVariables(map[string]string{"a": "{{b}}", "b": "{{a}}"})
+	output = m.highlightVars("{{a}}")
+	if !strings.Contains(output, "{{a}}") {
+		t.Errorf("Highlighter should keep cyclic variable literal, got %q", output)
+	}
+
+	output = m.highlightVars("{{$nope}}")
+	if !strings.Contains(output, "{{$nope}}") {
+		t.Errorf("Highlighter should keep unknown function literal, got %q", output)
+	}
+}
+
 func TestRequestModel_SyncURLFromParams(t *testing.T) {
 	m := NewRequestModel()
 	m.pathInput.SetValue("https://api.com/endpoint")
@@ -103,6 +118,63 @@ func TestRequestModel_SyncURLFromParams(t *testing.T) {
 	}
 }
 
+func TestRequestModel_DisabledParams(t *testing.T) {
+	m := NewRequestModel()
+	m.LoadRequest(storage.Request{
+		Method: "GET",
+		URL:    "https://api.com/items",
+		Query: storage.Params{
+			{Key: "page", Value: "1", Enabled: true},
+			{Key: "debug", Value: "true", Enabled: false},
+			{Key: "tag", Value: "{{tag}}", Enabled: true},
+		},
+		Headers: storage.Params{
+			{Key: "Accept", Value: "application/json", Enabled: true},
+			{Key: "Accept", Value: "text/plain", Enabled: true},
+		},
+	}, "")
+
+	// Disabled query params stay out of the URL but keep their row
+	if got := m.pathInput.Value(); got != "https://api.com/items?page=1&tag={{tag}}" {
+		t.Errorf("Expected URL without disabled params, got %q", got)
+	}
+	if len(m.queryInputs) != 3 || !m.queryInputs[1].disabled {
+		t.Fatalf("Expected the disabled row to be kept in place, got %d rows", len(m.queryInputs))
+	}
+
+	// Editing the URL keeps the disabled row
+	m.pathInput.SetValue("https://api.com/items?page=2&tag={{tag}}")
+	m.parseURLParams()
+	if len(m.queryInputs) != 3 || m.queryInputs[1].key.Value() != "debug" || !m.queryInputs[1].disabled {
+		t.Errorf("Disabled row lost after URL edit")
+	}
+
+	// Toggle the second Accept header off
+	m.focusedSection = SectionHeaders
+	m.focusedIndex = 1
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlT})
+
+	req, targetedURL := m.BuildRequest()
+	if req.URL != "https://api.com/items" || targetedURL != "https://api.com/items" {
+		t.Errorf("Expected query to be moved out of the URL, got %q / %q", req.URL, targetedURL)
+	}
+	wantQuery := storage.Params{
+		{Key: "page", Value: "2", Enabled: true},
+		{Key: "debug", Value: "true", Enabled: false},
+		{Key: "tag", Value: "{{tag}}", Enabled: true},
+	}
+	if !reflect.DeepEqual(req.Query, wantQuery) {
+		t.Errorf("Expected query %+v, got %+v", wantQuery, req.Query)
+	}
+	wantHeaders := storage.Params{
+		{Key: "Accept", Value: "application/json", Enabled: true},
+		{Key: "Accept", Value: "text/plain", Enabled: false},
+	}
+	if !reflect.DeepEqual(req.Headers, wantHeaders) {
+		t.Errorf("Expected headers %+v, got %+v", wantHeaders, req.Headers)
+	}
+}
+
 func TestRequestModel_LoadRequest_WithAuth(t *testing.T) {
 	m := NewRequestModel()
 
@@ -110,7 +182,7 @@ func TestRequestModel_LoadRequest_WithAuth(t *testing.T) {
 		Name:   "Auth Request",
 		Method: "POST",
 		URL:    "/api/login",
-		Auth: &storage.BasicAuth{
+		Auth: &storage.Auth{
 			Username: "testuser",
 			Password: "testpass",
 		},
@@ -118,8 +190,8 @@ func TestRequestModel_LoadRequest_WithAuth(t *testing.T) {
 
 	m.LoadRequest(req, "http://example.com")
 
-	if !m.authEnabled {
-		t.Error("Expected authEnabled to be true after loading request with auth")
+	if m.authMode != storage.AuthOverride {
+		t.Error("Expected auth mode override after loading request with auth")
 	}
 	if m.authUsername.Value() != "testuser" {
 		t.Errorf("authUsername = %q, want %q", m.authUsername.Value(), "testuser")
@@ -135,10 +207,10 @@ func TestRequestModel_LoadRequest_WithoutAuth(t *testing.T) {
 	// First load a request with auth to set state
 	m.LoadRequest(storage.Request{
 		Name: "First", Method: "GET", URL: "/first",
-		Auth: &storage.BasicAuth{Username: "user1", Password: "pass1"},
+		Auth: &storage.Auth{Username: "user1", Password: "pass1"},
 	}, "")
 
-	if !m.authEnabled {
+	if m.authMode != storage.AuthOverride {
 		t.Fatal("Expected auth to be enabled after first load")
 	}
 
@@ -147,8 +219,8 @@ func TestRequestModel_LoadRequest_WithoutAuth(t *testing.T) {
 		Name: "Second", Method: "GET", URL: "/second",
 	}, "")
 
-	if m.authEnabled {
-		t.Error("Expected authEnabled to be false after loading request without auth")
+	if m.authMode != storage.AuthInherit {
+		t.Error("Expected auth mode inherit after loading request without auth")
 	}
 	if m.authUsername.Value() != "" {
 		t.Errorf("authUsername should be empty, got %q", m.authUsername.Value())
@@ -163,7 +235,7 @@ func TestRequestModel_BuildRequest_WithAuth(t *testing.T) {
 
 	m.LoadRequest(storage.Request{
 		Name: "Auth Req", Method: "GET", URL: "/protected",
-		Auth: &storage.BasicAuth{Username: "admin", Password: "secret"},
+		Auth: &storage.Auth{Username: "admin", Password: "secret"},
 	}, "")
 
 	req, _ := m.BuildRequest()
@@ -184,11 +256,11 @@ func TestRequestModel_BuildRequest_AuthDisabled(t *testing.T) {
 
 	m.LoadRequest(storage.Request{
 		Name: "Auth Req", Method: "GET", URL: "/protected",
-		Auth: &storage.BasicAuth{Username: "admin", Password: "secret"},
+		Auth: &storage.Auth{Username: "admin", Password: "secret"},
 	}, "")
 
 	// Now disable auth
-	m.authEnabled = false
+	m.authMode = storage.AuthInherit
 
 	req, _ := m.BuildRequest()
 
@@ -215,12 +287,12 @@ func TestRequestModel_AuthToggle(t *testing.T) {
 	m := NewRequestModel()
 
 	// Initially disabled
-	if m.authEnabled {
-		t.Error("Auth should be disabled by default")
+	if m.authMode != storage.AuthInherit {
+		t.Error("Auth should be inherited by default")
 	}
 
 	// Toggle on
-	m.authEnabled = true
+	m.authMode = storage.AuthOverride
 	m.authUsername.SetValue("user")
 	m.authPassword.SetValue("pass")
 
@@ -233,7 +305,7 @@ func TestRequestModel_AuthToggle(t *testing.T) {
 	}
 
 	// Toggle off — credentials remain in fields but BuildRequest excludes them
-	m.authEnabled = false
+	m.authMode = storage.AuthInherit
 	req2, _ := m.BuildRequest()
 	if req2.Auth != nil {
 		t.Error("Expected nil Auth after disabling")
@@ -244,3 +316,150 @@ func TestRequestModel_AuthToggle(t *testing.T) {
 		t.Error("Username field should retain value when auth is disabled")
 	}
 }
+
+func TestRequestModel_InheritedAuthAndHeaders(t *testing.T) {
+	m := NewRequestModel()
+	m.LoadRequest(storage.Request{
+		Name: "Users", Method: "GET", URL: "/users",
+		Headers: storage.Params{{Key: "X-Team", Value: "mine", Enabled: true}},
+	}, "")
+	m.SetInherited(storage.Collection{
+		Name:    "API",
+		Headers: storage.Params{{Key: "X-Team", Value: "api", Enabled: true}, {Key: "X-Trace", Value: "on", Enabled: true}},
+		Auth:    &storage.Auth{Username: "svc", Password: "hunter2"},
+	}.Inherited())
+
+	// Inherited rows the request does not override are shown with their source
+	view := m.View()
+	if !strings.Contains(view, "X-Trace : on  (from API)") || strings.Contains(view, "X-Team : api") {
+		t.Errorf("Expected only X-Trace as an inherited row, got:\n%s", view)
+	}
+	if !strings.Contains(view, "User: svc") || strings.Contains(view, "hunter2") {
+		t.Errorf("Expected the inherited auth with a masked password, got:\n%s", view)
+	}
+
+	// Ctrl+B cycles inherit -> basic -> none
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlB})
+	if m.authMode != storage.AuthOverride {
+		t.Fatalf("Expected override after the first Ctrl+B, got %q", m.authMode)
+	}
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlB})
+	req, _ := m.BuildRequest()
+	if req.AuthMode != storage.AuthNone || req.Auth != nil {
+		t.Errorf("Expected auth mode none to be saved, got %q %+v", req.AuthMode, req.Auth)
+	}
+
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlB})
+	req, _ = m.BuildRequest()
+	if req.AuthMode != "" {
+		t.Errorf("Expected the implied inherit mode not to be saved, got %q", req.AuthMode)
+	}
+}
+
+func TestRequestModel_AuthTypes(t *testing.T) {
+	m := NewRequestModel()
+	m.LoadRequest(storage.Request{Name: "Me", Method: "GET", URL: "/me"}, "")
+
+	// Ctrl+B focuses the type row; → moves from Basic to Bearer
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlB})
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRight})
+	if m.authType != storage.AuthBearer {
+		t.Fatalf("Expected bearer, got %q", m.authType)
+	}
+	m.nextField()
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("{{token}}")})
+	req, _ := m.BuildRequest()
+	if req.Auth == nil || req.Auth.Type != storage.AuthBearer || req.Auth.Token != "{{token}}" {
+		t.Errorf("Expected a bearer token, got %+v", req.Auth)
+	}
+
+	// API key in the query, loaded back as it was saved
+	m.LoadRequest(storage.Request{
+		Name: "Me", Method: "GET", URL: "/me",
+		Auth: &storage.Auth{Type: storage.AuthAPIKey, Key: "api_key", Value: "k1", In: storage.InQuery},
+	}, "")
+	if got := m.authFields(); len(got) != 4 {
+		t.Errorf("Expected type, name, key and location rows, got %v", got)
+	}
+	req, _ = m.BuildRequest()
+	if req.Auth == nil || *req.Auth != (storage.Auth{Type: storage.AuthAPIKey, Key: "api_key", Value: "k1", In: storage.InQuery}) {
+		t.Errorf("API key auth not kept: %+v", req.Auth)
+	}
+	if req.AuthMode != "" {
+		t.Errorf("Expected the implied override mode not to be saved, got %q", req.AuthMode)
+	}
+
+	// OAuth2 rows depend on the grant
+	oauth2 := storage.Auth{Type: storage.AuthOAuth2, Grant: storage.GrantPassword, TokenURL: "https://auth/token", ClientID: "cli", Username: "alice", Password: "pw"}
+	m.LoadRequest(storage.Request{Name: "Me", Method: "GET", URL: "/me", Auth: &oauth2}, "")
+	if got := m.authFields(); len(got) != 8 {
+		t.Errorf("Expected 8 rows for the password grant, got %d", len(got))
+	}
+	req, _ = m.BuildRequest()
+	if req.Auth == nil || *req.Auth != oauth2 {
+		t.Errorf("OAuth2 auth not kept: %+v", req.Auth)
+	}
+
+	// HMAC keeps the settings that have no row
+	hmac := storage.Auth{Type: storage.AuthHMAC, SecretKey: "{{secret}}", Algorithm: storage.HMACSHA512, TimestampHeader: "X-Date", Encoding: "base64"}
+	m.LoadRequest(storage.Request{Name: "Me", Method: "GET", URL: "/me", Auth: &hmac}, "")
+	if got := m.authFields(); len(got) != 4 {
+		t.Errorf("Expected type, secret, algorithm and header rows, got %v", got)
+	}
+	req, _ = m.BuildRequest()
+	if req.Auth == nil || *req.Auth != hmac {
+		t.Errorf("HMAC auth not kept: %+v", req.Auth)
+	}
+}
+
+func TestRequestModel_PreviewShowsVariableScopes(t *testing.T) {
+	m := NewRequestModel()
+	m.SetScopes(storage.Scopes{
+		Collection:  map[string]string{"host": "api.test"},
+		Environment: map[string]string{"token": "env-token"},
+	})
+	m.LoadRequest(storage.Request{
+		Method:    "GET",
+		URL:       "https://{{host}}/{{id}}",
+		Headers:   storage.Params{{Key: "Authorization", Value: "Bearer {{token}}", Enabled: true}},
+		Variables: map[string]string{"id": "42"},
+	}, "")
+	m.Preview = true
+
+	vars := stripANSI(m.renderVarSources())
+	for _, want := range []string{"host : api.test  (collection)", "id : 42  (request)", "token : env-token  (environment)"} {
+		if !strings.Contains(vars, want) {
+			t.Errorf("Expected %q in variables preview, got:\n%s", want, vars)
+		}
+	}
+}
+
+func TestRequestModel_PreviewMasksSecrets(t *testing.T) {
+	m := NewRequestModel()
+	m.SetScopes(storage.Scopes{
+		Environment: map[string]string{"token": "s3cr3t", "auth": "Bearer {{token}}", "host": "api.test"},
+		Secrets:     []string{"token"},
+	})
+
+	for _, input := range []string{"{{token}}", "{{auth}}", "{{$base64 {{token}}}}"} {
+		if output := m.highlightVars(input); strings.Contains(output, "s3cr3t") || !strings.Contains(output, storage.Mask) {
+			t.Errorf("Expected %s to be masked, got %q", input, output)
+		}
+	}
+	if output := m.highlightVars("{{host}}"); !strings.Contains(output, "api.test") {
+		t.Errorf("Expected non-secret value to be shown, got %q", output)
+	}
+}
+
+func TestRequestModel_NoCookies(t *testing.T) {
+	m := NewRequestModel()
+	m.LoadRequest(storage.Request{Name: "Login", Method: "POST", URL: "/login", NoCookies: true}, "")
+	if req, _ := m.BuildRequest(); !req.NoCookies {
+		t.Error("Expected no_cookies to be kept")
+	}
+
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlO})
+	if req, _ := m.BuildRequest(); req.NoCookies {
+		t.Error("Expected Ctrl+O to send cookies again")
+	}
+}
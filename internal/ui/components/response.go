@@ -6,7 +6,9 @@ import (
 	"github.com/styltsou/tapi/internal/ui/styles"
 
 	"fmt"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/alecthomas/chroma/v2/formatters"
 	"github.com/alecthomas/chroma/v2/lexers"
@@ -22,12 +24,18 @@ import (
 
 // ResponseModel handles the response viewer
 type ResponseModel struct {
-	Width    int
-	Height   int
-	loading  bool
-	response *http.ProcessedResponse
-	request  storage.Request
-	viewport viewport.Model
+	Width     int
+	Height    int
+	loading   bool
+	cancelled bool
+	response  *http.ProcessedResponse
+	request   storage.Request
+	viewport  viewport.Model
+	tests     []http.AssertionResult // assertion results for the current response
+	view      responseView           // body, raw request or timing
+
+	downloading string        // file the loading response is saved to, "" when not downloading
+	progress    http.Progress // of the download so far
 
 	// Search state
 	searchInput   textinput.Model
@@ -38,6 +46,16 @@ type ResponseModel struct {
 	currentMatch  int           // index of current highlighted match (0-based)
 }
 
+// responseView is what the response pane shows
+type responseView int
+
+const (
+	viewBody   responseView = iota // status, tests, headers and body
+	viewRaw                        // the exchange as on the wire
+	viewTiming                     // the timing waterfall
+	viewTLS                        // the TLS connection and certificates
+)
+
 // SearchMatch represents a single match in the body text
 type SearchMatch struct {
 	StartByte int // byte offset in raw body
@@ -63,17 +81,45 @@ func (m *ResponseModel) SetSize(width, height int) {
 	m.Height = height
 	m.viewport.Width = width - 4
 	// Adjust viewport height. We reserve a bit of space for search bar if needed,
-	// but mostly it's just the pane.
-	m.viewport.Height = max(1, height-2)
+	// but mostly it's just the pane, below the tab bar.
+	m.viewport.Height = max(1, height-3)
 }
 
 func (m *ResponseModel) SetLoading(loading bool) {
 	m.loading = loading
+	m.downloading = ""
+	m.progress = http.Progress{}
+	if loading {
+		m.cancelled = false
+	}
+}
+
+// SetDownloading shows the loading response as a download to path
+func (m *ResponseModel) SetDownloading(path string) {
+	m.downloading = path
+}
+
+// SetProgress updates the progress of the download
+func (m *ResponseModel) SetProgress(progress http.Progress) {
+	m.progress = progress
+}
+
+// SetCancelled shows the cancelled state in place of a response
+func (m *ResponseModel) SetCancelled() {
+	m.loading = false
+	m.cancelled = true
+	m.response = nil
+	m.clearSearch()
 }
 
 func (m *ResponseModel) SetResponse(resp *http.ProcessedResponse, req storage.Request) {
+	m.cancelled = false
 	m.response = resp
 	m.request = req
+	m.tests = nil
+	if resp != nil {
+		m.tests = resp.Evaluate(req.Assertions)
+	}
 
 	// Clear any previous search
 	m.clearSearch()
@@ -97,6 +143,7 @@ func (m *ResponseModel) clearSearch() {
 func (m *ResponseModel) Clear() {
 	m.response = nil
 	m.request = storage.Request{}
+	m.tests = nil
 	m.clearSearch()
 	m.viewport.SetContent(styles.DimStyle.Render("No response yet. Execute a request to see results."))
 }
@@ -234,6 +281,14 @@ func (m *ResponseModel) countHeaderLines() int {
 	if m.response.Truncated {
 		lines += 2
 	}
+	// REDIRECTS label + one line per hop + blank
+	if len(m.response.Redirects) > 0 {
+		lines += len(m.response.Redirects) + 2
+	}
+	// TESTS label + one line per assertion + blank
+	if len(m.tests) > 0 {
+		lines += len(m.tests) + 2
+	}
 	// HEADERS label + header lines + blank
 	lines += 1
 	for _, values := range m.response.Headers {
@@ -249,6 +304,14 @@ func (m *ResponseModel) formatResponse() string {
 	if m.response == nil {
 		return styles.DimStyle.Render("No response yet. Execute a request to see results.")
 	}
+	switch m.view {
+	case viewRaw:
+		return m.formatRaw()
+	case viewTiming:
+		return m.formatTiming()
+	case viewTLS:
+		return m.formatTLS()
+	}
 
 	var sb strings.Builder
 
@@ -265,7 +328,18 @@ func (m *ResponseModel) formatResponse() string {
 	sb.WriteString("\n\n")
 
 	if m.response.Truncated {
-		sb.WriteString(styles.ErrorStatusStyle.Render("⚠️  Response truncated (>10MB)") + "\n\n")
+		sb.WriteString(styles.ErrorStatusStyle.Render(fmt.Sprintf("⚠️  Response truncated at %s (max_body_mb), SPC d downloads all of it", m.response.FormatSize())) + "\n\n")
+	}
+
+	if len(m.response.Redirects) > 0 {
+		sb.WriteString(m.formatRedirects())
+		sb.WriteString("\n")
+	}
+
+	// Assertion results
+	if len(m.tests) > 0 {
+		sb.WriteString(m.formatTests())
+		sb.WriteString("\n")
 	}
 
 	// Headers
@@ -282,6 +356,15 @@ func (m *ResponseModel) formatResponse() string {
 	sb.WriteString(styles.HeaderStyle.Render("BODY"))
 	sb.WriteString("\n")
 
+	if dl := m.response.Download; dl != nil {
+		saved := fmt.Sprintf("Saved %s to %s", http.FormatBytes(dl.Size), dl.Path)
+		if dl.Resumed {
+			saved += fmt.Sprintf(" (resumed, %s received)", m.response.FormatSize())
+		}
+		sb.WriteString(styles.DimStyle.Render(saved))
+		return sb.String()
+	}
+
 	body := m.response.BodyString()
 	contentType := m.response.GetHeader("Content-Type")
 
@@ -299,6 +382,312 @@ func (m *ResponseModel) formatResponse() string {
 	return sb.String()
 }
 
+// formatRaw renders the exchange as it went over the wire: the request with
+// every header added by auth, signing, cookies and the transport, then the
+// response status line and headers. Without a capture (capture_wire off) the
+// request is rebuilt from what was sent.
+func (m *ResponseModel) formatRaw() string {
+	var sb strings.Builder
+	sb.WriteString(styles.HeaderStyle.Render("RAW REQUEST"))
+	sb.WriteString("\n")
+	for i, hop := range m.response.Redirects {
+		sb.WriteString(styles.DimStyle.Render(fmt.Sprintf("Redirect %d: %s %s", i+1, hop.Method, hop.URL)) + "\n")
+		sb.WriteString(formatHop(hop) + "\n")
+	}
+	switch {
+	case m.response.Wire != nil:
+		sb.WriteString(strings.TrimRight(m.response.Wire.Request, "\n") + "\n\n")
+		sb.WriteString(styles.HeaderStyle.Render("RAW RESPONSE"))
+		sb.WriteString("\n")
+		sb.WriteString(m.response.Wire.Response)
+	case m.response.Request != nil:
+		sb.WriteString(m.response.Request.Raw() + "\n\n")
+		sb.WriteString(styles.DimStyle.Render("Rebuilt from the request; set capture_wire: true in config.yaml for the exact bytes."))
+	default:
+		sb.WriteString(styles.DimStyle.Render("The request was not recorded."))
+	}
+	return sb.String()
+}
+
+// formatHop renders a redirect response: status line and headers
+func formatHop(hop http.RedirectHop) string {
+	var sb strings.Builder
+	sb.WriteString(hop.Status + "\n")
+	keys := make([]string, 0, len(hop.Headers))
+	for key := range hop.Headers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		for _, value := range hop.Headers[key] {
+			sb.WriteString(key + ": " + value + "\n")
+		}
+	}
+	return sb.String()
+}
+
+// rawText returns what the Raw view copies to the clipboard: the redirect
+// responses, then the final exchange
+func (m *ResponseModel) rawText() string {
+	var sb strings.Builder
+	for _, hop := range m.response.Redirects {
+		sb.WriteString(hop.Method + " " + hop.URL + "\n" + formatHop(hop) + "\n")
+	}
+	switch {
+	case m.response.Wire != nil:
+		sb.WriteString(m.response.Wire.String())
+	case m.response.Request != nil:
+		sb.WriteString(m.response.Request.Raw())
+	}
+	return sb.String()
+}
+
+// responseTabs are the views of the response pane, with the key showing them
+var responseTabs = []struct {
+	view  responseView
+	label string
+}{
+	{viewBody, "Body"},
+	{viewRaw, "Raw R"},
+	{viewTiming, "Timing T"},
+	{viewTLS, "TLS S"},
+}
+
+// renderTabs renders the bar switching between the views of the response
+func (m ResponseModel) renderTabs() string {
+	var tabs []string
+	for _, tab := range responseTabs {
+		style := lipgloss.NewStyle().
+			Padding(0, 1).
+			Foreground(lipgloss.Color("#555555")).
+			Background(lipgloss.Color("#1a1b26"))
+		if tab.view == m.view {
+			style = style.
+				Foreground(lipgloss.Color("#ffffff")).
+				Background(lipgloss.Color("#7D56F4")).
+				Bold(true)
+		}
+		label := tab.label
+		if tab.view == viewTLS && m.certExpiring() {
+			// Flagged so an expiring certificate is noticed without opening the tab
+			label += " !"
+			style = style.Foreground(styles.ErrorColor)
+		}
+		tabs = append(tabs, style.Render(label))
+	}
+	return lipgloss.JoinHorizontal(lipgloss.Top, tabs...)
+}
+
+// timingBarWidth bounds the bars of the timing waterfall
+const timingBarWidth = 48
+
+// formatTiming renders the phases of the request as a waterfall, each bar
+// placed at its offset from the start of the request
+func (m *ResponseModel) formatTiming() string {
+	t := m.response.Timings
+	var sb strings.Builder
+	sb.WriteString(styles.HeaderStyle.Render("TIMING"))
+	sb.WriteString("\n")
+	if t.Total <= 0 {
+		sb.WriteString(styles.DimStyle.Render("No timings were recorded."))
+		return sb.String()
+	}
+
+	connection := "New connection"
+	if t.Reused {
+		connection = "Reused connection"
+	}
+	if t.RemoteAddr != "" {
+		connection += " to " + t.RemoteAddr
+	}
+	sb.WriteString(styles.DimStyle.Render(connection) + "\n\n")
+
+	width := max(10, min(timingBarWidth, m.viewport.Width-30))
+	column := func(d time.Duration) int {
+		return int(int64(d) * int64(width) / int64(t.Total))
+	}
+	phases := []struct {
+		name  string
+		span  http.Span
+		color lipgloss.Color
+		setup bool // skipped on a reused connection
+	}{
+		{"DNS lookup", t.DNS, lipgloss.Color("#00BFFF"), true},
+		{"TCP connect", t.Connect, lipgloss.Color("#FFA500"), true},
+		{"TLS handshake", t.TLS, lipgloss.Color("#DA70D6"), true},
+		{"Waiting (TTFB)", t.Wait, styles.SecondaryColor, false},
+		{"Content transfer", t.Transfer, lipgloss.Color("#1E90FF"), false},
+	}
+	for _, p := range phases {
+		label := fmt.Sprintf("%-20s", p.name)
+		if p.span.Duration <= 0 {
+			note := "—"
+			if t.Reused && p.setup {
+				note = "reused"
+			}
+			sb.WriteString(label + styles.DimStyle.Render(strings.Repeat(" ", width+2)+note) + "\n")
+			continue
+		}
+		start := min(column(p.span.Start), width-1)
+		length := max(1, min(column(p.span.Duration), width-start))
+		bar := strings.Repeat(" ", start) + lipgloss.NewStyle().Foreground(p.color).Render(strings.Repeat("█", length))
+		sb.WriteString(label + bar + strings.Repeat(" ", width-start-length+2) + formatTimingDuration(p.span.Duration) + "\n")
+	}
+	sb.WriteString("\n")
+	sb.WriteString(fmt.Sprintf("%-20s", "Time to first byte") + formatTimingDuration(t.FirstByte()) + "\n")
+	sb.WriteString(fmt.Sprintf("%-20s", "Total") + formatTimingDuration(t.Total) + "\n")
+	return sb.String()
+}
+
+// formatTimingDuration shows a phase length to a tenth of a millisecond
+func formatTimingDuration(d time.Duration) string {
+	if d < time.Millisecond {
+		return d.Round(time.Microsecond).String()
+	}
+	return d.Round(100 * time.Microsecond).String()
+}
+
+// formatProgress renders the download in progress: a bar when the size is
+// known, the bytes received, the rate and the time left
+func (m ResponseModel) formatProgress() string {
+	var sb strings.Builder
+	sb.WriteString(styles.HeaderStyle.Render("DOWNLOADING"))
+	sb.WriteString("\n")
+	sb.WriteString(m.downloading + "\n\n")
+
+	p := m.progress
+	switch {
+	case p.Elapsed == 0 && p.Received == 0:
+		sb.WriteString(styles.DimStyle.Render("Waiting for the response...") + "\n")
+	case p.Fraction() >= 0:
+		width := max(10, min(timingBarWidth, m.Width-12))
+		done := int(p.Fraction() * float64(width))
+		sb.WriteString(lipgloss.NewStyle().Foreground(styles.SecondaryColor).Render(strings.Repeat("█", done)))
+		sb.WriteString(styles.DimStyle.Render(strings.Repeat("░", width-done)))
+		sb.WriteString(fmt.Sprintf(" %3.0f%%\n", p.Fraction()*100))
+		stats := fmt.Sprintf("%s of %s · %s/s", http.FormatBytes(p.Received), http.FormatBytes(p.Total), http.FormatBytes(int64(p.Rate)))
+		if eta := p.ETA(); eta >= 0 {
+			stats += fmt.Sprintf(" · %s left", eta.Round(time.Second))
+		}
+		sb.WriteString(stats + "\n")
+	default:
+		sb.WriteString(fmt.Sprintf("%s · %s/s\n", http.FormatBytes(p.Received), http.FormatBytes(int64(p.Rate))))
+	}
+
+	sb.WriteString("\n" + styles.DimStyle.Render("SPC x to cancel, :download -c resumes"))
+	return sb.String()
+}
+
+// formatTLS renders the TLS connection of the response and the chain of
+// certificates the server presented, flagging those close to expiry
+func (m *ResponseModel) formatTLS() string {
+	info := m.response.TLS
+	var sb strings.Builder
+	sb.WriteString(styles.HeaderStyle.Render("TLS"))
+	sb.WriteString("\n")
+	if info == nil {
+		sb.WriteString(styles.DimStyle.Render("The response did not come over TLS."))
+		return sb.String()
+	}
+
+	field := func(name, value string) {
+		if value != "" {
+			sb.WriteString(fmt.Sprintf("%-14s%s\n", name, value))
+		}
+	}
+	field("Protocol", info.Protocol)
+	field("Version", info.Version)
+	field("Cipher suite", info.CipherSuite)
+	field("Server name", info.ServerName)
+	if info.Resumed {
+		field("Session", "resumed")
+	}
+
+	now := time.Now()
+	sb.WriteString("\n")
+	sb.WriteString(styles.HeaderStyle.Render(fmt.Sprintf("CERTIFICATES %d", len(info.Certificates))))
+	sb.WriteString("\n")
+	for i, cert := range info.Certificates {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString(styles.ParamStyle.Render(fmt.Sprintf("#%d %s", i+1, cert.Subject)) + "\n")
+		if len(cert.SANs) > 0 {
+			field("  SANs", strings.Join(cert.SANs, ", "))
+		}
+		field("  Issuer", cert.Issuer)
+		field("  Valid from", cert.NotBefore.Local().Format(time.DateTime))
+		field("  Expires", cert.NotAfter.Local().Format(time.DateTime))
+		switch {
+		case cert.Expired(now):
+			sb.WriteString(styles.ErrorColorStyle.Render("  ⚠️  Expired") + "\n")
+		case cert.ExpiresWithin(now, http.CertExpiryWarning):
+			days := int(cert.NotAfter.Sub(now).Hours() / 24)
+			sb.WriteString(styles.ErrorColorStyle.Render(fmt.Sprintf("  ⚠️  Expires in %d days", days)) + "\n")
+		}
+	}
+	return sb.String()
+}
+
+// certExpiring reports whether a certificate of the response is expired or
+// close to it
+func (m ResponseModel) certExpiring() bool {
+	if m.response == nil || m.response.TLS == nil {
+		return false
+	}
+	now := time.Now()
+	for _, cert := range m.response.TLS.Certificates {
+		if cert.ExpiresWithin(now, http.CertExpiryWarning) {
+			return true
+		}
+	}
+	return false
+}
+
+// formatRedirects renders the chain of redirects followed to the response,
+// one hop per line
+func (m *ResponseModel) formatRedirects() string {
+	var sb strings.Builder
+	sb.WriteString(styles.HeaderStyle.Render(fmt.Sprintf("REDIRECTS %d", len(m.response.Redirects))))
+	sb.WriteString("\n")
+	for _, hop := range m.response.Redirects {
+		sb.WriteString(fmt.Sprintf("  %s %s %s", styles.ParamStyle.Render(fmt.Sprint(hop.StatusCode)), hop.Method, hop.URL))
+		sb.WriteString(styles.DimStyle.Render(" → ") + hop.Location)
+		sb.WriteString(styles.DimStyle.Render("  "+formatTimingDuration(hop.Duration)) + "\n")
+	}
+	return sb.String()
+}
+
+// formatTests renders the pass/fail panel for the request's assertions
+func (m *ResponseModel) formatTests() string {
+	passed := 0
+	for _, t := range m.tests {
+		if t.Passed {
+			passed++
+		}
+	}
+
+	var sb strings.Builder
+	summary := fmt.Sprintf("TESTS %d/%d passed", passed, len(m.tests))
+	if passed == len(m.tests) {
+		sb.WriteString(styles.HeaderStyle.Render(summary))
+	} else {
+		sb.WriteString(styles.HeaderStyle.Copy().Foreground(styles.ErrorColor).Render(summary))
+	}
+	sb.WriteString("\n")
+
+	passStyle := lipgloss.NewStyle().Foreground(styles.SecondaryColor)
+	for _, t := range m.tests {
+		if t.Passed {
+			sb.WriteString(passStyle.Render("  ✓ ") + t.Assertion.String() + "\n")
+		} else {
+			sb.WriteString(styles.ErrorColorStyle.Render("  ✗ ") + t.Assertion.String() +
+				styles.DimStyle.Render(" — "+t.Message) + "\n")
+		}
+	}
+	return sb.String()
+}
+
 func highlight(content string, contentType string) string {
 	lexer := lexers.Get(contentType)
 	if lexer == nil {
@@ -371,7 +760,7 @@ func (m ResponseModel) Update(msg tea.Msg) (ResponseModel, tea.Cmd) {
 			return m, nil
 
 		case "/":
-			if m.response != nil {
+			if m.response != nil && m.view == viewBody {
 				m.searching = true
 				m.searchInput.Focus()
 				m.searchInput.SetValue("")
@@ -396,7 +785,36 @@ func (m ResponseModel) Update(msg tea.Msg) (ResponseModel, tea.Cmd) {
 				return m, nil
 			}
 
+		case "R", "T", "S":
+			if m.response != nil {
+				target := viewRaw
+				switch msg.String() {
+				case "T":
+					target = viewTiming
+				case "S":
+					target = viewTLS
+				}
+				if m.view == target {
+					target = viewBody
+				}
+				m.view = target
+				m.clearSearch()
+				m.viewport.SetContent(m.formatResponse())
+				m.viewport.GotoTop()
+				return m, nil
+			}
+
 		case "c":
+			if m.response != nil && m.view == viewRaw {
+				raw := m.rawText()
+				if raw == "" {
+					return m, nil
+				}
+				if err := clipboard.WriteAll(raw); err != nil {
+					return m, commands.ShowStatusCmd("Failed to copy the raw exchange", true)
+				}
+				return m, commands.ShowStatusCmd("Raw exchange copied to clipboard", false)
+			}
 			if m.response != nil {
 				err := clipboard.WriteAll(m.response.BodyString())
 				if err != nil {
@@ -413,8 +831,16 @@ func (m ResponseModel) Update(msg tea.Msg) (ResponseModel, tea.Cmd) {
 }
 
 func (m ResponseModel) View() string {
+	if m.loading && m.downloading != "" {
+		return m.formatProgress()
+	}
 	if m.loading {
-		return styles.DimStyle.Render("Loading...\n\nExecuting request...")
+		return styles.DimStyle.Render("Loading...\n\nExecuting request... (SPC x to cancel)")
+	}
+
+	if m.cancelled {
+		return styles.ErrorColorStyle.Render("Request cancelled.") + "\n" +
+			styles.DimStyle.Render("Run it again with SPC r.")
 	}
 
 	if m.response == nil {
@@ -422,6 +848,8 @@ func (m ResponseModel) View() string {
 	}
 
 	var view strings.Builder
+	view.WriteString(m.renderTabs())
+	view.WriteString("\n")
 
 	// Search bar (shown when searching or when matches are active)
 	if m.searching || m.searchActive {
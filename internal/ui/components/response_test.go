@@ -1,9 +1,11 @@
 package components
 
 import (
-
+	"strings"
 	"testing"
 
+	tea "github.com/charmbracelet/bubbletea"
+
 	"time"
 
 	"github.com/styltsou/tapi/internal/http"
@@ -455,6 +457,76 @@ func TestCountHeaderLines(t *testing.T) {
 	}
 }
 
+func TestResponseModel_TestsPanel(t *testing.T) {
+	m := NewResponseModel()
+	m.SetSize(100, 40)
+	req := storage.Request{Assertions: []storage.Assertion{
+		{Target: "status", Op: "equals", Value: "200"},
+		{Target: "json", Property: "$.ok", Op: "equals", Value: "false"},
+	}}
+	m.SetResponse(mockResponse(`{"ok": true}`), req)
+
+	content := stripANSI(m.formatResponse())
+	for _, want := range []string{"TESTS 1/2 passed", "✓ status equals 200", "✗ json $.ok equals false"} {
+		if !containsText(content, want) {
+			t.Errorf("Expected %q in tests panel, got:\n%s", want, content)
+		}
+	}
+
+	withoutTests := NewResponseModel()
+	withoutTests.SetResponse(mockResponse(`{"ok": true}`), storage.Request{})
+	if m.countHeaderLines() != withoutTests.countHeaderLines()+4 {
+		t.Errorf("Expected tests panel to add 4 header lines, got %d vs %d",
+			m.countHeaderLines(), withoutTests.countHeaderLines())
+	}
+}
+
+func TestResponseModel_RawRequest(t *testing.T) {
+	m := NewResponseModel()
+	m.SetSize(100, 40)
+	resp := mockResponse(`{"ok": true}`)
+	resp.Request = &http.SentRequest{
+		Method:  "POST",
+		URL:     "https://api.example.com/orders?dry_run=true",
+		Headers: map[string][]string{"X-Signature": {"abc123"}, "X-Timestamp": {"1700000000"}},
+		Body:    `{"item":1}`,
+	}
+	m.SetResponse(resp, storage.Request{})
+
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("R")})
+	content := stripANSI(m.formatResponse())
+	for _, want := range []string{"RAW REQUEST", "POST /orders?dry_run=true HTTP/1.1", "Host: api.example.com", "X-Signature: abc123", `{"item":1}`} {
+		if !containsText(content, want) {
+			t.Errorf("Expected %q in raw request, got:\n%s", want, content)
+		}
+	}
+
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("R")})
+	if content := stripANSI(m.formatResponse()); containsText(content, "RAW REQUEST") {
+		t.Errorf("Expected R to switch back to the response, got:\n%s", content)
+	}
+
+	// A wire capture replaces the rebuilt request and adds the response headers
+	resp.Wire = &http.WireDump{
+		Request:  "POST /orders?dry_run=true HTTP/1.1\nHost: api.example.com\nUser-Agent: Go-http-client/1.1\n\n{\"item\":1}",
+		Response: "HTTP/1.1 201 Created\nX-Trace: t-1\n\n",
+	}
+	m.SetResponse(resp, storage.Request{})
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("R")})
+	content = stripANSI(m.formatResponse())
+	for _, want := range []string{"User-Agent: Go-http-client/1.1", "RAW RESPONSE", "HTTP/1.1 201 Created", "X-Trace: t-1"} {
+		if !containsText(content, want) {
+			t.Errorf("Expected %q in the raw view, got:\n%s", want, content)
+		}
+	}
+	if raw := m.rawText(); !containsText(raw, "User-Agent") || !containsText(raw, "X-Trace: t-1") {
+		t.Errorf("Expected the copied text to hold the request and response, got:\n%s", raw)
+	}
+	if view := stripANSI(m.View()); !containsText(view, "Raw R") || !containsText(view, "Timing T") {
+		t.Errorf("Expected the tab bar in the view, got:\n%s", view)
+	}
+}
+
 // helper
 func containsText(s, substr string) bool {
 	// Strip ANSI for plain text check
@@ -502,3 +574,162 @@ func stripANSI(s string) string {
 	}
 	return string(result)
 }
+
+func TestResponseModel_Timing(t *testing.T) {
+	m := NewResponseModel()
+	m.SetSize(100, 40)
+	resp := mockResponse(`{"ok": true}`)
+	resp.Timings = http.Timings{
+		DNS:        http.Span{Start: 0, Duration: 5 * time.Millisecond},
+		Connect:    http.Span{Start: 5 * time.Millisecond, Duration: 10 * time.Millisecond},
+		TLS:        http.Span{Start: 15 * time.Millisecond, Duration: 25 * time.Millisecond},
+		Wait:       http.Span{Start: 41 * time.Millisecond, Duration: 50 * time.Millisecond},
+		Transfer:   http.Span{Start: 91 * time.Millisecond, Duration: 9 * time.Millisecond},
+		Total:      100 * time.Millisecond,
+		RemoteAddr: "93.184.216.34:443",
+	}
+	m.SetResponse(resp, storage.Request{})
+
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("T")})
+	content := stripANSI(m.formatResponse())
+	for _, want := range []string{"TIMING", "New connection to 93.184.216.34:443", "TLS handshake", "25ms", "Time to first byte", "91ms", "Total", "100ms"} {
+		if !containsText(content, want) {
+			t.Errorf("Expected %q in timing view, got:\n%s", want, content)
+		}
+	}
+
+	// A reused connection has no setup phases
+	resp.Timings = http.Timings{
+		Wait:   http.Span{Start: 1 * time.Millisecond, Duration: 30 * time.Millisecond},
+		Total:  32 * time.Millisecond,
+		Reused: true,
+	}
+	m.SetResponse(resp, storage.Request{})
+	content = stripANSI(m.formatResponse())
+	if !containsText(content, "Reused connection") || !containsText(content, "reused") {
+		t.Errorf("Expected the connection to be shown as reused, got:\n%s", content)
+	}
+
+	// R switches to the raw request, T back from timing to the response
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("R")})
+	if content := stripANSI(m.formatResponse()); !containsText(content, "RAW REQUEST") {
+		t.Errorf("Expected R to show the raw request, got:\n%s", content)
+	}
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("T")})
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("T")})
+	if content := stripANSI(m.formatResponse()); !containsText(content, "BODY") {
+		t.Errorf("Expected T twice to go back to the response, got:\n%s", content)
+	}
+}
+
+func TestResponseModel_Redirects(t *testing.T) {
+	m := NewResponseModel()
+	m.SetSize(120, 40)
+	resp := mockResponse(`{"ok": true}`)
+	resp.Redirects = []http.RedirectHop{
+		{Method: "GET", URL: "http://example.com/old", StatusCode: 301, Status: "301 Moved Permanently", Location: "/moved", Headers: map[string][]string{"Location": {"/moved"}}, Duration: 4 * time.Millisecond},
+		{Method: "GET", URL: "http://example.com/moved", StatusCode: 302, Status: "302 Found", Location: "https://example.com/final", Headers: map[string][]string{"Location": {"https://example.com/final"}, "Set-Cookie": {"a=1"}}, Duration: 2 * time.Millisecond},
+	}
+	m.SetResponse(resp, storage.Request{})
+
+	content := stripANSI(m.formatResponse())
+	for _, want := range []string{"REDIRECTS 2", "301 GET http://example.com/old → /moved", "302 GET http://example.com/moved → https://example.com/final", "4ms"} {
+		if !containsText(content, want) {
+			t.Errorf("Expected %q in the response, got:\n%s", want, content)
+		}
+	}
+
+	// The raw view has the headers of each hop
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("R")})
+	content = stripANSI(m.formatResponse())
+	for _, want := range []string{"Redirect 2: GET http://example.com/moved", "302 Found", "Set-Cookie: a=1"} {
+		if !containsText(content, want) {
+			t.Errorf("Expected %q in the raw view, got:\n%s", want, content)
+		}
+	}
+	if raw := m.rawText(); !containsText(raw, "GET http://example.com/old\n301 Moved Permanently\nLocation: /moved\n") {
+		t.Errorf("Expected the copied exchange to have the first hop, got:\n%s", raw)
+	}
+}
+
+func TestResponseModel_TLS(t *testing.T) {
+	m := NewResponseModel()
+	m.SetSize(120, 40)
+	resp := mockResponse(`{"ok": true}`)
+	resp.TLS = &http.TLSInfo{
+		Protocol:    "h2",
+		Version:     "TLS 1.3",
+		CipherSuite: "TLS_AES_128_GCM_SHA256",
+		Certificates: []http.CertInfo{
+			{Subject: "CN=api.example.com", Issuer: "CN=Example CA", SANs: []string{"api.example.com", "10.0.0.1"}, NotAfter: time.Now().AddDate(0, 0, 12).Add(time.Hour)},
+			{Subject: "CN=Example CA", Issuer: "CN=Example Root", NotAfter: time.Now().AddDate(5, 0, 0)},
+		},
+	}
+	m.SetResponse(resp, storage.Request{})
+	if !m.certExpiring() {
+		t.Error("Expected the expiring certificate to be flagged")
+	}
+
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("S")})
+	content := stripANSI(m.formatResponse())
+	for _, want := range []string{"Protocol", "h2", "TLS 1.3", "TLS_AES_128_GCM_SHA256", "CERTIFICATES 2", "#1 CN=api.example.com", "api.example.com, 10.0.0.1", "CN=Example Root", "Expires in 12 days"} {
+		if !containsText(content, want) {
+			t.Errorf("Expected %q in the TLS view, got:\n%s", want, content)
+		}
+	}
+	if strings.Count(content, "Expires in") != 1 {
+		t.Errorf("Expected only the leaf to be flagged, got:\n%s", content)
+	}
+
+	// Plain HTTP
+	resp.TLS = nil
+	m.SetResponse(resp, storage.Request{})
+	if content := stripANSI(m.formatResponse()); !containsText(content, "did not come over TLS") {
+		t.Errorf("Expected a note for plain HTTP, got:\n%s", content)
+	}
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("S")})
+	if content := stripANSI(m.formatResponse()); !containsText(content, "BODY") {
+		t.Errorf("Expected S again to go back to the response, got:\n%s", content)
+	}
+}
+
+func TestResponseModel_DownloadProgress(t *testing.T) {
+	m := NewResponseModel()
+	m.SetSize(100, 40)
+	m.SetLoading(true)
+	m.SetDownloading("/tmp/big.iso")
+	if view := stripANSI(m.View()); !containsText(view, "/tmp/big.iso") || !containsText(view, "Waiting for the response") {
+		t.Errorf("Expected the download to wait for the response, got:\n%s", view)
+	}
+
+	m.SetProgress(http.Progress{Received: 25 << 20, Total: 100 << 20, Elapsed: 5 * time.Second, Rate: 5 << 20})
+	view := stripANSI(m.View())
+	for _, want := range []string{"25%", "25.0 MB of 100.0 MB", "5.0 MB/s", "15s left", "SPC x to cancel"} {
+		if !containsText(view, want) {
+			t.Errorf("Expected %q in the progress, got:\n%s", want, view)
+		}
+	}
+
+	// Without Content-Length there is no bar or ETA
+	m.SetProgress(http.Progress{Received: 3 << 20, Total: -1, Elapsed: time.Second, Rate: 3 << 20})
+	view = stripANSI(m.View())
+	if !containsText(view, "3.0 MB · 3.0 MB/s") || containsText(view, "left") {
+		t.Errorf("Expected only the bytes and rate, got:\n%s", view)
+	}
+
+	// A new request is not a download
+	m.SetLoading(true)
+	if view := stripANSI(m.View()); containsText(view, "DOWNLOADING") {
+		t.Errorf("Expected a plain loading state, got:\n%s", view)
+	}
+
+	// The saved file replaces the body
+	resp := mockResponse("")
+	resp.Size = 4 << 20
+	resp.Download = &http.DownloadInfo{Path: "/tmp/big.iso", Size: 100 << 20, Resumed: true}
+	m.SetResponse(resp, storage.Request{})
+	m.SetLoading(false)
+	if content := stripANSI(m.formatResponse()); !containsText(content, "Saved 100.0 MB to /tmp/big.iso (resumed, 4.0 MB received)") {
+		t.Errorf("Expected the saved file, got:\n%s", content)
+	}
+}
@@ -1,14 +1,21 @@
 package components
 
 import (
+	"context"
+
 	"github.com/styltsou/tapi/internal/http"
 	"github.com/styltsou/tapi/internal/storage"
 )
 
 // RequestTab represents an open request tab
 type RequestTab struct {
-	Request  storage.Request
-	BaseURL  string
-	Response *http.ProcessedResponse
-	Label    string // e.g. "GET /users"
+	CollectionID string // the tab's request is identified by Request.ID
+	Request      storage.Request
+	BaseURL      string
+	Inherited    storage.Inherited // headers and auth from the request's collection and folders
+	Response     *http.ProcessedResponse
+	Label        string             // e.g. "GET /users"
+	Cancel       context.CancelFunc // aborts the in-flight request, nil when idle
+	Send         uint64             // ID of the in-flight request, matched by its outcome; 0 when idle
+	DownloadTo   string             // file the in-flight request is downloaded to
 }
@@ -0,0 +1,167 @@
+package components
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/styltsou/tapi/internal/oauth"
+	"github.com/styltsou/tapi/internal/storage"
+	uimsg "github.com/styltsou/tapi/internal/ui/msg"
+	"github.com/styltsou/tapi/internal/ui/styles"
+)
+
+// TokenModel shows the cached OAuth2 token of the active request and lets the
+// user renew or clear it
+type TokenModel struct {
+	Visible bool
+	Width   int
+	Height  int
+
+	auth    storage.Auth
+	env     string
+	token   oauth.Token
+	cached  bool
+	loading bool
+	err     error
+}
+
+func NewTokenModel() TokenModel {
+	return TokenModel{}
+}
+
+func (m *TokenModel) SetSize(width, height int) {
+	m.Width = width
+	m.Height = height
+}
+
+// Show opens the view for an oauth2 auth; the token arrives with TokenLoadedMsg
+func (m *TokenModel) Show(auth storage.Auth, env string) {
+	m.Visible = true
+	m.auth = auth
+	m.env = env
+	m.token = oauth.Token{}
+	m.cached = false
+	m.loading = true
+	m.err = nil
+}
+
+// Auth returns the oauth2 auth the view was opened for
+func (m TokenModel) Auth() storage.Auth {
+	return m.auth
+}
+
+// SetToken updates the view after a lookup, refresh or clear
+func (m *TokenModel) SetToken(msg uimsg.TokenLoadedMsg) {
+	m.token = msg.Token
+	m.cached = msg.Cached
+	m.err = msg.Err
+	m.loading = false
+}
+
+func (m TokenModel) Update(msg tea.Msg) (TokenModel, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch msg.String() {
+		case "esc", "q":
+			m.Visible = false
+		case "r":
+			if !m.loading {
+				m.loading = true
+				auth := m.auth
+				return m, func() tea.Msg { return uimsg.RefreshTokenMsg{Auth: auth} }
+			}
+		case "c":
+			if !m.loading {
+				m.loading = true
+				auth := m.auth
+				return m, func() tea.Msg { return uimsg.ClearTokenMsg{Auth: auth} }
+			}
+		}
+	}
+	return m, nil
+}
+
+func (m TokenModel) View() string {
+	if !m.Visible {
+		return ""
+	}
+
+	bg := styles.DarkGray
+	labelStyle := lipgloss.NewStyle().Foreground(styles.Gray).Background(bg).Width(15)
+	valueStyle := lipgloss.NewStyle().Background(bg)
+	titleStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("#FFFFFF")).
+		Background(lipgloss.Color("#7D56F4")).
+		Bold(true).
+		Padding(0, 2).
+		MarginBottom(1)
+
+	env := m.env
+	if env == "" {
+		env = "no environment"
+	}
+
+	var sb strings.Builder
+	sb.WriteString(titleStyle.Render("OAuth2 Token · " + env))
+	sb.WriteString("\n")
+	row := func(label, value string) {
+		sb.WriteString(labelStyle.Render(label) + valueStyle.Render(value) + "\n")
+	}
+
+	row("Grant", m.auth.EffectiveGrant())
+	row("Token URL", m.auth.TokenURL)
+	row("Client", m.auth.ClientID)
+	sb.WriteString("\n")
+
+	switch {
+	case m.loading:
+		row("Status", "working…")
+	case m.err != nil:
+		row("Status", lipgloss.NewStyle().Foreground(styles.ErrorColor).Background(bg).Render(m.err.Error()))
+	case !m.cached:
+		row("Status", "no cached token")
+	default:
+		row("Status", tokenStatus(m.token))
+		row("Access token", abbreviateToken(m.token.AccessToken))
+		if m.token.TokenType != "" {
+			row("Type", m.token.TokenType)
+		}
+		if m.token.Scope != "" {
+			row("Scope", m.token.Scope)
+		}
+		refresh := "no"
+		if m.token.RefreshToken != "" {
+			refresh = "yes"
+		}
+		row("Refresh token", refresh)
+	}
+
+	sb.WriteString("\n")
+	sb.WriteString(lipgloss.NewStyle().Foreground(styles.Gray).Background(bg).Render("r renew · c clear · esc close"))
+
+	width := min(60, m.Width-4)
+	content := styles.Solidify(sb.String(), width, lipgloss.NewStyle().Background(bg))
+	return styles.ModalStyle.Render(content)
+}
+
+// tokenStatus describes when a token expires
+func tokenStatus(t oauth.Token) string {
+	switch {
+	case t.Expiry.IsZero():
+		return "valid, no expiry"
+	case !t.Valid():
+		return "expired " + t.Expiry.Format("15:04:05")
+	default:
+		return fmt.Sprintf("valid, expires in %s", time.Until(t.Expiry).Round(time.Second))
+	}
+}
+
+// abbreviateToken shows the start of a token only, enough to tell tokens apart
+func abbreviateToken(token string) string {
+	if len(token) <= 12 {
+		return storage.Mask
+	}
+	return token[:8] + "…" + storage.Mask
+}
@@ -85,7 +85,7 @@ func (m WelcomeModel) Update(msg tea.Msg) (WelcomeModel, tea.Cmd) {
 				return m, func() tea.Msg {
 					return uimsg.ConfirmActionMsg{
 						Title: "Delete collection: " + styles.ErrorColorStyle.Render(col.Name),
-						OnConfirm: uimsg.DeleteCollectionMsg{Name: col.Name},
+						OnConfirm: uimsg.DeleteCollectionMsg{CollectionID: col.ID},
 					}
 				}
 			}
@@ -127,7 +127,7 @@ func (m WelcomeModel) View() string {
 	const minListWidth = 40
 	listWidth := minListWidth
 	for _, col := range m.collections {
-		reqCount := fmt.Sprintf("%d requests", len(col.Requests))
+		reqCount := fmt.Sprintf("%d requests", col.RequestCount())
 		w := len(col.Name) + len(reqCount) + 4 // +4 for spacing
 		if w > listWidth {
 			listWidth = w
@@ -163,7 +163,7 @@ func (m WelcomeModel) View() string {
 		listItems = append(listItems, styles.DimStyle.Render("No collections yet. Create one to get started!"))
 	} else {
 		for i, col := range m.collections {
-			reqCount := fmt.Sprintf("%d requests", len(col.Requests))
+			reqCount := fmt.Sprintf("%d requests", col.RequestCount())
 			// Use spaces to push reqCount to the right, or just simple spacing
 			// For a true "space-between" effect we need to calculate padding manually or use lipgloss.PlaceHorizontal
 			
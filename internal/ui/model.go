@@ -1,6 +1,8 @@
 package ui
 
 import (
+	"context"
+
 	"github.com/styltsou/tapi/internal/ui/commands"
 	"github.com/styltsou/tapi/internal/ui/components"
 	"github.com/styltsou/tapi/internal/ui/keys"
@@ -12,8 +14,10 @@ import (
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/styltsou/tapi/internal/config"
+	"github.com/styltsou/tapi/internal/cookies"
 	"github.com/styltsou/tapi/internal/http"
 	"github.com/styltsou/tapi/internal/logger"
+	"github.com/styltsou/tapi/internal/oauth"
 	"github.com/styltsou/tapi/internal/storage"
 )
 
@@ -64,6 +68,8 @@ type Model struct {
 	menu        components.CommandMenuModel
 	collectionSelector components.CollectionSelectorModel
 	helpOverlay components.HelpOverlayModel
+	tokenView   components.TokenModel
+	cookieView  components.CookiesModel
 	help        help.Model
 	keys        keys.KeyMap
 	Width       int
@@ -86,10 +92,15 @@ type Model struct {
 	// HTTP client
 	httpClient *http.Client
 	cfg        config.Config
+	cancel     context.CancelFunc // in-flight request when no tab is open
+	send       uint64             // ID of that request
+	sends      uint64             // last request ID handed out
 
 	// Current context
 	currentCollection *storage.Collection
 	currentEnv        *storage.Environment
+	globals           map[string]string // ~/.tapi/globals.yaml
+	runtimeVars       map[string]string // values extracted from responses, override the env
 
 	// Status line
 	statusText  string
@@ -113,11 +124,25 @@ func NewModel(cfg config.Config) Model {
 	ci.Placeholder = ""
 	ci.CharLimit = 100
 
+	httpClient := http.NewClient(cfg.TimeoutDuration())
+	httpClient.CaptureWire = cfg.CaptureWire
+	httpClient.MaxBodySize = cfg.MaxBodySize()
+	if store, err := oauth.DefaultStore(); err == nil {
+		httpClient.Tokens = oauth.NewManager(store)
+	}
+	if cfg.CookieJar {
+		if jar, err := cookies.DefaultJar(); err == nil {
+			httpClient.Cookies = jar
+		} else {
+			logger.Logger.Warn("Cookie jar unavailable", "error", err)
+		}
+	}
+
 	return Model{
 		state:       uimsg.ViewWelcome,
 		focusedPane: PaneCollections,
 		keys:        keys.DefaultKeyMap(),
-		httpClient:  http.NewClient(cfg.TimeoutDuration()),
+		httpClient:  httpClient,
 		cfg:         cfg,
 		sidebarVisible: true,
 		mode:         ModeNormal,
@@ -135,6 +160,8 @@ func NewModel(cfg config.Config) Model {
 		menu:        components.NewCommandMenuModel(),
 		collectionSelector: components.NewCollectionSelectorModel(),
 		helpOverlay: components.NewHelpOverlayModel(),
+		tokenView:   components.NewTokenModel(),
+		cookieView:  components.NewCookiesModel(),
 		help:        help.New(),
 	}
 }
@@ -147,6 +174,7 @@ func (m Model) Init() tea.Cmd {
 		tea.EnterAltScreen,
 		commands.LoadCollectionsCmd(),
 		commands.LoadEnvsCmd(),
+		commands.LoadGlobalsCmd(),
 	)
 }
 
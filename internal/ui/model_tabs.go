@@ -1,6 +1,11 @@
 package ui
 
 import (
+	"context"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/styltsou/tapi/internal/storage"
+	"github.com/styltsou/tapi/internal/ui/commands"
 	"github.com/styltsou/tapi/internal/ui/components"
 )
 
@@ -17,6 +22,7 @@ func (m *Model) loadActiveTab() {
 	if m.activeTab >= 0 && m.activeTab < len(m.tabs) {
 		tab := m.tabs[m.activeTab]
 		m.request.LoadRequest(tab.Request, tab.BaseURL)
+		m.request.SetInherited(tab.Inherited)
 
 		if tab.Response != nil {
 			m.response.SetResponse(tab.Response, tab.Request)
@@ -26,6 +32,13 @@ func (m *Model) loadActiveTab() {
 			m.response = components.NewResponseModel()
 			m.response.SetSize(w, h)
 		}
+		// A request still running in the tab
+		if tab.Send != 0 {
+			m.response.SetLoading(true)
+			if tab.DownloadTo != "" {
+				m.response.SetDownloading(tab.DownloadTo)
+			}
+		}
 	}
 }
 
@@ -34,6 +47,9 @@ func (m *Model) closeTab(index int) {
 		return
 	}
 
+	if m.tabs[index].Cancel != nil {
+		m.tabs[index].Cancel()
+	}
 	m.tabs = append(m.tabs[:index], m.tabs[index+1:]...)
 
 	if m.activeTab >= len(m.tabs) {
@@ -55,3 +71,106 @@ func (m *Model) closeTab(index int) {
 		m.loadActiveTab()
 	}
 }
+
+// activeCollectionID returns the ID of the collection the active tab's request belongs to
+func (m Model) activeCollectionID() string {
+	if m.activeTab >= 0 && m.activeTab < len(m.tabs) {
+		return m.tabs[m.activeTab].CollectionID
+	}
+	return ""
+}
+
+// activeInherited returns what the active tab's request inherits from its
+// collection and folders
+func (m Model) activeInherited() storage.Inherited {
+	if m.activeTab >= 0 && m.activeTab < len(m.tabs) {
+		return m.tabs[m.activeTab].Inherited
+	}
+	return storage.Inherited{}
+}
+
+// startSend records a request started from the active tab, or from the
+// pane when no tab is open, and returns the ID its outcome comes back with
+func (m *Model) startSend(cancel context.CancelFunc, downloadTo string) uint64 {
+	m.sends++
+	if m.activeTab >= 0 && m.activeTab < len(m.tabs) {
+		tab := &m.tabs[m.activeTab]
+		tab.Cancel, tab.Send, tab.DownloadTo = cancel, m.sends, downloadTo
+	} else {
+		m.cancel, m.send = cancel, m.sends
+	}
+	return m.sends
+}
+
+// finishSend clears the request with the given ID and returns the tab it was
+// sent from, -1 for the pane without tabs. ok is false when the request is
+// unknown, its tab having been closed.
+func (m *Model) finishSend(send uint64) (tab int, ok bool) {
+	if send == 0 {
+		return 0, false
+	}
+	for i := range m.tabs {
+		if m.tabs[i].Send == send {
+			if m.tabs[i].Cancel != nil {
+				m.tabs[i].Cancel()
+			}
+			m.tabs[i].Cancel, m.tabs[i].Send, m.tabs[i].DownloadTo = nil, 0, ""
+			return i, true
+		}
+	}
+	if m.send == send {
+		if m.cancel != nil {
+			m.cancel()
+		}
+		m.cancel, m.send = nil, 0
+		return -1, true
+	}
+	return 0, false
+}
+
+// shows reports whether the response pane shows the given tab, -1 standing
+// for the pane without tabs
+func (m Model) shows(tab int) bool {
+	if tab == -1 {
+		return len(m.tabs) == 0
+	}
+	return tab == m.activeTab
+}
+
+// sendTab returns the tab the request with the given ID was sent from, as
+// finishSend does, leaving the request in flight
+func (m Model) sendTab(send uint64) (tab int, ok bool) {
+	if send == 0 {
+		return 0, false
+	}
+	for i := range m.tabs {
+		if m.tabs[i].Send == send {
+			return i, true
+		}
+	}
+	return -1, m.send == send
+}
+
+// takeActiveCancel returns and clears the cancel handle of the active tab.
+// The request stays known by its ID until its outcome arrives.
+func (m *Model) takeActiveCancel() context.CancelFunc {
+	var cancel context.CancelFunc
+	if m.activeTab >= 0 && m.activeTab < len(m.tabs) {
+		cancel = m.tabs[m.activeTab].Cancel
+		m.tabs[m.activeTab].Cancel = nil
+	} else {
+		cancel = m.cancel
+		m.cancel = nil
+	}
+	return cancel
+}
+
+// cancelActiveRequest aborts the request running in the active tab, if any
+func (m *Model) cancelActiveRequest() tea.Cmd {
+	cancel := m.takeActiveCancel()
+	if cancel == nil {
+		return commands.ShowStatusCmd("No request in progress", false)
+	}
+	cancel()
+	return nil
+}
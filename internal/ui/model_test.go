@@ -13,7 +13,7 @@ import (
 )
 
 func TestMain(m *testing.M) {
-	_ = logger.Init()
+	logger.InitDiscard()
 	os.Exit(m.Run())
 }
 
@@ -3,7 +3,9 @@ package msg
 
 import (
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/styltsou/tapi/internal/cookies"
 	"github.com/styltsou/tapi/internal/http"
+	"github.com/styltsou/tapi/internal/oauth"
 
 	"github.com/styltsou/tapi/internal/storage"
 )
@@ -61,23 +63,54 @@ type ErrMsg struct {
 // ExecuteRequestMsg is sent by RequestBuilder to MainModel to execute a request
 type ExecuteRequestMsg struct {
 	Request     storage.Request
-	BaseURL     string // Base URL from the collection
-	TargetedURL string // The actual URL to execute (after substitutions)
+	BaseURL     string            // Base URL from the collection
+	TargetedURL string            // The actual URL to execute (after substitutions)
+	Inherited   storage.Inherited // headers and auth from the request's collection and folders
+	DownloadTo  string            // save the body to this file instead of showing it
+	Resume      bool              // continue a partial DownloadTo file
 }
 
 // ResponseReadyMsg is sent by HTTP Client to MainModel when a response is received
 type ResponseReadyMsg struct {
+	Send     uint64 // ID of the request, see RequestTab.Send
 	Response *http.ProcessedResponse
 	Request  storage.Request // Include original request for context
 }
 
+// DownloadProgressMsg reports how far a download got. Updates delivers the
+// next report, then the outcome of the download.
+type DownloadProgressMsg struct {
+	Send     uint64
+	Progress http.Progress
+	Updates  <-chan tea.Msg
+}
+
+// RequestCancelledMsg is sent when an in-flight request was aborted by the user
+type RequestCancelledMsg struct {
+	Send    uint64
+	Request storage.Request
+}
+
+// RequestFailedMsg is sent when a request got no response
+type RequestFailedMsg struct {
+	Send uint64
+	Err  error
+}
+
 // ========================================
 // Storage Messages
 // ========================================
 
 // SaveRequestMsg is sent when user presses Ctrl+S to save a request
 type SaveRequestMsg struct {
-	Request storage.Request
+	CollectionID string
+	Request      storage.Request // matched by Request.ID
+}
+
+// RequestSavedMsg is sent when a request is successfully saved
+type RequestSavedMsg struct {
+	CollectionID string
+	Request      storage.Request
 }
 
 // SaveCollectionMsg is sent to save an entire collection
@@ -87,8 +120,8 @@ type SaveCollectionMsg struct {
 
 // DeleteRequestMsg is sent to delete a request
 type DeleteRequestMsg struct {
-	CollectionName string
-	RequestName    string
+	CollectionID string
+	RequestID    string
 }
 
 // ========================================
@@ -117,14 +150,41 @@ type CreateRequestMsg struct {
 
 // RequestCreatedMsg is sent when a request is successfully created
 type RequestCreatedMsg struct {
-	CollectionName string
-	Request        storage.Request
+	CollectionID string
+	Request      storage.Request
+}
+
+// MoveRequestMsg moves a request into a folder, given as a "/" separated path
+// (empty for the collection root)
+type MoveRequestMsg struct {
+	CollectionID string
+	RequestID    string
+	FolderPath   string
+}
+
+// RequestMovedMsg is sent when a request was moved to another folder
+type RequestMovedMsg struct {
+	CollectionID string
+	RequestID    string
+	FolderPath   string
+}
+
+// CreateFolderMsg creates a folder (and missing parents) in a collection
+type CreateFolderMsg struct {
+	CollectionID string
+	Path         string
+}
+
+// FolderCreatedMsg is sent when a folder was created
+type FolderCreatedMsg struct {
+	CollectionID string
+	Path         string
 }
 
 // RequestDeletedMsg is sent when a request is successfully deleted
 type RequestDeletedMsg struct {
-	CollectionName string
-	RequestName    string
+	CollectionID string
+	RequestID    string
 }
 
 // DeleteRequestMsg is sent to delete a request
@@ -134,29 +194,30 @@ type CreateCollectionMsg struct {
 }	
 
 type DeleteCollectionMsg struct {
-	Name string
+	CollectionID string
 }
 
 // CollectionDeletedMsg is sent when a collection is successfully deleted
 type CollectionDeletedMsg struct {
-	Name string
+	CollectionID string
 }
 
 type RenameCollectionMsg struct {
-	OldName string
-	NewName string
+	CollectionID string
+	NewName      string
 }
 
 // CollectionRenamedMsg is sent when a collection is successfully renamed
 type CollectionRenamedMsg struct {
-	OldName string
-	NewName string
+	CollectionID string
+	NewName      string
 }
 
 // PromptForInputMsg triggers the input modal
 type PromptForInputMsg struct {
 	Title       string
 	Placeholder string
+	Secret      bool // mask the typed value
 	OnCommit    func(string) tea.Msg
 }
 
@@ -193,8 +254,10 @@ type CollectionsLoadedMsg struct {
 
 // RequestSelectedMsg is sent when a request is selected from a collection
 type RequestSelectedMsg struct {
-	Request storage.Request
-	BaseURL string // Base URL from parent collection
+	CollectionID string
+	Request      storage.Request
+	BaseURL      string            // Base URL from parent collection
+	Inherited    storage.Inherited // headers and auth from the request's collection and folders
 }
 
 // ========================================
@@ -212,6 +275,16 @@ type EnvsLoadedMsg struct {
 	Envs []storage.Environment
 }
 
+// UnlockSecretsMsg is sent when the user enters the passphrase for encrypted secrets
+type UnlockSecretsMsg struct {
+	Passphrase string
+}
+
+// GlobalsLoadedMsg is sent when the global variables are loaded
+type GlobalsLoadedMsg struct {
+	Variables map[string]string
+}
+
 // EnvSavedMsg is sent when an environment is successfully saved
 type EnvSavedMsg struct {
 	Env storage.Environment
@@ -275,14 +348,14 @@ type ConfirmActionMsg struct {
 
 // DuplicateRequestMsg duplicates a request in a collection
 type DuplicateRequestMsg struct {
-	CollectionName string
-	RequestName    string
+	CollectionID string
+	RequestID    string
 }
 
 // RequestDuplicatedMsg is sent when a request is successfully duplicated
 type RequestDuplicatedMsg struct {
-	CollectionName string
-	RequestName    string
+	CollectionID string
+	Request      storage.Request // the copy
 }
 
 // ClearStatusMsg clears the status bar text (used for auto-dismiss)
@@ -295,3 +368,46 @@ type CopyAsCurlMsg struct{}
 type ClearNotificationMsg struct {
 	ID int
 }
+
+// ========================================
+// OAuth2 Token Messages
+// ========================================
+
+// RefreshTokenMsg requests a new access token for an oauth2 auth
+type RefreshTokenMsg struct {
+	Auth storage.Auth
+}
+
+// ClearTokenMsg removes the cached access token of an oauth2 auth
+type ClearTokenMsg struct {
+	Auth storage.Auth
+}
+
+// TokenLoadedMsg carries the cached token of an oauth2 auth after a lookup,
+// refresh or clear
+type TokenLoadedMsg struct {
+	Token  oauth.Token
+	Cached bool
+	Err    error
+}
+
+// ========================================
+// Cookie Jar Messages
+// ========================================
+
+// SaveCookieMsg stores an edited cookie in the jar
+type SaveCookieMsg struct {
+	Cookie cookies.Cookie
+}
+
+// DeleteCookieMsg removes a cookie from the jar
+type DeleteCookieMsg struct {
+	Cookie cookies.Cookie
+}
+
+// CookiesLoadedMsg carries the cookies of the active environment after a
+// lookup, edit or delete
+type CookiesLoadedMsg struct {
+	Cookies []cookies.Cookie
+	Err     error
+}
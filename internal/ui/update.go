@@ -6,12 +6,17 @@ import (
 	"github.com/styltsou/tapi/internal/ui/commands"
 	uimsg "github.com/styltsou/tapi/internal/ui/msg"
 
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 
+	"github.com/atotto/clipboard"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/styltsou/tapi/internal/http"
 	"github.com/styltsou/tapi/internal/storage"
+	"github.com/styltsou/tapi/internal/storage/exporter"
 )
 
 // Update handles all messages and updates the model
@@ -82,24 +87,179 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, tea.Batch(cmds...)
 }
 
-// applyCurrentEnv applies the current environment variables to a request
+// scopes returns the variable layers available to requests in the current context.
+// The request scope is filled in per request.
+func (m *Model) scopes() storage.Scopes {
+	s := storage.Scopes{
+		Global:  m.globals,
+		Runtime: m.runtimeVars,
+	}
+	if m.currentCollection != nil {
+		s.Collection = m.currentCollection.Variables
+	}
+	if m.currentEnv != nil {
+		s.Environment = m.currentEnv.Variables
+		s.Secrets = m.currentEnv.Secrets
+	}
+	return s
+}
+
+// variables returns the merged variables used to substitute req
+func (m *Model) variables(req storage.Request) map[string]string {
+	s := m.scopes()
+	s.Request = req.Variables
+	return s.Merged()
+}
+
+// syncVariables pushes the current scopes to the request editor (autocomplete, preview)
+func (m *Model) syncVariables() {
+	m.request.SetScopes(m.scopes())
+}
+
+// applyCurrentEnv applies the variables of every scope to a request
 func (m *Model) applyCurrentEnv(req storage.Request) storage.Request {
-	if m.currentEnv == nil {
-		return req
+	return storage.SubstituteRequest(req, m.variables(req))
+}
+
+// clientFor returns the HTTP client with the proxy and TLS settings of a
+// request: config, then the collection, then the environment
+func (m *Model) clientFor(inherited storage.Inherited) (*http.Client, error) {
+	transport := storage.MergeTransports(m.cfg.Transport, inherited.Transport)
+	if m.currentEnv != nil {
+		transport = storage.MergeTransports(transport, m.currentEnv.Transport)
+	}
+	return m.httpClient.WithTransport(transport)
+}
+
+// openTokenView shows the cached OAuth2 token of the active request, with its
+// inherited auth and variables resolved
+func (m *Model) openTokenView() tea.Cmd {
+	req, _ := m.request.BuildRequest()
+	req = m.applyCurrentEnv(m.activeInherited().Apply(req))
+	if req.Auth == nil || req.Auth.EffectiveType() != storage.AuthOAuth2 {
+		return commands.ShowStatusCmd("The request does not use OAuth2", true)
+	}
+	if m.httpClient.Tokens == nil {
+		return commands.ShowStatusCmd("OAuth2 tokens are not available", true)
+	}
+	m.tokenView.Show(*req.Auth, m.httpClient.Tokens.Environment())
+	return commands.LoadTokenCmd(m.httpClient.Tokens, *req.Auth)
+}
+
+// openCookieView lists the cookies of the active environment
+func (m *Model) openCookieView() tea.Cmd {
+	if m.httpClient.Cookies == nil {
+		return commands.ShowStatusCmd("The cookie jar is off (cookie_jar in config.yaml)", true)
+	}
+	m.cookieView.Show(m.httpClient.Cookies.Environment())
+	return commands.LoadCookiesCmd(m.httpClient.Cookies)
+}
+
+// copyAsCurl copies the current request as a cURL command. Literal secret values
+// are replaced by their {{name}} placeholder.
+func (m *Model) copyAsCurl() tea.Cmd {
+	req, targetedURL := m.request.BuildRequest()
+	if targetedURL != "" {
+		req.URL = targetedURL
+	}
+	req = m.activeInherited().Apply(req)
+	curlCmd := exporter.ExportCurl(req, m.request.BaseURL)
+	if m.currentEnv != nil {
+		curlCmd = storage.RedactSecretValues(curlCmd, m.currentEnv.SecretValues())
+	}
+	if err := clipboard.WriteAll(curlCmd); err != nil {
+		return commands.ShowStatusCmd("Failed to copy cURL", true)
+	}
+	return commands.ShowStatusCmd("cURL copied to clipboard", false)
+}
+
+// downloadRequest returns the message running the current request as a
+// download to path, or to a file named after the URL when path is empty
+func (m *Model) downloadRequest(path string, resume bool) uimsg.ExecuteRequestMsg {
+	req, targetedURL := m.request.BuildRequest()
+	if path == "" {
+		named := req
+		if targetedURL != "" {
+			named.URL = targetedURL
+		}
+		path = downloadName(m.applyCurrentEnv(named).URL)
+	}
+	return uimsg.ExecuteRequestMsg{
+		Request:     req,
+		BaseURL:     m.request.BaseURL,
+		TargetedURL: targetedURL,
+		Inherited:   m.activeInherited(),
+		DownloadTo:  path,
+		Resume:      resume,
+	}
+}
+
+// promptDownload asks where to download the response of the current request
+func (m *Model) promptDownload() tea.Cmd {
+	name := m.downloadRequest("", false).DownloadTo
+	return func() tea.Msg {
+		return uimsg.PromptForInputMsg{
+			Title:       "Download Response",
+			Placeholder: name,
+			OnCommit: func(val string) tea.Msg {
+				return downloadPathMsg{Path: strings.TrimSpace(val)}
+			},
+		}
+	}
+}
+
+// downloadName is the file a response is saved to when none is given: the
+// last segment of the URL path, as curl -O does
+func downloadName(rawURL string) string {
+	if _, httpURL, ok := storage.SplitUnixURL(rawURL); ok {
+		rawURL = httpURL
+	}
+	if u, err := url.Parse(rawURL); err == nil {
+		if name := path.Base(u.Path); name != "." && name != "/" {
+			return name
+		}
+	}
+	return "download"
+}
+
+// applyExtractions stores values extracted from a response as runtime variables.
+// Rules marked persist are also written back to the active environment file.
+func (m *Model) applyExtractions(resp *http.ProcessedResponse, rules []storage.Extraction) tea.Cmd {
+	if resp == nil || len(rules) == 0 {
+		return nil
 	}
 
-	req.URL = storage.Substitute(req.URL, m.currentEnv.Variables)
-	req.Body = storage.Substitute(req.Body, m.currentEnv.Variables)
-	for k, v := range req.Headers {
-		req.Headers[k] = storage.Substitute(v, m.currentEnv.Variables)
+	vars, err := resp.Extract(rules)
+	if m.runtimeVars == nil {
+		m.runtimeVars = make(map[string]string)
+	}
+	for k, v := range vars {
+		m.runtimeVars[k] = v
 	}
+	m.syncVariables()
 
-	if req.Auth != nil {
-		req.Auth.Username = storage.Substitute(req.Auth.Username, m.currentEnv.Variables)
-		req.Auth.Password = storage.Substitute(req.Auth.Password, m.currentEnv.Variables)
+	var cmds []tea.Cmd
+	if err != nil {
+		cmds = append(cmds, commands.ShowStatusCmd(err.Error(), true))
+	}
+
+	if m.currentEnv != nil {
+		persisted := false
+		env := *m.currentEnv
+		env.Variables = storage.MergeVariables(env.Variables)
+		for _, rule := range rules {
+			if v, ok := vars[rule.Var]; ok && rule.Persist {
+				env.Variables[rule.Var] = v
+				persisted = true
+			}
+		}
+		if persisted {
+			m.currentEnv = &env
+			cmds = append(cmds, commands.PersistEnvCmd(env))
+		}
 	}
 
-	return req
+	return tea.Batch(cmds...)
 }
 
 func (m Model) executeCommand(cmdStr string) (Model, tea.Cmd, bool) {
@@ -121,15 +281,45 @@ func (m Model) executeCommand(cmdStr string) (Model, tea.Cmd, bool) {
 		return m, tea.Quit, true
 	case "w", "write":
 		req, _ := m.request.BuildRequest()
+		collectionID := m.activeCollectionID()
 		return m, func() tea.Msg {
-			return uimsg.SaveRequestMsg{Request: req}
+			return uimsg.SaveRequestMsg{CollectionID: collectionID, Request: req}
 		}, true
 	case "wq", "x":
 		req, _ := m.request.BuildRequest()
 		return m, tea.Sequence(
-			func() tea.Msg { return uimsg.SaveRequestMsg{Request: req} },
+			commands.SaveRequestCmd(m.activeCollectionID(), req),
 			tea.Quit,
 		), true
+	case "cancel":
+		return m, m.cancelActiveRequest(), true
+	case "download":
+		// :download [-c] [path], -c continues a partial file
+		resume := len(parts) > 1 && parts[1] == "-c"
+		if resume {
+			parts = parts[1:]
+		}
+		path := ""
+		if len(parts) > 1 {
+			path = strings.Join(parts[1:], " ")
+		}
+		execute := m.downloadRequest(path, resume)
+		return m, func() tea.Msg { return execute }, true
+	case "unlock":
+		return m, func() tea.Msg {
+			return uimsg.PromptForInputMsg{
+				Title:       "Unlock Secrets",
+				Placeholder: "Passphrase",
+				Secret:      true,
+				OnCommit: func(val string) tea.Msg {
+					return uimsg.UnlockSecretsMsg{Passphrase: val}
+				},
+			}
+		}, true
+	case "clearvars":
+		m.runtimeVars = nil
+		m.syncVariables()
+		return m, commands.ShowStatusCmd("Runtime variables cleared", false), true
 	case "h", "help":
 		m.helpOverlay.Toggle()
 		return m, nil, true
@@ -143,6 +333,12 @@ type confirmedDeleteEnvMsg struct {
 	Name string
 }
 
+// downloadPathMsg is the internal message sent with the path entered in the
+// download prompt, empty for the default name
+type downloadPathMsg struct {
+	Path string
+}
+
 // expandTilde replaces a leading ~ with the user's home directory
 func expandTilde(path string) string {
 	if strings.HasPrefix(path, "~/") || path == "~" {
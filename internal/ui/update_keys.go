@@ -1,12 +1,9 @@
 package ui
 
 import (
-	"github.com/atotto/clipboard"
 	"github.com/charmbracelet/bubbles/cursor"
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
-	"github.com/styltsou/tapi/internal/storage/exporter"
-	"github.com/styltsou/tapi/internal/ui/commands"
 	uimsg "github.com/styltsou/tapi/internal/ui/msg"
 )
 
@@ -23,6 +20,20 @@ func (m Model) handleKeyMsg(msg tea.KeyMsg) (Model, tea.Cmd, bool) {
 		return m, helpCmd, true
 	}
 
+	// The token view handles its own keys
+	if m.tokenView.Visible {
+		newToken, tokenCmd := m.tokenView.Update(msg)
+		m.tokenView = newToken
+		return m, tokenCmd, true
+	}
+
+	// So does the cookie view
+	if m.cookieView.Visible {
+		newCookies, cookiesCmd := m.cookieView.Update(msg)
+		m.cookieView = newCookies
+		return m, cookiesCmd, true
+	}
+
 	// If a modal is open, route to it
 	if m.menu.Visible || m.env.Visible || m.state == uimsg.ViewEnvEditor || m.collectionSelector.Visible || m.state == uimsg.ViewInput || m.state == uimsg.ViewConfirm {
 		// Let modals handle their own keys (handled below in routing section of generic Update)
@@ -58,13 +69,15 @@ func (m Model) handleKeyMsg(msg tea.KeyMsg) (Model, tea.Cmd, bool) {
 		case "r":
 			// Trigger request execution from request model
 			req, targetedURL := m.request.BuildRequest()
+			inherited := m.activeInherited()
 			return m, func() tea.Msg {
-				return uimsg.ExecuteRequestMsg{Request: req, BaseURL: m.request.BaseURL, TargetedURL: targetedURL}
+				return uimsg.ExecuteRequestMsg{Request: req, BaseURL: m.request.BaseURL, TargetedURL: targetedURL, Inherited: inherited}
 			}, true
 		case "s":
 			req, _ := m.request.BuildRequest()
+			collectionID := m.activeCollectionID()
 			return m, func() tea.Msg {
-				return uimsg.SaveRequestMsg{Request: req}
+				return uimsg.SaveRequestMsg{CollectionID: collectionID, Request: req}
 			}, true
 		case "p":
 			m.focusedPane = PaneRequest
@@ -79,16 +92,19 @@ func (m Model) handleKeyMsg(msg tea.KeyMsg) (Model, tea.Cmd, bool) {
 			return m, nil, true
 		case "y":
 			// Copy as cURL
-			req, targetedURL := m.request.BuildRequest()
-			if targetedURL != "" {
-				req.URL = targetedURL
-			}
-			curlCmd := exporter.ExportCurl(req, m.request.BaseURL)
-			err := clipboard.WriteAll(curlCmd)
-			if err != nil {
-				return m, commands.ShowStatusCmd("Failed to copy cURL", true), true
-			}
-			return m, commands.ShowStatusCmd("cURL copied to clipboard", false), true
+			return m, m.copyAsCurl(), true
+		case "a":
+			// Inspect the OAuth2 token of the request
+			return m, m.openTokenView(), true
+		case "k":
+			// Manage the cookies of the environment
+			return m, m.openCookieView(), true
+		case "d":
+			// Download the response body to a file
+			return m, m.promptDownload(), true
+		case "x":
+			// Cancel the in-flight request
+			return m, m.cancelActiveRequest(), true
 		case "w":
 			// Close current tab
 			if len(m.tabs) > 0 {
@@ -1,10 +1,16 @@
 package ui
 
 import (
+	nethttp "net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/styltsou/tapi/internal/config"
+	"github.com/styltsou/tapi/internal/http"
 	"github.com/styltsou/tapi/internal/storage"
 	"github.com/styltsou/tapi/internal/ui/components"
 	uimsg "github.com/styltsou/tapi/internal/ui/msg"
@@ -89,21 +95,89 @@ func TestModel_Update_CreateRequestClosesModal(t *testing.T) {
 	}
 }
 
+func TestModel_Update_CollectionMsgsMatchByID(t *testing.T) {
+	m := NewModel(config.DefaultConfig())
+	m.currentCollection = &storage.Collection{ID: "col-1", Name: "Users"}
+	m.state = uimsg.ViewCollectionList
+
+	// Another collection with the same name is not this one
+	m2, _ := m.Update(uimsg.CollectionRenamedMsg{CollectionID: "col-2", NewName: "Accounts"})
+	m = m2.(Model)
+	if m.currentCollection.Name != "Users" {
+		t.Errorf("Expected the current collection to keep its name, got %q", m.currentCollection.Name)
+	}
+	m2, _ = m.Update(uimsg.CollectionRenamedMsg{CollectionID: "col-1", NewName: "Accounts"})
+	m = m2.(Model)
+	if m.currentCollection.Name != "Accounts" {
+		t.Errorf("Expected the current collection to be renamed, got %q", m.currentCollection.Name)
+	}
+
+	m2, _ = m.Update(uimsg.CollectionDeletedMsg{CollectionID: "col-1"})
+	m = m2.(Model)
+	if m.currentCollection != nil || m.state != uimsg.ViewWelcome {
+		t.Errorf("Expected the deleted collection to be closed, got %+v in state %v", m.currentCollection, m.state)
+	}
+}
+
+func TestModel_Update_CreateCollectionThenRequest(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	m := NewModel(config.DefaultConfig())
+	m.state = uimsg.ViewInput
+
+	m2, cmd := m.Update(uimsg.CreateCollectionMsg{Name: "Orders"})
+	m = m2.(Model)
+	selected, ok := cmd().(uimsg.CollectionSelectedMsg)
+	if !ok {
+		t.Fatalf("Expected the new collection to be selected, got %T", cmd())
+	}
+	if selected.Collection.ID == "" {
+		t.Fatal("Expected the selected collection to have an ID")
+	}
+	m2, _ = m.Update(selected)
+	m = m2.(Model)
+
+	m2, cmd = m.Update(uimsg.CreateRequestMsg{Name: "List orders"})
+	m = m2.(Model)
+	created, ok := cmd().(uimsg.RequestCreatedMsg)
+	if !ok {
+		t.Fatalf("Expected the request to be created, got %+v", cmd())
+	}
+	if created.CollectionID != selected.Collection.ID {
+		t.Errorf("Expected the request in collection %s, got %s", selected.Collection.ID, created.CollectionID)
+	}
+
+	// The reloaded collection is still the current one
+	collections, _, err := storage.LoadCollections()
+	if err != nil {
+		t.Fatalf("LoadCollections failed: %v", err)
+	}
+	m2, _ = m.Update(uimsg.CollectionsLoadedMsg{Collections: collections})
+	m = m2.(Model)
+	if m.currentCollection == nil || m.state == uimsg.ViewWelcome {
+		t.Fatalf("Expected the collection to stay open, got %+v in state %v", m.currentCollection, m.state)
+	}
+	if len(m.currentCollection.Requests) != 1 || m.currentCollection.Requests[0].Name != "List orders" {
+		t.Errorf("Expected the new request in the collection, got %+v", m.currentCollection.Requests)
+	}
+}
+
 func TestModel_Update_RequestDeletedMsg(t *testing.T) {
 	m := NewModel(config.DefaultConfig())
 	m.state = uimsg.ViewInput // Simulate confirmation modal open
 	
-	// Setup a tab for the request to be deleted
+	// Setup a tab for the request to be deleted, and one for a request with the
+	// same name in another collection
 	reqName := "Delete Me"
 	m.tabs = []components.RequestTab{
-		{Request: storage.Request{Name: reqName}, BaseURL: "http://test.com"},
+		{CollectionID: "col-1", Request: storage.Request{ID: "req-1", Name: reqName}, BaseURL: "http://test.com"},
+		{CollectionID: "col-2", Request: storage.Request{ID: "req-2", Name: reqName}, BaseURL: "http://test.com"},
 	}
 	m.activeTab = 0
 	
 	// Send RequestDeletedMsg
 	msg := uimsg.RequestDeletedMsg{
-		CollectionName: "Col",
-		RequestName:    reqName,
+		CollectionID: "col-1",
+		RequestID:    "req-1",
 	}
 	
 	m2, _ := m.Update(msg)
@@ -114,12 +188,20 @@ func TestModel_Update_RequestDeletedMsg(t *testing.T) {
 		t.Errorf("Expected state ViewCollectionList, got %v", m.state)
 	}
 	
-	// Verify tab removed
+	// Verify only the matching tab was removed
+	if len(m.tabs) != 1 || m.tabs[0].Request.ID != "req-2" {
+		t.Fatalf("Expected only the other collection's tab to remain, got %+v", m.tabs)
+	}
+	if m.activeTab != 0 {
+		t.Errorf("Expected activeTab to be 0, got %d", m.activeTab)
+	}
+
+	// Deleting the last tab resets the active tab
+	m2, _ = m.Update(uimsg.RequestDeletedMsg{CollectionID: "col-2", RequestID: "req-2"})
+	m = m2.(Model)
 	if len(m.tabs) != 0 {
 		t.Errorf("Expected tabs to be empty, got %d", len(m.tabs))
 	}
-	
-	// Verify active tab reset
 	if m.activeTab != -1 {
 		t.Errorf("Expected activeTab to be -1, got %d", m.activeTab)
 	}
@@ -155,3 +237,238 @@ func TestModel_CommandExecution(t *testing.T) {
 		t.Error("View returned empty string")
 	}
 }
+
+func TestModel_CancelRequest(t *testing.T) {
+	m := NewModel(config.DefaultConfig())
+	m.state = uimsg.ViewCollectionList
+	m.tabs = []components.RequestTab{{Request: storage.Request{Name: "Slow"}}}
+	m.activeTab = 0
+
+	m2, _ := m.Update(uimsg.ExecuteRequestMsg{Request: storage.Request{Method: "GET", URL: "http://127.0.0.1:1"}})
+	m = m2.(Model)
+	if m.tabs[0].Cancel == nil {
+		t.Fatal("Expected cancel handle to be stored on the active tab")
+	}
+
+	send := m.tabs[0].Send
+	m, _, _ = m.executeCommand("cancel")
+	if m.tabs[0].Cancel != nil {
+		t.Error("Expected cancel handle to be cleared after :cancel")
+	}
+
+	m2, _ = m.Update(uimsg.RequestCancelledMsg{Send: send})
+	m = m2.(Model)
+	if view := m.response.View(); !strings.Contains(view, "cancelled") {
+		t.Errorf("Expected cancelled state in response view, got %q", view)
+	}
+}
+
+func TestModel_RoutesResponsesToTheirTab(t *testing.T) {
+	m := NewModel(config.DefaultConfig())
+	m.state = uimsg.ViewCollectionList
+	m.tabs = []components.RequestTab{
+		{Request: storage.Request{ID: "a", Name: "A"}},
+		{Request: storage.Request{ID: "b", Name: "B"}},
+	}
+
+	// A request runs in each tab, A's first
+	m.activeTab = 0
+	m2, _ := m.Update(uimsg.ExecuteRequestMsg{Request: storage.Request{Method: "GET", URL: "http://127.0.0.1:1/a"}})
+	m = m2.(Model)
+	sendA := m.tabs[0].Send
+	m.activeTab = 1
+	m.loadActiveTab()
+	m2, _ = m.Update(uimsg.ExecuteRequestMsg{Request: storage.Request{Method: "GET", URL: "http://127.0.0.1:1/b"}})
+	m = m2.(Model)
+	if sendA == 0 || m.tabs[1].Send == 0 || sendA == m.tabs[1].Send {
+		t.Fatalf("Expected distinct request IDs, got %d and %d", sendA, m.tabs[1].Send)
+	}
+
+	// A's response lands in A while B stays in flight and on screen
+	respA := &http.ProcessedResponse{StatusCode: 200, Status: "200 OK", Body: []byte("from A")}
+	m2, _ = m.Update(uimsg.ResponseReadyMsg{Send: sendA, Response: respA})
+	m = m2.(Model)
+	if m.tabs[0].Response != respA || m.tabs[1].Response != nil {
+		t.Error("Expected the response to be saved to the tab that sent it")
+	}
+	if m.tabs[1].Cancel == nil || m.tabs[0].Cancel != nil {
+		t.Error("Expected only A's request to be finished")
+	}
+	if view := m.response.View(); !strings.Contains(view, "Loading") {
+		t.Errorf("Expected B to still be loading, got:\n%s", view)
+	}
+
+	// Closing B, a background tab, leaves A as it is; B's late outcome is dropped
+	sendB := m.tabs[1].Send
+	m.activeTab = 0
+	m.loadActiveTab()
+	m.closeTab(1)
+	m2, _ = m.Update(uimsg.RequestCancelledMsg{Send: sendB})
+	m = m2.(Model)
+	if view := m.response.View(); strings.Contains(view, "cancelled") {
+		t.Errorf("Expected A's response to stay on screen, got:\n%s", view)
+	}
+}
+
+func TestModel_ExtractsRuntimeVariables(t *testing.T) {
+	m := NewModel(config.DefaultConfig())
+	m.state = uimsg.ViewCollectionList
+	m.currentEnv = &storage.Environment{Name: "dev", Variables: map[string]string{"token": "stale", "host": "api.dev"}}
+
+	resp := &http.ProcessedResponse{StatusCode: 200, Body: []byte(`{"token": "fresh"}`)}
+	req := storage.Request{Extract: []storage.Extraction{{Var: "token", From: "json", Property: "$.token"}}}
+	send := m.startSend(nil, "")
+	m2, _ := m.Update(uimsg.ResponseReadyMsg{Send: send, Response: resp, Request: req})
+	m = m2.(Model)
+
+	got := m.applyCurrentEnv(storage.Request{URL: "https://{{host}}/me?t={{token}}"})
+	if got.URL != "https://api.dev/me?t=fresh" {
+		t.Errorf("Expected runtime variable to override env, got %q", got.URL)
+	}
+	if m.currentEnv.Variables["token"] != "stale" {
+		t.Error("Expected environment to be untouched without persist")
+	}
+
+	m, _, _ = m.executeCommand("clearvars")
+	if got := m.applyCurrentEnv(storage.Request{URL: "{{token}}"}); got.URL != "stale" {
+		t.Errorf("Expected env value after :clearvars, got %q", got.URL)
+	}
+}
+
+func TestModel_BlocksUnresolvedVariables(t *testing.T) {
+	m := NewModel(config.DefaultConfig())
+	m.state = uimsg.ViewCollectionList
+
+	m2, cmd := m.Update(uimsg.ExecuteRequestMsg{Request: storage.Request{Method: "GET", URL: "http://127.0.0.1:1/{{missing}}"}})
+	m = m2.(Model)
+	if m.cancel != nil {
+		t.Error("Expected request with unresolved variables not to be sent")
+	}
+	status, ok := cmd().(uimsg.StatusMsg)
+	if !ok || !status.IsError || !strings.Contains(status.Message, "missing") {
+		t.Errorf("Expected error naming the missing variable, got %+v", status)
+	}
+
+	m.cfg.AllowUnresolved = true
+	m2, _ = m.Update(uimsg.ExecuteRequestMsg{Request: storage.Request{Method: "GET", URL: "http://127.0.0.1:1/{{missing}}"}})
+	m = m2.(Model)
+	if m.cancel == nil {
+		t.Error("Expected request to be sent when unresolved variables are allowed")
+	}
+	m.cancel()
+}
+
+func TestModel_BlocksInvalidTransport(t *testing.T) {
+	m := NewModel(config.DefaultConfig())
+	m.state = uimsg.ViewCollectionList
+	m.currentEnv = &storage.Environment{Name: "dev", Transport: storage.Transport{MinTLS: "1.9"}}
+
+	m2, cmd := m.Update(uimsg.ExecuteRequestMsg{Request: storage.Request{Method: "GET", URL: "https://127.0.0.1:1"}})
+	m = m2.(Model)
+	if m.cancel != nil {
+		t.Error("Expected request with invalid transport settings not to be sent")
+	}
+	status, ok := cmd().(uimsg.StatusMsg)
+	if !ok || !status.IsError || !strings.Contains(status.Message, "min_tls_version") {
+		t.Errorf("Expected error naming the bad setting, got %+v", status)
+	}
+}
+
+func TestModel_EnvsLoadedRefreshesActiveEnv(t *testing.T) {
+	m := NewModel(config.DefaultConfig())
+	m.currentEnv = &storage.Environment{Name: "dev", Variables: map[string]string{"token": "old"}}
+
+	envs := []storage.Environment{{Name: "dev", Variables: map[string]string{"token": "new"}}}
+	m2, _ := m.Update(uimsg.EnvsLoadedMsg{Envs: envs})
+	m = m2.(Model)
+
+	if got := m.applyCurrentEnv(storage.Request{URL: "{{token}}"}); got.URL != "new" {
+		t.Errorf("Expected reloaded environment values, got %q", got.URL)
+	}
+}
+
+func TestModel_Download(t *testing.T) {
+	content := strings.Repeat("0123456789", 10_000)
+	server := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		w.Write([]byte(content))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "body.txt")
+	m := NewModel(config.DefaultConfig())
+	m.state = uimsg.ViewCollectionList
+	m2, cmd := m.Update(uimsg.ExecuteRequestMsg{Request: storage.Request{Method: "GET", URL: server.URL}, DownloadTo: path})
+	m = m2.(Model)
+	if !strings.Contains(m.response.View(), "DOWNLOADING") {
+		t.Errorf("Expected the download to be shown, got:\n%s", m.response.View())
+	}
+
+	// Progress reports come until the response
+	msg := cmd()
+	for {
+		if _, ok := msg.(uimsg.DownloadProgressMsg); !ok {
+			break
+		}
+		m2, cmd = m.Update(msg)
+		m = m2.(Model)
+		msg = cmd()
+	}
+	ready, ok := msg.(uimsg.ResponseReadyMsg)
+	if !ok {
+		t.Fatalf("Expected the response after the download, got %T", msg)
+	}
+	m2, _ = m.Update(ready)
+	m = m2.(Model)
+
+	if saved, _ := os.ReadFile(path); string(saved) != content {
+		t.Errorf("Expected the body in %s, got %d bytes", path, len(saved))
+	}
+	if view := m.response.View(); !strings.Contains(view, "Saved") || !strings.Contains(view, path) {
+		t.Errorf("Expected the saved file in the response pane, got:\n%s", view)
+	}
+}
+
+func TestExecuteCommand_Download(t *testing.T) {
+	m := NewModel(config.DefaultConfig())
+	m.state = uimsg.ViewCollectionList
+	m.request.LoadRequest(storage.Request{Method: "GET", URL: "https://example.com/files/report.pdf?v=2"}, "")
+
+	tests := []struct {
+		command string
+		path    string
+		resume  bool
+	}{
+		{"download", "report.pdf", false},
+		{"download out/my report.pdf", "out/my report.pdf", false},
+		{"download -c", "report.pdf", true},
+		{"download -c ~/big.iso", "~/big.iso", true},
+	}
+	for _, tt := range tests {
+		_, cmd, _ := m.executeCommand(tt.command)
+		msg, ok := cmd().(uimsg.ExecuteRequestMsg)
+		if !ok {
+			t.Fatalf("%s: expected the request to be executed, got %T", tt.command, cmd())
+		}
+		if msg.DownloadTo != tt.path || msg.Resume != tt.resume {
+			t.Errorf("%s: expected download to %q (resume %v), got %q (resume %v)", tt.command, tt.path, tt.resume, msg.DownloadTo, msg.Resume)
+		}
+	}
+}
+
+func TestDownloadName(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://example.com/files/report.pdf", "report.pdf"},
+		{"https://example.com/archive.tar.gz?token=1", "archive.tar.gz"},
+		{"https://example.com/", "download"},
+		{"https://example.com", "download"},
+		{"unix:///var/run/docker.sock:/images/get", "get"},
+	}
+	for _, tt := range tests {
+		if got := downloadName(tt.url); got != tt.want {
+			t.Errorf("downloadName(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
@@ -1,15 +1,16 @@
 package ui
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"time"
 
-	"github.com/atotto/clipboard"
+	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/styltsou/tapi/internal/http"
 	"github.com/styltsou/tapi/internal/logger"
 	"github.com/styltsou/tapi/internal/storage"
-	"github.com/styltsou/tapi/internal/storage/exporter"
 	"github.com/styltsou/tapi/internal/storage/importer"
 	"github.com/styltsou/tapi/internal/ui/commands"
 	"github.com/styltsou/tapi/internal/ui/components"
@@ -60,6 +61,8 @@ func (m Model) handleAppMsg(msg tea.Msg) (Model, tea.Cmd, bool) {
 		m.menu.SetSize(msg.Width, msg.Height)
 		m.collectionSelector.SetSize(msg.Width, msg.Height)
 		m.helpOverlay.SetSize(msg.Width, msg.Height)
+		m.tokenView.SetSize(msg.Width, msg.Height)
+		m.cookieView.SetSize(msg.Width, msg.Height)
 
 		logger.Logger.Debug("Window resized", "width", msg.Width, "height", msg.Height)
 		return m, nil, true
@@ -69,7 +72,7 @@ func (m Model) handleAppMsg(msg tea.Msg) (Model, tea.Cmd, bool) {
 		errStr := msg.Err.Error()
 		message := "Error: " + errStr
 		if strings.Contains(errStr, "timeout") {
-			message = "Request Timed Out (30s)"
+			message = "Request Timed Out"
 		} else if strings.Contains(errStr, "connection refused") || strings.Contains(errStr, "no such host") {
 			message = "Network Error: Check your connection"
 		}
@@ -80,16 +83,7 @@ func (m Model) handleAppMsg(msg tea.Msg) (Model, tea.Cmd, bool) {
 		}, true
 
 	case uimsg.CopyAsCurlMsg:
-		// Delegate to leader key handler logic
-		req, targetedURL := m.request.BuildRequest()
-		if targetedURL != "" {
-			req.URL = targetedURL
-		}
-		curlCmd := exporter.ExportCurl(req, m.request.BaseURL)
-		if err := clipboard.WriteAll(curlCmd); err != nil {
-			return m, commands.ShowStatusCmd("Failed to copy cURL", true), true
-		}
-		return m, commands.ShowStatusCmd("cURL copied to clipboard", false), true
+		return m, m.copyAsCurl(), true
 
 	case uimsg.ToggleSidebarMsg:
 		m.sidebarVisible = !m.sidebarVisible
@@ -130,39 +124,89 @@ func (m Model) handleAppMsg(msg tea.Msg) (Model, tea.Cmd, bool) {
 		return m, nil, true
 
 	case uimsg.ExecuteRequestMsg:
+		req := msg.Request
+		// TargetedURL has the path params filled in; variables are substituted below
+		if msg.TargetedURL != "" {
+			req.URL = msg.TargetedURL
+		}
+		req = msg.Inherited.Apply(req)
+		finalReq := m.applyCurrentEnv(req)
+
+		var warning tea.Cmd
+		if err := storage.CheckResolved(finalReq, m.variables(req)); err != nil {
+			if !m.cfg.AllowUnresolved {
+				return m, commands.ShowStatusCmd("Not sent, "+err.Error(), true), true
+			}
+			warning = commands.ShowStatusCmd("Warning: "+err.Error(), true)
+		}
+
+		client, err := m.clientFor(msg.Inherited)
+		if err != nil {
+			return m, commands.ShowStatusCmd("Not sent, "+strings.ReplaceAll(err.Error(), "\n", ": "), true), true
+		}
+
 		m.focusedPane = PaneResponse
 		m.response.SetLoading(true)
-		finalReq := m.applyCurrentEnv(msg.Request)
 		// Inject default headers from config (don't override request-specific headers)
-		for k, v := range m.cfg.DefaultHeaders {
-			if _, exists := finalReq.Headers[k]; !exists {
-				if finalReq.Headers == nil {
-					finalReq.Headers = make(map[string]string)
-				}
-				finalReq.Headers[k] = v
-			}
+		finalReq = finalReq.WithDefaultHeaders(m.cfg.DefaultHeaders)
+		ctx, cancel := context.WithCancel(context.Background())
+		if msg.DownloadTo != "" {
+			path := expandTilde(msg.DownloadTo)
+			m.response.SetDownloading(path)
+			send := m.startSend(cancel, path)
+			return m, tea.Batch(warning, commands.DownloadCmd(ctx, client, send, finalReq, msg.BaseURL, http.Download{Path: path, Resume: msg.Resume})), true
 		}
-		// If TargetedURL is present, we temporarily override the URL in the request
-		// or pass it explicitly.
-		if msg.TargetedURL != "" {
-			finalReq.URL = msg.TargetedURL
+		send := m.startSend(cancel, "")
+		return m, tea.Batch(warning, commands.ExecuteRequestCmd(ctx, client, send, finalReq, msg.BaseURL)), true
+
+	case uimsg.DownloadProgressMsg:
+		if tab, ok := m.sendTab(msg.Send); ok && m.shows(tab) {
+			m.response.SetProgress(msg.Progress)
 		}
-		return m, commands.ExecuteRequestCmd(m.httpClient, finalReq, msg.BaseURL), true
+		return m, commands.WaitForDownloadCmd(msg.Updates), true
 
+	// The outcome of a request goes to the tab that sent it, which may no
+	// longer be the active one, and is dropped if that tab was closed
 	case uimsg.ResponseReadyMsg:
-		m.response.SetResponse(msg.Response, msg.Request)
-		m.response.SetLoading(false)
-		// Save response to current tab
-		if m.activeTab >= 0 && m.activeTab < len(m.tabs) {
-			m.tabs[m.activeTab].Response = msg.Response
+		tab, ok := m.finishSend(msg.Send)
+		if !ok {
+			return m, nil, true
 		}
-		return m, nil, true
+		if tab >= 0 {
+			m.tabs[tab].Response = msg.Response
+		}
+		if m.shows(tab) {
+			m.response.SetResponse(msg.Response, msg.Request)
+			m.response.SetLoading(false)
+		}
+		return m, m.applyExtractions(msg.Response, msg.Request.Extract), true
+
+	case uimsg.RequestCancelledMsg:
+		tab, ok := m.finishSend(msg.Send)
+		if !ok {
+			return m, nil, true
+		}
+		if m.shows(tab) {
+			m.response.SetCancelled()
+		}
+		return m, commands.ShowStatusCmd("Request cancelled", false), true
+
+	case uimsg.RequestFailedMsg:
+		tab, ok := m.finishSend(msg.Send)
+		if !ok {
+			return m, nil, true
+		}
+		if m.shows(tab) {
+			m.response.SetLoading(false)
+		}
+		return m.handleAppMsg(uimsg.ErrMsg{Err: msg.Err})
 
 	case uimsg.CollectionSelectedMsg:
 		m.state = uimsg.ViewCollectionList
 		col := msg.Collection
 		m.currentCollection = &col
 		m.collections.SetCollection(msg.Collection)
+		m.syncVariables()
 		m.focusedPane = PaneCollections
 		newM, cmd := m.Update(tea.WindowSizeMsg{Width: m.Width, Height: m.Height}) 
 		return newM.(Model), cmd, true
@@ -180,13 +224,26 @@ func (m Model) handleAppMsg(msg tea.Msg) (Model, tea.Cmd, bool) {
 			return m, cmd, true
 		}
 		
+		// Open tabs inherit from a collection or folders that may have changed
+		for i := range m.tabs {
+			if c := storage.CollectionIndex(msg.Collections, m.tabs[i].CollectionID); c != -1 {
+				if entry, ok := msg.Collections[c].Entry(m.tabs[i].Request.ID); ok {
+					m.tabs[i].Inherited = entry.Inherited
+					if i == m.activeTab {
+						m.request.SetInherited(entry.Inherited)
+					}
+				}
+			}
+		}
+
 		// Refresh current collection if active
 		if m.currentCollection != nil {
 			found := false
 			for i := range msg.Collections {
-				if msg.Collections[i].Name == m.currentCollection.Name {
+				if msg.Collections[i].ID == m.currentCollection.ID {
 					m.currentCollection = &msg.Collections[i]
 					m.collections.SetCollection(msg.Collections[i])
+					m.syncVariables()
 					found = true
 					break
 				}
@@ -207,7 +264,7 @@ func (m Model) handleAppMsg(msg tea.Msg) (Model, tea.Cmd, bool) {
 	case uimsg.RequestSelectedMsg:
 		// Check if this request is already open in a tab
 		for i, tab := range m.tabs {
-			if tab.Request.Name == msg.Request.Name && tab.BaseURL == msg.BaseURL {
+			if tab.CollectionID == msg.CollectionID && tab.Request.ID == msg.Request.ID {
 				// Switch to existing tab
 				m.saveCurrentTab()
 				m.activeTab = i
@@ -224,13 +281,16 @@ func (m Model) handleAppMsg(msg tea.Msg) (Model, tea.Cmd, bool) {
 			label = label[:20] + "…"
 		}
 		m.tabs = append(m.tabs, components.RequestTab{
-			Request:  msg.Request,
-			BaseURL:  msg.BaseURL,
-			Response: nil,
-			Label:    label,
+			CollectionID: msg.CollectionID,
+			Request:      msg.Request,
+			BaseURL:      msg.BaseURL,
+			Inherited:    msg.Inherited,
+			Response:     nil,
+			Label:        label,
 		})
 		m.activeTab = len(m.tabs) - 1
 		m.request.LoadRequest(msg.Request, msg.BaseURL)
+		m.request.SetInherited(msg.Inherited)
 		// Clear response for new tab
 		w, h := m.response.Width, m.response.Height
 		m.response = components.NewResponseModel()
@@ -252,6 +312,13 @@ func (m Model) handleAppMsg(msg tea.Msg) (Model, tea.Cmd, bool) {
 			}
 		}, true
 
+	case downloadPathMsg:
+		m.state = uimsg.ViewCollectionList
+		if m.currentCollection == nil {
+			m.state = uimsg.ViewWelcome
+		}
+		return m.handleAppMsg(m.downloadRequest(msg.Path, false))
+
 	case confirmedDeleteEnvMsg:
 		return m, commands.DeleteEnvCmd(msg.Name), true
 
@@ -262,6 +329,11 @@ func (m Model) handleAppMsg(msg tea.Msg) (Model, tea.Cmd, bool) {
 		m.input.TextInput.SetValue("")
 		m.input.OnCommitMsg = msg.OnCommit
 		m.input.OnCancelMsg = func() tea.Msg { return uimsg.BackMsg{} }
+		m.input.TextInput.EchoMode = textinput.EchoNormal
+		if msg.Secret {
+			m.input.TextInput.EchoMode = textinput.EchoPassword
+			m.input.TextInput.EchoCharacter = '•'
+		}
 		// m.input.Init() returns a Cmd, usually Blink
 		return m, m.input.Init(), true
 
@@ -269,20 +341,34 @@ func (m Model) handleAppMsg(msg tea.Msg) (Model, tea.Cmd, bool) {
 		if strings.TrimSpace(msg.Name) == "" {
 			return m, commands.ShowStatusCmd("Request name cannot be empty", true), true
 		}
-		targetCol := "My Collection"
-		if m.currentCollection != nil {
-			targetCol = m.currentCollection.Name
+		// Close input modal and trigger creation
+		m.state = uimsg.ViewCollectionList
+		if m.currentCollection == nil {
+			return m, commands.ShowStatusCmd("Open a collection to create the request in", true), true
 		}
 		// Create a basic GET request by default
 		newReq := storage.Request{
-			Name:    msg.Name,
-			Method:  "GET",
-			URL:     "https://httpbin.org/get",
-			Headers: make(map[string]string),
+			ID:     storage.NewID(),
+			Name:   msg.Name,
+			Method: "GET",
+			URL:    "https://httpbin.org/get",
+		}
+		return m, commands.CreateRequestCmd(m.currentCollection.ID, newReq), true
+	case uimsg.SaveRequestMsg:
+		return m, commands.SaveRequestCmd(msg.CollectionID, msg.Request), true
+
+	case uimsg.RequestSavedMsg:
+		// Keep the tab and the sidebar in sync with what was written
+		for i := range m.tabs {
+			if m.tabs[i].CollectionID == msg.CollectionID && m.tabs[i].Request.ID == msg.Request.ID {
+				m.tabs[i].Request = msg.Request
+			}
 		}
-		// Close input modal and trigger creation
-		m.state = uimsg.ViewCollectionList
-		return m, commands.CreateRequestCmd(targetCol, newReq), true
+		return m, tea.Batch(
+			commands.ShowStatusCmd("Request saved", false),
+			commands.LoadCollectionsCmd(),
+		), true
+
 	case uimsg.RequestCreatedMsg:
 		return m, tea.Batch(
 			commands.ShowStatusCmd("Request created", false),
@@ -290,7 +376,7 @@ func (m Model) handleAppMsg(msg tea.Msg) (Model, tea.Cmd, bool) {
 		), true
 
 	case uimsg.DeleteRequestMsg:
-		return m, commands.DeleteRequestCmd(msg.CollectionName, msg.RequestName), true
+		return m, commands.DeleteRequestCmd(msg.CollectionID, msg.RequestID), true
 
 	case uimsg.RequestDeletedMsg:
 		// Close modal if open (implicitly handled by state change)
@@ -299,34 +385,31 @@ func (m Model) handleAppMsg(msg tea.Msg) (Model, tea.Cmd, bool) {
 		}
 		
 		// Reactive update: Remove request from current collection
-		if m.currentCollection != nil && m.currentCollection.Name == msg.CollectionName {
-			newReqs := []storage.Request{}
-			for _, req := range m.currentCollection.Requests {
-				if req.Name != msg.RequestName {
-					newReqs = append(newReqs, req)
-				}
-			}
-			m.currentCollection.Requests = newReqs
+		if m.currentCollection != nil && m.currentCollection.ID == msg.CollectionID {
+			m.currentCollection.RemoveRequest(msg.RequestID)
 			m.collections.SetCollection(*m.currentCollection)
 		}
 		
 		// Remove from tabs if open
 		newTabs := []components.RequestTab{}
 		activeTabClosed := false
+		activeTab := m.activeTab
 		for i, tab := range m.tabs {
-			// Check if this tab matches the deleted request
-			// We might need collection name in tab to be 100% sure, but request name + baseurl is good enough for now
-			// If we had collection name in tab, it would be safer. 
-			// For now, let's just match by name.
-			if tab.Request.Name == msg.RequestName {
+			if tab.CollectionID == msg.CollectionID && tab.Request.ID == msg.RequestID {
 				if i == m.activeTab {
 					activeTabClosed = true
+				} else if i < m.activeTab {
+					activeTab--
+				}
+				if tab.Cancel != nil {
+					tab.Cancel()
 				}
 				continue
 			}
 			newTabs = append(newTabs, tab)
 		}
 		m.tabs = newTabs
+		m.activeTab = activeTab
 		
 		// Adjust active tab
 		if activeTabClosed {
@@ -338,15 +421,13 @@ func (m Model) handleAppMsg(msg tea.Msg) (Model, tea.Cmd, bool) {
 				// Clear request view
 				m.request.Clear()
 				m.response.Clear()
-				// Show empty state or something? 
-				// For now just clear it.
 			}
 		} else if m.activeTab >= len(m.tabs) {
 			m.activeTab = len(m.tabs) - 1
 		}
 		
 		// If the currently viewed request (not via tab) was deleted
-		if m.activeTab == -1 && m.request.GetRequestName() == msg.RequestName {
+		if m.activeTab == -1 && m.request.GetRequestID() == msg.RequestID {
 			m.request.Clear()
 			m.response.Clear()
 		}
@@ -354,10 +435,10 @@ func (m Model) handleAppMsg(msg tea.Msg) (Model, tea.Cmd, bool) {
 		return m, nil, true
 
 	case uimsg.DeleteCollectionMsg:
-		return m, commands.DeleteCollectionCmd(msg.Name), true
+		return m, commands.DeleteCollectionCmd(msg.CollectionID), true
 
 	case uimsg.CollectionDeletedMsg:
-		if m.currentCollection != nil && m.currentCollection.Name == msg.Name {
+		if m.currentCollection != nil && m.currentCollection.ID == msg.CollectionID {
 			m.currentCollection = nil
 			m.state = uimsg.ViewWelcome
 		}
@@ -367,7 +448,7 @@ func (m Model) handleAppMsg(msg tea.Msg) (Model, tea.Cmd, bool) {
 		), true
 
 	case uimsg.CollectionRenamedMsg:
-		if m.currentCollection != nil && m.currentCollection.Name == msg.OldName {
+		if m.currentCollection != nil && m.currentCollection.ID == msg.CollectionID {
 			m.currentCollection.Name = msg.NewName
 		}
 		return m, tea.Batch(
@@ -380,7 +461,7 @@ func (m Model) handleAppMsg(msg tea.Msg) (Model, tea.Cmd, bool) {
 			return m, commands.ShowStatusCmd("Collection name cannot be empty", true), true
 		}
 		col := storage.Collection{Name: msg.Name, Requests: []storage.Request{}}
-		if err := storage.SaveCollection(col); err != nil {
+		if err := storage.SaveCollection(&col); err != nil {
 			return m, commands.ShowStatusCmd("Error: "+err.Error(), true), true
 		}
 		// Select the newly created collection and go to workspace
@@ -391,11 +472,80 @@ func (m Model) handleAppMsg(msg tea.Msg) (Model, tea.Cmd, bool) {
 
 	case uimsg.RenameCollectionMsg:
 		m.state = uimsg.ViewCollectionList
-		return m, commands.RenameCollectionCmd(msg.OldName, msg.NewName), true
+		return m, commands.RenameCollectionCmd(msg.CollectionID, msg.NewName), true
+
+	case uimsg.EnvsLoadedMsg:
+		m.env.SetEnvironments(msg.Envs)
+		// Pick up edits (or unlocked secrets) of the active environment
+		if m.currentEnv != nil {
+			for i := range msg.Envs {
+				if msg.Envs[i].Name == m.currentEnv.Name {
+					env := msg.Envs[i]
+					m.currentEnv = &env
+					m.syncVariables()
+					break
+				}
+			}
+		}
+		return m, nil, true
+
+	case components.SaveEnvMsg:
+		return m, commands.SaveEnvCmd(msg.Env), true
+
+	case uimsg.UnlockSecretsMsg:
+		m.state = uimsg.ViewCollectionList
+		if m.currentCollection == nil {
+			m.state = uimsg.ViewWelcome
+		}
+		storage.SetPassphrase(msg.Passphrase)
+		return m, tea.Batch(
+			commands.LoadEnvsCmd(),
+			commands.ShowStatusCmd("Passphrase set for this session", false),
+		), true
+
+	case uimsg.GlobalsLoadedMsg:
+		m.globals = msg.Variables
+		m.syncVariables()
+		return m, nil, true
+
+	case uimsg.RefreshTokenMsg:
+		client, err := m.clientFor(m.activeInherited())
+		if err != nil {
+			return m, commands.ShowStatusCmd(strings.ReplaceAll(err.Error(), "\n", ": "), true), true
+		}
+		return m, commands.RefreshTokenCmd(client, msg.Auth), true
+
+	case uimsg.ClearTokenMsg:
+		return m, commands.ClearTokenCmd(m.httpClient.Tokens, msg.Auth), true
+
+	case uimsg.TokenLoadedMsg:
+		m.tokenView.SetToken(msg)
+		return m, nil, true
+
+	case uimsg.SaveCookieMsg:
+		return m, commands.SaveCookieCmd(m.httpClient.Cookies, msg.Cookie), true
+
+	case uimsg.DeleteCookieMsg:
+		return m, commands.DeleteCookieCmd(m.httpClient.Cookies, msg.Cookie), true
+
+	case uimsg.CookiesLoadedMsg:
+		m.cookieView.SetCookies(msg)
+		return m, nil, true
 
 	case uimsg.EnvChangedMsg:
 		m.currentEnv = &msg.NewEnv
-		m.request.SetVariables(msg.NewEnv.Variables)
+		m.syncVariables()
+		if m.httpClient.Tokens != nil {
+			m.httpClient.Tokens.SetEnvironment(msg.NewEnv.Name)
+		}
+		if m.httpClient.Cookies != nil {
+			if err := m.httpClient.Cookies.SetEnvironment(msg.NewEnv.Name); err != nil {
+				logger.Logger.Warn("Failed to load cookies", "env", msg.NewEnv.Name, "error", err)
+			}
+		}
+		if msg.NewEnv.Locked() {
+			return m, commands.ShowStatusCmd(fmt.Sprintf("Env: %s has locked secrets, use :unlock", msg.NewEnv.Name), true), true
+		}
 		return m, commands.ShowStatusCmd(fmt.Sprintf("Env: %s", msg.NewEnv.Name), false), true
 
 	case uimsg.StatusMsg:
@@ -448,8 +598,8 @@ func (m Model) handleAppMsg(msg tea.Msg) (Model, tea.Cmd, bool) {
 		if err != nil {
 			return m, commands.ShowStatusCmd("Import failed: "+err.Error(), true), true
 		}
-		for _, col := range collections {
-			if saveErr := storage.SaveCollection(col); saveErr != nil {
+		for i := range collections {
+			if saveErr := storage.SaveCollection(&collections[i]); saveErr != nil {
 				return m, commands.ShowStatusCmd("Import save failed: "+saveErr.Error(), true), true
 			}
 		}
@@ -488,8 +638,36 @@ func (m Model) handleAppMsg(msg tea.Msg) (Model, tea.Cmd, bool) {
 		m.confirm.SetSize(m.Width, m.Height)
 		return m, m.confirm.Init(), true
 
+	case uimsg.MoveRequestMsg:
+		m.state = uimsg.ViewCollectionList
+		return m, commands.MoveRequestCmd(msg.CollectionID, msg.RequestID, msg.FolderPath), true
+
+	case uimsg.RequestMovedMsg:
+		// Open tabs pick up their new folders' headers and auth on reload
+		status := "Request moved to " + msg.FolderPath
+		if msg.FolderPath == "" {
+			status = "Request moved to the collection root"
+		}
+		return m, tea.Batch(
+			commands.ShowStatusCmd(status, false),
+			commands.LoadCollectionsCmd(),
+		), true
+
+	case uimsg.CreateFolderMsg:
+		m.state = uimsg.ViewCollectionList
+		if strings.Trim(msg.Path, "/ ") == "" {
+			return m, commands.ShowStatusCmd("Folder name cannot be empty", true), true
+		}
+		return m, commands.CreateFolderCmd(msg.CollectionID, msg.Path), true
+
+	case uimsg.FolderCreatedMsg:
+		return m, tea.Batch(
+			commands.ShowStatusCmd("Folder created", false),
+			commands.LoadCollectionsCmd(),
+		), true
+
 	case uimsg.DuplicateRequestMsg:
-		return m, commands.DuplicateRequestCmd(msg.CollectionName, msg.RequestName), true
+		return m, commands.DuplicateRequestCmd(msg.CollectionID, msg.RequestID), true
 
 	case uimsg.RequestDuplicatedMsg:
 		return m, tea.Batch(
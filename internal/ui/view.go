@@ -53,6 +53,10 @@ func (m Model) View() string {
 	var overlay string
 	if m.helpOverlay.Visible {
 		overlay = m.helpOverlay.View()
+	} else if m.tokenView.Visible {
+		overlay = m.tokenView.View()
+	} else if m.cookieView.Visible {
+		overlay = m.cookieView.View()
 	} else if m.menu.Visible {
 		overlay = m.menu.View()
 	} else if m.env.Visible {